@@ -34,7 +34,6 @@ for arch in "${linux_archs[@]}"; do
   export ARCH=$arch
   PKG=$root_package/cmd/agent ./build/build_one.sh
   PKG=$root_package/cmd/remove-finalizers ./build/build_one.sh
-  PKG=$root_package/cmd/restarter ./build/build_one.sh
   PKG=$root_package/cmd/scheduler ./build/build_one.sh
   unset ARCH
 done
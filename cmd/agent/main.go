@@ -21,19 +21,26 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
+	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/chaperon"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/cleanup"
+	"admiralty.io/multicluster-scheduler/pkg/controllers/events"
+	"admiralty.io/multicluster-scheduler/pkg/controllers/failover"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/feedback"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/follow"
+	"admiralty.io/multicluster-scheduler/pkg/controllers/follow/generic"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/follow/ingress"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/follow/service"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/resources"
 	"admiralty.io/multicluster-scheduler/pkg/controllers/source"
+	"admiralty.io/multicluster-scheduler/pkg/controllers/target"
 	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	clientset "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	customscheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
 	"admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/leaderelection"
@@ -41,19 +48,29 @@ import (
 	"admiralty.io/multicluster-scheduler/pkg/vk/http"
 	"admiralty.io/multicluster-scheduler/pkg/vk/node"
 	"admiralty.io/multicluster-scheduler/pkg/webhooks/proxypod"
+	targetwebhook "admiralty.io/multicluster-scheduler/pkg/webhooks/target"
 	"admiralty.io/multicluster-service-account/pkg/config"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	vklog "github.com/virtual-kubelet/virtual-kubelet/log"
 	logruslogger "github.com/virtual-kubelet/virtual-kubelet/log/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	kubeinformers "k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/klog/v2"
 	"k8s.io/sample-controller/pkg/signals"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -69,35 +86,87 @@ func main() {
 	o := parseFlags()
 	setupLogging(ctx, o)
 
-	agentCfg := agentconfig.NewFromCRD(ctx)
-
 	cfg, ns, err := config.ConfigAndNamespaceForKubeconfigAndContext("", "")
 	utilruntime.Must(err)
 
 	k, err := kubernetes.NewForConfig(cfg)
 	utilruntime.Must(err)
 
-	startWebhook(ctx, cfg, agentCfg)
-	go startVirtualKubeletServers(ctx, agentCfg, k)
+	customClient, err := versioned.NewForConfig(cfg)
+	utilruntime.Must(err)
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	utilruntime.Must(err)
+
+	followConfig, err := agentconfig.LoadFollowConfig(o.followConfig)
+	utilruntime.Must(err)
+
+	// targets are watched by all replicas (not just the leader),
+	// because the webhook and virtual kubelet servers need them too
+	targetSet := agentconfig.NewTargetSet()
+	target.Watch(ctx, k, customClient, targetSet)
+	if !targetSet.WaitForSync(ctx) {
+		return
+	}
+
+	startWebhook(ctx, cfg, o, targetSet)
+	go startVirtualKubeletServers(ctx, targetSet, k)
 
 	if o.leaderElect {
 		leaderelection.Run(ctx, ns, "admiralty-controller-manager", k, func(ctx context.Context) {
-			runControllers(ctx, agentCfg, cfg, k)
+			runControllers(ctx, o, targetSet, k, customClient, dynamicClient, followConfig)
 		})
 	} else {
-		runControllers(ctx, agentCfg, cfg, k)
+		runControllers(ctx, o, targetSet, k, customClient, dynamicClient, followConfig)
 	}
 }
 
-func runControllers(ctx context.Context, agentCfg agentconfig.Config, cfg *rest.Config, k *kubernetes.Clientset) {
-	var nodeStatusUpdaters map[string]resources.NodeStatusUpdater
-	if len(agentCfg.Targets) > 0 {
-		nodeStatusUpdaters = startVirtualKubeletControllers(ctx, agentCfg, k)
-	}
-	startOldStyleControllers(ctx, agentCfg, cfg, k, nodeStatusUpdaters)
+func runControllers(ctx context.Context, o *options, targetSet *agentconfig.TargetSet, k *kubernetes.Clientset, customClient *versioned.Clientset, dynamicClient dynamic.Interface, followConfig agentconfig.FollowConfig) {
+	followed := resolveFollowedResources(k, followConfig)
+	targetSet.AddHandler(&targetRunner{
+		ctx:           ctx,
+		clusterName:   os.Getenv("CLUSTER_NAME"),
+		k:             k,
+		customClient:  customClient,
+		dynamicClient: dynamicClient,
+		followed:      followed,
+		cancels:       map[string]context.CancelFunc{},
+	})
+	startClusterScopedControllers(ctx, o, targetSet, k, customClient, dynamicClient, followed)
 	<-ctx.Done()
 }
 
+type followedResource struct {
+	agentconfig.FollowedResource
+	gvr schema.GroupVersionResource
+}
+
+// resolveFollowedResources checks the kinds and resources of followed resources against discovery.
+// Kinds that cannot be resolved (e.g., CRDs not installed yet) aren't followed until the agent restarts.
+func resolveFollowedResources(k kubernetes.Interface, c agentconfig.FollowConfig) []followedResource {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(k.Discovery()))
+	var followed []followedResource
+	for _, r := range c.Resources {
+		gvk := r.GroupVersionKind()
+		m, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("cannot follow %s: %v", gvk, err))
+			continue
+		}
+		if m.Scope.Name() != meta.RESTScopeNameNamespace {
+			utilruntime.HandleError(fmt.Errorf("cannot follow %s: not namespaced", gvk))
+			continue
+		}
+		if m.Resource.Resource != r.Resource {
+			// RBAC is granted by resource name
+			utilruntime.HandleError(fmt.Errorf("cannot follow %s: resource is %s, not %s", gvk, m.Resource.Resource, r.Resource))
+			continue
+		}
+		followed = append(followed, followedResource{r, m.Resource})
+	}
+	return followed
+}
+
 type startable interface {
 	// Start doesn't block
 	Start(stopCh <-chan struct{})
@@ -108,133 +177,257 @@ type runnable interface {
 	Run(ctx context.Context, threadiness int) error
 }
 
-func startOldStyleControllers(
-	ctx context.Context,
-	agentCfg agentconfig.Config,
-	cfg *rest.Config,
-	k *kubernetes.Clientset,
-	nodeStatusUpdaters map[string]resources.NodeStatusUpdater,
-) {
-	customClient, err := versioned.NewForConfig(cfg)
-	utilruntime.Must(err)
+// withThreadiness overrides the threadiness that start runs a controller with
+type withThreadiness struct {
+	runnable
+	threadiness int
+}
+
+func (c withThreadiness) Run(ctx context.Context, _ int) error {
+	return c.runnable.Run(ctx, c.threadiness)
+}
+
+func start(ctx context.Context, factories []startable, controllers []runnable) {
+	for _, f := range factories {
+		f.Start(ctx.Done())
+	}
+
+	for _, c := range controllers {
+		c := c
+		go func() {
+			// caches may never sync if a target is removed while unreachable, that's ok
+			if err := c.Run(ctx, 1); err != nil && ctx.Err() == nil {
+				utilruntime.Must(err)
+			}
+		}()
+	}
+}
+
+// targetRunner starts and stops the per-target virtual kubelet, informers and controllers,
+// as targets are added and removed at runtime.
+type targetRunner struct {
+	ctx          context.Context
+	clusterName  string
+	k            *kubernetes.Clientset
+	customClient *versioned.Clientset
+
+	dynamicClient dynamic.Interface
+	followed      []followedResource
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+var _ agentconfig.TargetHandler = &targetRunner{}
+
+func (r *targetRunner) AddTarget(t agentconfig.Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[t.VirtualNodeName]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(r.ctx)
+	if err := r.startTarget(ctx, t); err != nil {
+		cancel()
+		utilruntime.HandleError(fmt.Errorf("cannot start target %s: %v", t.VirtualNodeName, err))
+		return
+	}
+	r.cancels[t.VirtualNodeName] = cancel
+	klog.Infof("started target %s", t.VirtualNodeName)
+}
+
+func (r *targetRunner) RemoveTarget(t agentconfig.Target) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cancel, ok := r.cancels[t.VirtualNodeName]; ok {
+		// the virtual node is left alone (it becomes not ready when its lease expires), as it would be after a restart,
+		// because deleting it would cause the pod garbage collector to delete proxy pods bound to it
+		cancel()
+		delete(r.cancels, t.VirtualNodeName)
+		klog.Infof("stopped target %s", t.VirtualNodeName)
+	}
+}
+
+func (r *targetRunner) startTarget(ctx context.Context, target agentconfig.Target) error {
+	k := r.k
+	customClient := r.customClient
+	clusterName := r.clusterName
 
 	var factories []startable
 	var controllers []runnable
 
-	clusterName := os.Getenv("CLUSTER_NAME")
-
-	for _, target := range agentCfg.Targets {
-		kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(k, time.Second*30, kubeinformers.WithNamespace(target.Namespace))
-		factories = append(factories, kubeInformerFactory)
-		customInformerFactory := informers.NewSharedInformerFactoryWithOptions(customClient, time.Second*30, informers.WithNamespace(target.Namespace))
-		factories = append(factories, customInformerFactory)
-
-		var targetCustomClient versioned.Interface
-		var targetPodChaperonInformer v1alpha1.PodChaperonInformer
-		var targetClusterSummaryInformer v1alpha1.ClusterSummaryInformer
-		if target.Self {
-			// re-use
-			targetCustomClient = customClient
-			targetPodChaperonInformer = customInformerFactory.Multicluster().V1alpha1().PodChaperons()
-			targetClusterSummaryInformer = customInformerFactory.Multicluster().V1alpha1().ClusterSummaries()
-		} else {
-			targetKubeClient, err := kubernetes.NewForConfig(target.ClientConfig)
-			utilruntime.Must(err)
-			targetCustomClient, err = versioned.NewForConfig(target.ClientConfig)
-			utilruntime.Must(err)
-
-			targetKubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(targetKubeClient, time.Second*30, kubeinformers.WithNamespace(target.Namespace))
-			factories = append(factories, targetKubeInformerFactory)
-			targetCustomInformerFactory := informers.NewSharedInformerFactoryWithOptions(targetCustomClient, time.Second*30, informers.WithNamespace(target.Namespace))
-			factories = append(factories, targetCustomInformerFactory)
-
-			targetPodChaperonInformer = targetCustomInformerFactory.Multicluster().V1alpha1().PodChaperons()
-			targetClusterSummaryInformer = targetCustomInformerFactory.Multicluster().V1alpha1().ClusterSummaries()
-
-			controllers = append(
-				controllers,
-				follow.NewConfigMapController(
-					clusterName,
-					target,
-					k,
-					targetKubeClient,
-					kubeInformerFactory.Core().V1().Pods(),
-					kubeInformerFactory.Core().V1().ConfigMaps(),
-					targetKubeInformerFactory.Core().V1().ConfigMaps(),
-				),
-				service.NewController(
-					clusterName,
-					target,
-					k,
-					targetKubeClient,
-					kubeInformerFactory.Core().V1().Endpoints(),
-					kubeInformerFactory.Core().V1().Services(),
-					kubeInformerFactory.Core().V1().Pods(),
-					targetKubeInformerFactory.Core().V1().Services(),
-				),
-				follow.NewSecretController(
-					clusterName,
-					target,
-					k,
-					targetKubeClient,
-					kubeInformerFactory.Core().V1().Pods(),
-					kubeInformerFactory.Core().V1().Secrets(),
-					targetKubeInformerFactory.Core().V1().Secrets(),
-				),
-				ingress.NewIngressController(
-					clusterName,
-					target,
-					k,
-					targetKubeClient,
-					kubeInformerFactory.Core().V1().Services(),
-					kubeInformerFactory.Networking().V1().Ingresses(),
-					targetKubeInformerFactory.Networking().V1().Ingresses(),
-				),
-			)
+	nodeStatusUpdater := startVirtualKubeletController(ctx, target, k)
+
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(k, time.Second*30, kubeinformers.WithNamespace(target.Namespace))
+	factories = append(factories, kubeInformerFactory)
+	customInformerFactory := informers.NewSharedInformerFactoryWithOptions(customClient, time.Second*30, informers.WithNamespace(target.Namespace))
+	factories = append(factories, customInformerFactory)
+
+	var targetCustomClient versioned.Interface
+	var targetPodChaperonInformer v1alpha1.PodChaperonInformer
+	var targetClusterSummaryInformer v1alpha1.ClusterSummaryInformer
+	var targetEventInformer coreinformers.EventInformer
+	if target.Self {
+		// re-use
+		targetCustomClient = customClient
+		targetPodChaperonInformer = customInformerFactory.Multicluster().V1alpha1().PodChaperons()
+		targetClusterSummaryInformer = customInformerFactory.Multicluster().V1alpha1().ClusterSummaries()
+		targetEventInformer = kubeInformerFactory.Core().V1().Events()
+	} else {
+		targetKubeClient, err := kubernetes.NewForConfig(target.ClientConfig)
+		if err != nil {
+			return err
+		}
+		targetCustomClient, err = versioned.NewForConfig(target.ClientConfig)
+		if err != nil {
+			return err
+		}
+		targetDynamicClient, err := dynamic.NewForConfig(target.ClientConfig)
+		if err != nil {
+			return err
+		}
+
+		targetKubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(targetKubeClient, time.Second*30, kubeinformers.WithNamespace(target.Namespace))
+		factories = append(factories, targetKubeInformerFactory)
+		targetCustomInformerFactory := informers.NewSharedInformerFactoryWithOptions(targetCustomClient, time.Second*30, informers.WithNamespace(target.Namespace))
+		factories = append(factories, targetCustomInformerFactory)
+
+		targetPodChaperonInformer = targetCustomInformerFactory.Multicluster().V1alpha1().PodChaperons()
+		targetClusterSummaryInformer = targetCustomInformerFactory.Multicluster().V1alpha1().ClusterSummaries()
+		targetEventInformer = targetKubeInformerFactory.Core().V1().Events()
+
+		dynamicInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(r.dynamicClient, time.Second*30, target.Namespace, nil)
+		factories = append(factories, dynamicInformerFactory)
+		targetDynamicInformerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(targetDynamicClient, time.Second*30, target.Namespace, nil)
+		factories = append(factories, targetDynamicInformerFactory)
+
+		for _, f := range r.followed {
+			controllers = append(controllers, generic.NewController(
+				clusterName,
+				target,
+				f.FollowedResource,
+				f.gvr,
+				r.dynamicClient,
+				targetDynamicClient,
+				kubeInformerFactory.Core().V1().Pods(),
+				dynamicInformerFactory.ForResource(f.gvr),
+				targetDynamicInformerFactory.ForResource(f.gvr),
+			))
 		}
+
 		controllers = append(
 			controllers,
-			feedback.NewController(
+			follow.NewConfigMapController(
+				clusterName,
+				target,
+				k,
+				targetKubeClient,
+				kubeInformerFactory.Core().V1().Pods(),
+				kubeInformerFactory.Core().V1().ConfigMaps(),
+				targetKubeInformerFactory.Core().V1().ConfigMaps(),
+			),
+			service.NewController(
 				clusterName,
 				target,
 				k,
-				targetCustomClient,
+				targetKubeClient,
+				kubeInformerFactory.Core().V1().Endpoints(),
+				kubeInformerFactory.Core().V1().Services(),
 				kubeInformerFactory.Core().V1().Pods(),
-				targetPodChaperonInformer,
+				targetKubeInformerFactory.Core().V1().Services(),
 			),
-			resources.NewUpstreamController(
+			follow.NewSecretController(
+				clusterName,
+				target,
+				k,
+				targetKubeClient,
+				kubeInformerFactory.Core().V1().Pods(),
+				kubeInformerFactory.Core().V1().Secrets(),
+				targetKubeInformerFactory.Core().V1().Secrets(),
+			),
+			follow.NewServiceAccountController(
+				clusterName,
+				target,
+				k,
+				targetKubeClient,
+				kubeInformerFactory.Core().V1().Pods(),
+				kubeInformerFactory.Core().V1().ServiceAccounts(),
+				kubeInformerFactory.Rbac().V1().RoleBindings(),
+				kubeInformerFactory.Rbac().V1().Roles(),
+				targetKubeInformerFactory.Core().V1().ServiceAccounts(),
+				targetKubeInformerFactory.Rbac().V1().RoleBindings(),
+				targetKubeInformerFactory.Rbac().V1().Roles(),
+			),
+			ingress.NewIngressController(
+				clusterName,
 				target,
 				k,
-				kubeInformerFactory.Core().V1().Nodes(),
-				targetClusterSummaryInformer,
-				nodeStatusUpdaters[target.VirtualNodeName],
+				targetKubeClient,
+				kubeInformerFactory.Core().V1().Services(),
+				kubeInformerFactory.Networking().V1().Ingresses(),
+				targetKubeInformerFactory.Networking().V1().Ingresses(),
 			),
 		)
 	}
-	factories, controllers = addClusterScopedFactoriesAndControllers(ctx, agentCfg, k, customClient, factories, controllers)
-
-	for _, f := range factories {
-		f.Start(ctx.Done())
-	}
+	controllers = append(
+		controllers,
+		feedback.NewController(
+			clusterName,
+			target,
+			k,
+			targetCustomClient,
+			kubeInformerFactory.Core().V1().Pods(),
+			targetPodChaperonInformer,
+		),
+		events.NewController(
+			ctx,
+			clusterName,
+			target,
+			k,
+			kubeInformerFactory.Core().V1().Pods(),
+			targetEventInformer,
+			targetPodChaperonInformer,
+		),
+		resources.NewUpstreamController(
+			target,
+			k,
+			kubeInformerFactory.Core().V1().Nodes(),
+			targetClusterSummaryInformer,
+			customInformerFactory.Multicluster().V1alpha1().ClusterTargets(),
+			customInformerFactory.Multicluster().V1alpha1().Targets(),
+			nodeStatusUpdater,
+		),
+	)
 
-	for _, c := range controllers {
-		c := c
-		go func() { utilruntime.Must(c.Run(ctx, 1)) }()
-	}
+	start(ctx, factories, controllers)
+	return nil
 }
 
-func addClusterScopedFactoriesAndControllers(
+func startClusterScopedControllers(
 	ctx context.Context,
-	agentCfg agentconfig.Config,
+	o *options,
+	targetSet *agentconfig.TargetSet,
 	k *kubernetes.Clientset,
 	customClient *clientset.Clientset,
-	factories []startable,
-	controllers []runnable,
-) ([]startable, []runnable) {
+	dynamicClient dynamic.Interface,
+	followed []followedResource,
+) {
+	var factories []startable
+	var controllers []runnable
+
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(k, time.Second*30)
 	factories = append(factories, kubeInformerFactory)
 	customInformerFactory := informers.NewSharedInformerFactory(customClient, time.Second*30)
 	factories = append(factories, customInformerFactory)
+	dynamicInformerFactory := dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, time.Second*30)
+	factories = append(factories, dynamicInformerFactory)
+
+	followedInformers := make(map[schema.GroupVersionResource]kubeinformers.GenericInformer, len(followed))
+	for _, f := range followed {
+		followedInformers[f.gvr] = dynamicInformerFactory.ForResource(f.gvr)
+	}
 
 	controllers = append(
 		controllers,
@@ -250,13 +443,29 @@ func addClusterScopedFactoriesAndControllers(
 		),
 		cleanup.NewController(
 			k,
+			dynamicClient,
 			kubeInformerFactory.Core().V1().Pods(),
 			kubeInformerFactory.Core().V1().Services(),
 			kubeInformerFactory.Networking().V1().Ingresses(),
 			kubeInformerFactory.Core().V1().ConfigMaps(),
 			kubeInformerFactory.Core().V1().Secrets(),
-			agentCfg.GetKnownFinalizers(),
+			kubeInformerFactory.Core().V1().ServiceAccounts(),
+			followedInformers,
+			targetSet.GetKnownFinalizers,
 		),
+		failover.NewController(
+			k,
+			kubeInformerFactory.Core().V1().Pods(),
+			kubeInformerFactory.Core().V1().Nodes(),
+			o.failover,
+		),
+		withThreadiness{target.NewStatusController(
+			customClient,
+			customInformerFactory.Multicluster().V1alpha1().ClusterTargets(),
+			customInformerFactory.Multicluster().V1alpha1().Targets(),
+			kubeInformerFactory.Core().V1().Secrets(),
+			kubeInformerFactory.Core().V1().Nodes(),
+		), target.StatusProbeWorkers},
 	)
 
 	// HACK: indirect feature gate, disable source controller if clustersources cannot be listed (e.g., not allowed)
@@ -276,11 +485,17 @@ func addClusterScopedFactoriesAndControllers(
 			kubeInformerFactory.Rbac().V1().ClusterRoleBindings(),
 		))
 	}
-	return factories, controllers
+
+	start(ctx, factories, controllers)
 }
 
-func startWebhook(ctx context.Context, cfg *rest.Config, agentCfg agentconfig.Config) {
+func startWebhook(ctx context.Context, cfg *rest.Config, o *options, targetSet *agentconfig.TargetSet) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(kubescheme.AddToScheme(scheme))
+	utilruntime.Must(customscheme.AddToScheme(scheme))
+
 	mgr, err := manager.New(cfg, manager.Options{
+		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress: "0",
 		},
@@ -290,7 +505,24 @@ func startWebhook(ctx context.Context, cfg *rest.Config, agentCfg agentconfig.Co
 
 	err = builder.WebhookManagedBy(mgr).
 		For(&corev1.Pod{}).
-		WithDefaulter(proxypod.Mutator{KnownFinalizers: agentCfg.GetKnownFinalizersByNamespace()}).
+		WithDefaulter(proxypod.Mutator{
+			KnownFinalizers:                       targetSet.GetKnownFinalizersInNamespace,
+			SourcePodManifestCompressionThreshold: o.sourcePodManifestCompressionThreshold,
+			Client:                                mgr.GetClient(),
+		}).
+		WithValidator(proxypod.Validator{}).
+		Complete()
+	utilruntime.Must(err)
+
+	err = builder.WebhookManagedBy(mgr).
+		For(&multiclusterv1alpha1.Target{}).
+		WithValidator(targetwebhook.Validator{}).
+		Complete()
+	utilruntime.Must(err)
+
+	err = builder.WebhookManagedBy(mgr).
+		For(&multiclusterv1alpha1.ClusterTarget{}).
+		WithValidator(targetwebhook.Validator{}).
 		Complete()
 	utilruntime.Must(err)
 
@@ -301,44 +533,31 @@ func startWebhook(ctx context.Context, cfg *rest.Config, agentCfg agentconfig.Co
 	}()
 }
 
-func startVirtualKubeletControllers(ctx context.Context, agentCfg agentconfig.Config, k kubernetes.Interface) map[string]resources.NodeStatusUpdater {
-	nodeStatusUpdaters := make(map[string]resources.NodeStatusUpdater, len(agentCfg.Targets))
-	for _, target := range agentCfg.Targets {
-		t := target
-		p := &node.NodeProvider{}
-		nodeStatusUpdaters[t.VirtualNodeName] = p
-		go func() {
-			if err := node.Run(ctx, t, k, p); err != nil && errors.Cause(err) != context.Canceled {
-				vklog.G(ctx).Fatal(err)
-			}
-		}()
-	}
-	return nodeStatusUpdaters
+func startVirtualKubeletController(ctx context.Context, t agentconfig.Target, k kubernetes.Interface) resources.NodeStatusUpdater {
+	p := &node.NodeProvider{}
+	go func() {
+		if err := node.Run(ctx, t, k, p); err != nil && errors.Cause(err) != context.Canceled {
+			vklog.G(ctx).Fatal(err)
+		}
+	}()
+	return p
 }
 
-func startVirtualKubeletServers(ctx context.Context, agentCfg agentconfig.Config, k kubernetes.Interface) {
-	targetConfigs := make(map[string]*rest.Config, len(agentCfg.Targets))
-	targetClients := make(map[string]kubernetes.Interface, len(agentCfg.Targets))
-	for _, target := range agentCfg.Targets {
-		n := target.VirtualNodeName
-		targetConfigs[n] = target.ClientConfig
-		targetClient, err := kubernetes.NewForConfig(target.ClientConfig)
-		utilruntime.Must(err)
-		targetClients[n] = targetClient
-	}
+func startVirtualKubeletServers(ctx context.Context, targetSet *agentconfig.TargetSet, k kubernetes.Interface) {
+	p := http.NewProxyPodProvider(ctx, k)
+	targetSet.AddHandler(p)
 
 	certPEM, keyPEM, err := csr.GetCertificateFromKubernetesAPIServer(ctx, k)
 	if wait.Interrupted(err) {
-		utilruntime.HandleError(fmt.Errorf("timed out waiting for virtual kubelet serving certificate to be signed, pod logs/exec won't be supported"))
+		utilruntime.HandleError(fmt.Errorf("timed out waiting for virtual kubelet serving certificate to be signed, pod logs, exec, attach, port-forward and stats won't be supported"))
 		return
 	}
 	utilruntime.Must(err) // likely RBAC issue
 
-	cancelHTTP, err := http.SetupHTTPServer(ctx, &http.LogsExecProvider{
-		SourceClient:  k,
-		TargetConfigs: targetConfigs,
-		TargetClients: targetClients,
-	}, certPEM, keyPEM)
+	auth, err := http.NewAuth(ctx, k)
+	utilruntime.Must(err)
+
+	cancelHTTP, err := http.SetupHTTPServer(ctx, p, auth, certPEM, keyPEM)
 	utilruntime.Must(err)
 
 	// this is a little convoluted, TODO: check the close/cancel/context mess with SetupHTTPServer
@@ -349,12 +568,22 @@ func startVirtualKubeletServers(ctx context.Context, agentCfg agentconfig.Config
 type options struct {
 	logLevel    string
 	leaderElect bool
+	failover    failover.Options
+
+	sourcePodManifestCompressionThreshold int
+
+	followConfig string
 }
 
 func parseFlags() *options {
 	o := &options{}
 	flag.StringVar(&o.logLevel, "log-level", "info", `set the log level, e.g. "debug", "info", "warn", "error"`)
 	flag.BoolVar(&o.leaderElect, "leader-elect", false, "Start a leader election client and gain leadership before executing the main loop. Enable this when running replicated components for high availability.")
+	flag.DurationVar(&o.failover.GracePeriod, "failover-grace-period", 40*time.Second, "How long a target must be unavailable before the proxy pods bound to its virtual node are marked as not ready.")
+	flag.DurationVar(&o.failover.Toleration, "failover-toleration", 5*time.Minute, "How long a target must be unavailable before the proxy pods bound to its virtual node are deleted (or evicted), so that their controllers recreate them elsewhere. Zero disables deletion.")
+	flag.BoolVar(&o.failover.Evict, "failover-evict", false, "Evict proxy pods from unavailable targets, respecting pod disruption budgets, rather than delete them.")
+	flag.IntVar(&o.sourcePodManifestCompressionThreshold, "source-pod-manifest-compression-threshold", 16*1024, "Size in bytes above which source pod manifests are gzipped in proxy pod annotations, to stay under the annotation size limit. Zero disables compression.")
+	flag.StringVar(&o.followConfig, "follow-config", "", "Path to a YAML file listing additional resources that follow proxy pods to target clusters, e.g., pod disruption budgets or custom resources.")
 	klog.InitFlags(nil)
 	flag.Parse()
 	return o
@@ -18,11 +18,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
@@ -30,6 +35,10 @@ import (
 )
 
 func main() {
+	var followed resources
+	flag.Var(&followed, "resource", "A resource followed by the generic follow controller, in the resource.version.group format (e.g., poddisruptionbudgets.v1.policy). Can be repeated.")
+	flag.Parse()
+
 	ctx := context.Background()
 
 	cfg := config.GetConfigOrDie()
@@ -37,13 +46,35 @@ func main() {
 	k, err := kubernetes.NewForConfig(cfg)
 	utilruntime.Must(err)
 
-	p := patchAll{k}
+	d, err := dynamic.NewForConfig(cfg)
+	utilruntime.Must(err)
+
+	p := patchAll{k, d}
 
 	p.patchPods(ctx)
 	p.patchServices(ctx)
 	p.patchConfigMaps(ctx)
 	p.patchSecrets(ctx)
 	p.patchIngresses(ctx)
+	p.patchServiceAccounts(ctx)
+	for _, gvr := range followed {
+		p.patchFollowed(ctx, gvr)
+	}
+}
+
+type resources []schema.GroupVersionResource
+
+func (r *resources) String() string {
+	return fmt.Sprint(*r)
+}
+
+func (r *resources) Set(s string) error {
+	gvr, _ := schema.ParseResourceArg(s)
+	if gvr == nil {
+		return fmt.Errorf("invalid resource %q, expected resource.version.group", s)
+	}
+	*r = append(*r, *gvr)
+	return nil
 }
 
 func patch(finalizers []string) string {
@@ -52,6 +83,7 @@ func patch(finalizers []string) string {
 
 type patchAll struct {
 	k *kubernetes.Clientset
+	d dynamic.Interface
 }
 
 func (p patchAll) patchPods(ctx context.Context) {
@@ -128,3 +160,41 @@ func (p patchAll) patchIngresses(ctx context.Context) {
 		utilruntime.Must(err)
 	}
 }
+
+func (p patchAll) patchServiceAccounts(ctx context.Context) {
+	l, err := p.k.CoreV1().ServiceAccounts("").List(ctx, metav1.ListOptions{LabelSelector: common.LabelKeyHasFinalizer})
+	utilruntime.Must(err)
+	for _, o := range l.Items {
+		var finalizers []string
+		for _, f := range o.Finalizers {
+			if strings.HasPrefix(f, common.KeyPrefix) {
+				finalizers = append(finalizers, f)
+			}
+		}
+		_, err := p.k.CoreV1().ServiceAccounts(o.Namespace).Patch(ctx, o.Name, types.StrategicMergePatchType, []byte(patch(finalizers)), metav1.PatchOptions{})
+		utilruntime.Must(err)
+	}
+}
+
+// patchFollowed removes finalizers with merge patches, because custom resources don't support strategic merge patches
+func (p patchAll) patchFollowed(ctx context.Context, gvr schema.GroupVersionResource) {
+	l, err := p.d.Resource(gvr).Namespace("").List(ctx, metav1.ListOptions{LabelSelector: common.LabelKeyHasFinalizer})
+	utilruntime.Must(err)
+	for _, o := range l.Items {
+		var finalizers []string
+		for _, f := range o.GetFinalizers() {
+			if !strings.HasPrefix(f, common.KeyPrefix) {
+				finalizers = append(finalizers, f)
+			}
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"finalizers":      finalizers,
+				"resourceVersion": o.GetResourceVersion(),
+			},
+		})
+		utilruntime.Must(err)
+		_, err = p.d.Resource(gvr).Namespace(o.GetNamespace()).Patch(ctx, o.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+		utilruntime.Must(err)
+	}
+}
@@ -4,14 +4,19 @@ go 1.22.0
 
 require (
 	admiralty.io/multicluster-service-account v0.6.1
+	github.com/evanphx/json-patch/v5 v5.9.0
 	github.com/go-test/deep v1.0.8
 	github.com/hashicorp/go-multierror v1.1.1
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_model v0.4.0
+	github.com/prometheus/common v0.44.0
 	github.com/sirupsen/logrus v1.9.3
 	github.com/stretchr/testify v1.8.4
 	github.com/virtual-kubelet/virtual-kubelet v1.11.0
 	k8s.io/api v0.30.5
+	k8s.io/apiextensions-apiserver v0.30.1
 	k8s.io/apimachinery v0.30.5
+	k8s.io/apiserver v0.30.5
 	k8s.io/client-go v0.30.5
 	k8s.io/code-generator v0.30.5
 	k8s.io/component-base v0.30.5
@@ -37,7 +42,6 @@ require (
 	github.com/distribution/reference v0.5.0 // indirect
 	github.com/emicklei/go-restful/v3 v3.11.0 // indirect
 	github.com/evanphx/json-patch v4.12.0+incompatible // indirect
-	github.com/evanphx/json-patch/v5 v5.9.0 // indirect
 	github.com/felixge/httpsnoop v1.0.3 // indirect
 	github.com/fsnotify/fsnotify v1.7.0 // indirect
 	github.com/go-logr/logr v1.4.1 // indirect
@@ -75,8 +79,6 @@ require (
 	github.com/opencontainers/selinux v1.11.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/prometheus/client_golang v1.16.0 // indirect
-	github.com/prometheus/client_model v0.4.0 // indirect
-	github.com/prometheus/common v0.44.0 // indirect
 	github.com/prometheus/procfs v0.12.0 // indirect
 	github.com/spf13/cobra v1.7.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
@@ -118,8 +120,6 @@ require (
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	k8s.io/apiextensions-apiserver v0.30.1 // indirect
-	k8s.io/apiserver v0.30.5 // indirect
 	k8s.io/cloud-provider v0.27.4 // indirect
 	k8s.io/component-helpers v0.30.5 // indirect
 	k8s.io/controller-manager v0.30.5 // indirect
@@ -35,6 +35,10 @@ type ClusterSummary struct {
 	Capacity v1.ResourceList `json:"capacity,omitempty"`
 	// +optional
 	Allocatable v1.ResourceList `json:"allocatable,omitempty"`
+	// Conditions are aggregated from the cluster's nodes' conditions (Ready, MemoryPressure, DiskPressure and PIDPressure).
+	// Their heartbeat times are refreshed periodically, so source clusters can tell whether the summary is stale.
+	// +optional
+	Conditions []v1.NodeCondition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -44,6 +44,34 @@ type ClusterTargetSpec struct {
 	KubeconfigSecret *ClusterKubeconfigSecret `json:"kubeconfigSecret,omitempty"`
 	// +optional
 	ExcludedLabelsRegexp *string `json:"excludedLabelsRegexp,omitempty"`
+	// Timeouts override the scheduler plugins' default timeouts for this target.
+	// +optional
+	Timeouts *TargetTimeouts `json:"timeouts,omitempty"`
+	// Weight is the relative preference for this target, from 0 to 100 (defaults to 50),
+	// used by the proxy scheduler to score virtual nodes, e.g., give cheaper clusters higher weights.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+	// Priority is a preference for this target that applies regardless of the pods' scheduling strategies (defaults to 0).
+	// Among targets of the same tier whose candidates were reserved, the proxy scheduler favors higher priorities.
+	// +optional
+	Priority *int32 `json:"priority,omitempty"`
+	// Tier groups targets for cloud bursting (defaults to 0): the proxy scheduler only considers targets of a tier
+	// when candidates in all targets of lower tiers are unschedulable.
+	// +optional
+	Tier *int32 `json:"tier,omitempty"`
+	// PriorityClassMap maps the names of priority classes in this cluster to priority classes in the target cluster,
+	// for delegate pods, so they can preempt lower-priority pods there. Unmapped priority classes are kept as is,
+	// so they must exist in the target cluster. The empty key maps pods without a priority class.
+	// +optional
+	PriorityClassMap map[string]string `json:"priorityClassMap,omitempty"`
+	// DelegatePodTransformations are applied in order to the delegate pods of this target, after the built-in transformations,
+	// e.g., to use a registry mirror, set a runtime class, or add labels and tolerations required by the target cluster.
+	// +optional
+	DelegatePodTransformations []DelegatePodTransformation `json:"delegatePodTransformations,omitempty"`
+	// ServiceAccountRBAC is whether and how the permissions of the service accounts that follow delegate pods
+	// to the target cluster are mirrored there (defaults to None).
+	// +optional
+	ServiceAccountRBAC ServiceAccountRBAC `json:"serviceAccountRBAC,omitempty"`
 }
 
 type ClusterKubeconfigSecret struct {
@@ -56,6 +84,17 @@ type ClusterKubeconfigSecret struct {
 }
 
 type ClusterTargetStatus struct {
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastContactTime is the last time the target cluster was successfully contacted.
+	// +optional
+	LastContactTime *metav1.Time `json:"lastContactTime,omitempty"`
+	// +optional
+	VirtualNodeName string `json:"virtualNodeName,omitempty"`
+	// +optional
+	Finalizer string `json:"finalizer,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
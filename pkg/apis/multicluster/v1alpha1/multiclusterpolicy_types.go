@@ -0,0 +1,119 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MulticlusterPolicy makes pods in its namespace multicluster pods, without the multicluster.admiralty.io/elect annotation.
+// The mutating pod admission webhook applies the first matching policy (by name) to each pod,
+// in the form of the equivalent annotations, unless the pod already has them.
+// +k8s:openapi-gen=true
+type MulticlusterPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec MulticlusterPolicySpec `json:"spec,omitempty"`
+}
+
+type MulticlusterPolicySpec struct {
+	// PodSelector selects the pods the policy applies to. All pods match an empty or nil selector.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// NoReservation is equivalent to the multicluster.admiralty.io/no-reservation annotation.
+	// +optional
+	NoReservation bool `json:"noReservation,omitempty"`
+	// ProxyPodSchedulingConstraints is equivalent to the multicluster.admiralty.io/proxy-pod-scheduling-constraints annotation.
+	// +optional
+	ProxyPodSchedulingConstraints *ProxyPodSchedulingConstraints `json:"proxyPodSchedulingConstraints,omitempty"`
+	// UseConstraintsFromSpecForProxyPodScheduling is equivalent to
+	// the multicluster.admiralty.io/use-constraints-from-spec-for-proxy-pod-scheduling annotation.
+	// +optional
+	UseConstraintsFromSpecForProxyPodScheduling bool `json:"useConstraintsFromSpecForProxyPodScheduling,omitempty"`
+	// NoPrefixLabelRegexp is equivalent to the multicluster.admiralty.io/no-prefix-label-regexp annotation.
+	// +optional
+	NoPrefixLabelRegexp string `json:"noPrefixLabelRegexp,omitempty"`
+}
+
+// ProxyPodSchedulingConstraints are the scheduling constraints of proxy pods, i.e., to select and tolerate virtual nodes.
+type ProxyPodSchedulingConstraints struct {
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MulticlusterPolicyList contains a list of MulticlusterPolicy
+type MulticlusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MulticlusterPolicy `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// ClusterMulticlusterPolicy is a MulticlusterPolicy that applies to pods in the namespaces it selects.
+// Namespaced policies take precedence over cluster-scoped policies.
+// +k8s:openapi-gen=true
+type ClusterMulticlusterPolicy struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +optional
+	Spec ClusterMulticlusterPolicySpec `json:"spec,omitempty"`
+}
+
+type ClusterMulticlusterPolicySpec struct {
+	// NamespaceSelector selects the namespaces whose pods the policy applies to. All namespaces match an empty or nil selector.
+	// Note that the mutating pod admission webhook is only called in namespaces labeled multicluster-scheduler=enabled.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	MulticlusterPolicySpec `json:",inline"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +genclient:nonNamespaced
+
+// ClusterMulticlusterPolicyList contains a list of ClusterMulticlusterPolicy
+type ClusterMulticlusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterMulticlusterPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MulticlusterPolicy{}, &MulticlusterPolicyList{}, &ClusterMulticlusterPolicy{}, &ClusterMulticlusterPolicyList{})
+}
@@ -17,6 +17,7 @@
 package v1alpha1
 
 import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -43,8 +44,115 @@ type TargetSpec struct {
 	KubeconfigSecret *KubeconfigSecret `json:"kubeconfigSecret,omitempty"`
 	// +optional
 	ExcludedLabelsRegexp *string `json:"excludedLabelsRegexp,omitempty"`
+	// Timeouts override the scheduler plugins' default timeouts for this target.
+	// +optional
+	Timeouts *TargetTimeouts `json:"timeouts,omitempty"`
+	// Weight is the relative preference for this target, from 0 to 100 (defaults to 50),
+	// used by the proxy scheduler to score virtual nodes, e.g., give cheaper clusters higher weights.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+	// Priority is a preference for this target that applies regardless of the pods' scheduling strategies (defaults to 0).
+	// Among targets of the same tier whose candidates were reserved, the proxy scheduler favors higher priorities.
+	// +optional
+	Priority *int32 `json:"priority,omitempty"`
+	// Tier groups targets for cloud bursting (defaults to 0): the proxy scheduler only considers targets of a tier
+	// when candidates in all targets of lower tiers are unschedulable.
+	// +optional
+	Tier *int32 `json:"tier,omitempty"`
+	// PriorityClassMap maps the names of priority classes in this cluster to priority classes in the target cluster,
+	// for delegate pods, so they can preempt lower-priority pods there. Unmapped priority classes are kept as is,
+	// so they must exist in the target cluster. The empty key maps pods without a priority class.
+	// +optional
+	PriorityClassMap map[string]string `json:"priorityClassMap,omitempty"`
+	// DelegatePodTransformations are applied in order to the delegate pods of this target, after the built-in transformations,
+	// e.g., to use a registry mirror, set a runtime class, or add labels and tolerations required by the target cluster.
+	// +optional
+	DelegatePodTransformations []DelegatePodTransformation `json:"delegatePodTransformations,omitempty"`
+	// ServiceAccountRBAC is whether and how the permissions of the service accounts that follow delegate pods
+	// to the target cluster are mirrored there (defaults to None).
+	// +optional
+	ServiceAccountRBAC ServiceAccountRBAC `json:"serviceAccountRBAC,omitempty"`
+}
+
+// TargetTimeouts are per-target overrides of the scheduler plugins' timeouts, e.g., for slow serverless clusters.
+type TargetTimeouts struct {
+	// Filter is how long the proxy scheduler waits for a candidate to be reserved or found unschedulable in the target cluster.
+	// +optional
+	Filter *metav1.Duration `json:"filter,omitempty"`
+	// PreBind is how long the proxy scheduler waits for the candidate to be bound in the target cluster.
+	// +optional
+	PreBind *metav1.Duration `json:"preBind,omitempty"`
+	// Permit is how long the candidate scheduler waits for the candidate to be allowed by the proxy scheduler.
+	// +optional
+	Permit *metav1.Duration `json:"permit,omitempty"`
+}
+
+// DelegatePodTransformation is a step of a target's delegate pod transformation pipeline.
+// Exactly one of its fields must be set.
+type DelegatePodTransformation struct {
+	// JSONPatch is a JSON patch (RFC 6902) applied to the delegate pod chaperon, e.g., to add labels (under /metadata/labels)
+	// or tolerations (under /spec/tolerations). Missing parents of added paths are created,
+	// and removing missing paths is a no-op.
+	// +optional
+	JSONPatch []JSONPatchOperation `json:"jsonPatch,omitempty"`
+	// FieldMapping maps the values of a string field of the delegate pod chaperon, e.g., a runtime class name.
+	// +optional
+	FieldMapping *FieldMapping `json:"fieldMapping,omitempty"`
+	// ImageRewrite rewrites the images of the delegate pod's containers, e.g., to use a registry mirror.
+	// +optional
+	ImageRewrite *ImageRewrite `json:"imageRewrite,omitempty"`
+	// Custom is the name of a transformation compiled into the agent (see delegatepod.RegisterTransformer).
+	// +optional
+	Custom string `json:"custom,omitempty"`
 }
 
+// JSONPatchOperation is an operation of a JSON patch (RFC 6902).
+type JSONPatchOperation struct {
+	// Op is add, remove, replace, move, copy or test.
+	Op string `json:"op"`
+	// Path is a JSON pointer (RFC 6901), e.g., /spec/runtimeClassName.
+	Path string `json:"path"`
+	// From is the source JSON pointer of move and copy operations.
+	// +optional
+	From string `json:"from,omitempty"`
+	// Value is the value of add, replace and test operations.
+	// +optional
+	Value *apiextensionsv1.JSON `json:"value,omitempty"`
+}
+
+// FieldMapping maps the values of a string field.
+type FieldMapping struct {
+	// Path is a JSON pointer (RFC 6901) to the field, e.g., /spec/runtimeClassName.
+	Path string `json:"path"`
+	// Values maps the field's values to new values. The empty key maps a missing field, and the empty value removes the field.
+	// Unmapped values are kept as is.
+	Values map[string]string `json:"values"`
+}
+
+// ImageRewrite rewrites container images matching a regular expression.
+type ImageRewrite struct {
+	// Regexp matches the images to rewrite, e.g., ^docker\.io/
+	Regexp string `json:"regexp"`
+	// Replacement replaces the matches, and may refer to submatches, e.g., $1 (see regexp.Regexp.ReplaceAllString).
+	Replacement string `json:"replacement"`
+}
+
+// ServiceAccountRBAC is how the permissions of followed service accounts are mirrored in target clusters.
+type ServiceAccountRBAC string
+
+const (
+	// ServiceAccountRBACNone doesn't mirror permissions: followed service accounts only have the permissions
+	// granted to them in the target cluster.
+	ServiceAccountRBACNone ServiceAccountRBAC = "None"
+	// ServiceAccountRBACRoleBindings mirrors the role bindings of followed service accounts, i.e., in their namespaces.
+	// Other subjects than followed service accounts aren't mirrored. The roles and cluster roles that the role bindings
+	// refer to must exist in the target cluster.
+	ServiceAccountRBACRoleBindings ServiceAccountRBAC = "RoleBindings"
+	// ServiceAccountRBACRoleBindingsAndRoles also mirrors the roles that the role bindings refer to.
+	// Cluster roles must still exist in the target cluster.
+	ServiceAccountRBACRoleBindingsAndRoles ServiceAccountRBAC = "RoleBindingsAndRoles"
+)
+
 type KubeconfigSecret struct {
 	Name string `json:"name"`
 	// +optional
@@ -54,8 +162,32 @@ type KubeconfigSecret struct {
 }
 
 type TargetStatus struct {
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// LastContactTime is the last time the target cluster was successfully contacted,
+	// refreshed when conditions change, or every few minutes otherwise.
+	// +optional
+	LastContactTime *metav1.Time `json:"lastContactTime,omitempty"`
+	// +optional
+	VirtualNodeName string `json:"virtualNodeName,omitempty"`
+	// +optional
+	Finalizer string `json:"finalizer,omitempty"`
 }
 
+// condition types on Target and ClusterTarget status
+const (
+	// TargetConditionKubeconfigValid is true if self XOR kubeconfigSecret, and the kubeconfig secret exists and is valid.
+	TargetConditionKubeconfigValid = "KubeconfigValid"
+	// TargetConditionReachable is true if the target cluster's API server answers.
+	TargetConditionReachable = "Reachable"
+	// TargetConditionAuthorized is true if the target cluster authorizes us to list pod chaperons.
+	TargetConditionAuthorized = "Authorized"
+	// TargetConditionVirtualNodeReady is true if the target's virtual node exists and is ready.
+	TargetConditionVirtualNodeReady = "VirtualNodeReady"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // TargetList contains a list of Target
@@ -40,23 +40,77 @@ var (
 
 	AnnotationKeyUseConstraintsFromSpecForProxyPodScheduling = KeyPrefix + "use-constraints-from-spec-for-proxy-pod-scheduling"
 
+	// AnnotationKeySchedulingStrategy tells the proxy scheduler how to rank target clusters:
+	// SchedulingStrategySpread (default) prefers targets with more remaining allocatable resources,
+	// SchedulingStrategyBinpack prefers targets with less, and
+	// SchedulingStrategyCheapestFirst prefers targets with higher weights (see TargetSpec.Weight).
+	AnnotationKeySchedulingStrategy = KeyPrefix + "scheduling-strategy"
+
+	SchedulingStrategySpread        = "spread"
+	SchedulingStrategyBinpack       = "binpack"
+	SchedulingStrategyCheapestFirst = "cheapest-first"
+
+	// AnnotationKeyPodGroup makes the proxy scheduler gang-schedule pods with the same pod group name (in the same namespace):
+	// their candidates are only allowed once AnnotationKeyPodGroupMinMember of them are reserved in a single target,
+	// or across targets if AnnotationKeyPodGroupAcrossTargets is "true"; otherwise, they are all released.
+	// Candidates are annotated too, so candidate schedulers release the waiting members of a group together.
+	AnnotationKeyPodGroup              = KeyPrefix + "pod-group"
+	AnnotationKeyPodGroupMinMember     = KeyPrefix + "pod-group-min-member"
+	AnnotationKeyPodGroupAcrossTargets = KeyPrefix + "pod-group-across-targets"
+
 	// AnnotationNoPrefixLabelRegexp defines a regex that when matched on labels, the label
 	// gets copied as-is to the delegate pod without appending KeyPrefix prefix
 	AnnotationNoPrefixLabelRegexp = KeyPrefix + "no-prefix-label-regexp"
 
 	// annotations on proxy pods (by mutating admission webhook)
 
+	// AnnotationKeyPolicy records the MulticlusterPolicy or ClusterMulticlusterPolicy applied to a pod, as "<kind>/<name>"
+	AnnotationKeyPolicy = KeyPrefix + "policy"
+
 	KeyPrefixSourcePod = KeyPrefix + "sourcepod-"
 
 	AnnotationKeySourcePodManifest = KeyPrefixSourcePod + "manifest"
+	// AnnotationKeySourcePodManifestGzip replaces AnnotationKeySourcePodManifest for large source pods:
+	// its value is the base64-encoded gzipped YAML manifest (see proxypod.SetSourcePod)
+	AnnotationKeySourcePodManifestGzip = KeyPrefixSourcePod + "manifest-gzip"
 
 	// annotations on delegate pod chaperons (by scheduler plugins)
 
 	AnnotationKeyIsReserved = KeyPrefix + "is-reserved"
 	AnnotationKeyIsAllowed  = KeyPrefix + "is-allowed"
 
+	// AnnotationKeyPreemptionCost is set by the candidate scheduler on unschedulable candidates
+	// whose pods could preempt others, to the JSON-encoded delegatepod.PreemptionCost of a dry run.
+	// AnnotationKeyIsAllowedToPreempt is set by the proxy scheduler on the candidate in the cheapest target,
+	// and relayed to the candidate pod by the pod chaperon controller, so the candidate scheduler retries and preempts.
+	AnnotationKeyPreemptionCost     = KeyPrefix + "preemption-cost"
+	AnnotationKeyIsAllowedToPreempt = KeyPrefix + "is-allowed-to-preempt"
+
+	// AnnotationKeyPermitTimeout overrides the candidate scheduler's permit timeout (a duration string, e.g., "2m"),
+	// set by the proxy scheduler from the target's timeouts.
+	AnnotationKeyPermitTimeout = KeyPrefix + "permit-timeout"
+
 	AnnotationKeyPodMissingSince = KeyPrefix + "pod-missing-since"
 
+	// AnnotationKeyPodChaperonGeneration is set by the chaperon controller on pods, to the generation of their pod chaperons
+	// whose allowed spec updates were last applied to them
+	AnnotationKeyPodChaperonGeneration = KeyPrefix + "pod-chaperon-generation"
+
+	// PodConditionTypeCandidatesScheduled is a condition of proxy pods (set by the proxy scheduler),
+	// whose message explains why each target rejected the pod, while it is unschedulable
+	PodConditionTypeCandidatesScheduled = KeyPrefix + "CandidatesScheduled"
+
+	// PodConditionTypeTargetAvailable is a condition of proxy pods (set by the failover controller),
+	// false while their target has been unavailable for longer than a grace period,
+	// during which the feedback controller doesn't sync their statuses from their delegate pods'
+	PodConditionTypeTargetAvailable = KeyPrefix + "TargetAvailable"
+
+	// NodeConditionTypeClusterSummaryFresh is a condition of virtual nodes (set by the upstream resources controller),
+	// false when the target's cluster summary hasn't been refreshed recently, e.g., because the target's agent is down;
+	// the virtual node is then tainted with TaintKeyClusterSummaryStale (NoSchedule)
+	NodeConditionTypeClusterSummaryFresh = KeyPrefix + "ClusterSummaryFresh"
+	TaintKeyClusterSummaryStale          = KeyPrefix + "cluster-summary-stale"
+
 	// annotations on following services and ingresses (for cloud controller manager to configure DNS)
 
 	AnnotationKeyGlobal = KeyPrefix + "global"
@@ -79,9 +133,10 @@ var (
 
 	AnnotationKeyOriginalSelector = KeyPrefix + "original-selector"
 
-	AnnotationKeyRestartedAt = KeyPrefix + "restartedAt"
-
 	LabelKeyTargetNamespace   = KeyPrefix + "target-namespace"
 	LabelKeyTargetName        = KeyPrefix + "target-name"
 	LabelKeyClusterTargetName = KeyPrefix + "cluster-target-name"
+	LabelKeyTargetWeight      = KeyPrefix + "target-weight"
+	LabelKeyTargetPriority    = KeyPrefix + "target-priority"
+	LabelKeyTargetTier        = KeyPrefix + "target-tier"
 )
@@ -17,151 +17,143 @@
 package agent
 
 import (
-	"context"
-	"log"
+	"fmt"
 
 	"admiralty.io/multicluster-scheduler/pkg/common"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
 	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
-	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
 	"admiralty.io/multicluster-scheduler/pkg/name"
 )
 
-type Config struct {
-	Targets []Target
-}
-
-func (c Config) GetKnownFinalizers() []string {
-	var knownFinalizers []string
-	for _, target := range c.Targets {
-		knownFinalizers = append(knownFinalizers, target.Finalizer)
-	}
-	return knownFinalizers
+type Target struct {
+	Name                       string
+	ClientConfig               *rest.Config
+	Self                       bool // optimization to re-use clients, informers, etc.
+	Namespace                  string
+	ExcludedLabelsRegexp       *string
+	Timeouts                   *v1alpha1.TargetTimeouts
+	Weight                     *int32
+	Priority                   *int32
+	Tier                       *int32
+	PriorityClassMap           map[string]string
+	DelegatePodTransformations []v1alpha1.DelegatePodTransformation
+	ServiceAccountRBAC         v1alpha1.ServiceAccountRBAC
+	VirtualNodeName            string
+	Finalizer                  string
 }
 
-func (c Config) GetKnownFinalizersByNamespace() map[string][]string {
-	knownFinalizers := map[string][]string{}
-	for _, target := range c.Targets {
-		knownFinalizers[target.Namespace] = append(knownFinalizers[target.Namespace], target.Finalizer)
+// GetTier returns the target's tier, 0 by default
+func (t Target) GetTier() int32 {
+	if t.Tier == nil {
+		return 0
 	}
-	return knownFinalizers
-}
-
-type Target struct {
-	Name                 string
-	ClientConfig         *rest.Config
-	Self                 bool // optimization to re-use clients, informers, etc.
-	Namespace            string
-	ExcludedLabelsRegexp *string
-	VirtualNodeName      string
-	Finalizer            string
+	return *t.Tier
 }
 
 func (t *Target) complete() {
-	t.VirtualNodeName = name.FromParts(name.Long, []int{0}, []int{1}, "admiralty", t.Namespace, t.Name)
-	t.Finalizer = common.KeyPrefix + name.FromParts(name.Short, nil, []int{0}, t.Namespace, t.Name)
+	t.VirtualNodeName = VirtualNodeName(t.Namespace, t.Name)
+	t.Finalizer = Finalizer(t.Namespace, t.Name)
 }
 
-// until we watch targets at runtime, we can already load them from objects at startup
-func NewFromCRD(ctx context.Context) Config {
-	cfg := config.GetConfigOrDie()
-
-	customClient, err := versioned.NewForConfig(cfg)
-	utilruntime.Must(err)
-
-	k, err := kubernetes.NewForConfig(cfg)
-	utilruntime.Must(err)
-
-	agentCfg := Config{}
-
-	cl, err := customClient.MulticlusterV1alpha1().ClusterTargets().List(ctx, metav1.ListOptions{})
-	utilruntime.Must(err)
-	for _, t := range cl.Items {
-		addClusterTarget(ctx, k, &agentCfg, t)
-	}
-
-	l, err := customClient.MulticlusterV1alpha1().Targets(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
-	utilruntime.Must(err)
-	for _, t := range l.Items {
-		addTarget(ctx, k, &agentCfg, t)
-	}
+// VirtualNodeName returns the name of the virtual node of a Target (or ClusterTarget, if namespace is empty).
+func VirtualNodeName(namespace, targetName string) string {
+	return name.FromParts(name.Long, []int{0}, []int{1}, "admiralty", namespace, targetName)
+}
 
-	return agentCfg
+// Finalizer returns the finalizer that a Target (or ClusterTarget, if namespace is empty) adds to proxy pods, etc.
+func Finalizer(namespace, targetName string) string {
+	return common.KeyPrefix + name.FromParts(name.Short, nil, []int{0}, namespace, targetName)
 }
 
-func addClusterTarget(ctx context.Context, k *kubernetes.Clientset, agentCfg *Config, t v1alpha1.ClusterTarget) {
+// TargetFromClusterTarget builds a Target from a ClusterTarget object.
+// kubeconfigSecret must be the secret referenced by the ClusterTarget, or nil if it targets its own cluster.
+func TargetFromClusterTarget(t *v1alpha1.ClusterTarget, kubeconfigSecret *corev1.Secret) (Target, error) {
 	if t.Spec.Self == (t.Spec.KubeconfigSecret != nil) {
-		log.Printf("invalid ClusterTarget %s: self XOR kubeconfigSecret != nil", t.Name)
-		return
-		// TODO validating webhook to catch user error upstream
+		return Target{}, fmt.Errorf("invalid ClusterTarget %s: self XOR kubeconfigSecret != nil", t.Name)
 	}
 	var cfg *rest.Config
 	if kcfg := t.Spec.KubeconfigSecret; kcfg != nil {
 		var err error
-		cfg, err = getConfigFromKubeconfigSecretOrDie(ctx, k, kcfg.Namespace, kcfg.Name, kcfg.Key, kcfg.Context)
+		cfg, err = getConfigFromKubeconfigSecret(kubeconfigSecret, kcfg.Key, kcfg.Context)
 		if err != nil {
-			log.Printf("invalid ClusterTarget %s: %v", t.Name, err)
-			return
+			return Target{}, fmt.Errorf("invalid ClusterTarget %s: %v", t.Name, err)
 		}
 	} else {
-		cfg = config.GetConfigOrDie()
+		var err error
+		cfg, err = config.GetConfig()
+		if err != nil {
+			return Target{}, err
+		}
 	}
 
 	c := Target{
-		Name:                 t.Name,
-		ClientConfig:         cfg,
-		Namespace:            corev1.NamespaceAll,
-		Self:                 t.Spec.Self,
-		ExcludedLabelsRegexp: t.Spec.ExcludedLabelsRegexp,
+		Name:                       t.Name,
+		ClientConfig:               cfg,
+		Namespace:                  corev1.NamespaceAll,
+		Self:                       t.Spec.Self,
+		ExcludedLabelsRegexp:       t.Spec.ExcludedLabelsRegexp,
+		Timeouts:                   t.Spec.Timeouts,
+		Weight:                     t.Spec.Weight,
+		Priority:                   t.Spec.Priority,
+		Tier:                       t.Spec.Tier,
+		PriorityClassMap:           t.Spec.PriorityClassMap,
+		DelegatePodTransformations: t.Spec.DelegatePodTransformations,
+		ServiceAccountRBAC:         t.Spec.ServiceAccountRBAC,
 	}
 	c.complete()
-	agentCfg.Targets = append(agentCfg.Targets, c)
+	return c, nil
 }
 
-func addTarget(ctx context.Context, k *kubernetes.Clientset, agentCfg *Config, t v1alpha1.Target) {
+// TargetFromTarget builds a Target from a Target object.
+// kubeconfigSecret must be the secret referenced by the Target, or nil if it targets its own cluster.
+func TargetFromTarget(t *v1alpha1.Target, kubeconfigSecret *corev1.Secret) (Target, error) {
 	if t.Spec.Self == (t.Spec.KubeconfigSecret != nil) {
-		log.Printf("invalid Target %s in namespace %s: self XOR kubeconfigSecret != nil", t.Name, t.Namespace)
-		return
-		// TODO validating webhook to catch user error upstream
+		return Target{}, fmt.Errorf("invalid Target %s in namespace %s: self XOR kubeconfigSecret != nil", t.Name, t.Namespace)
 	}
 	var cfg *rest.Config
 	if kcfg := t.Spec.KubeconfigSecret; kcfg != nil {
 		var err error
-		cfg, err = getConfigFromKubeconfigSecretOrDie(ctx, k, t.Namespace, kcfg.Name, kcfg.Key, kcfg.Context)
+		cfg, err = getConfigFromKubeconfigSecret(kubeconfigSecret, kcfg.Key, kcfg.Context)
 		if err != nil {
-			log.Printf("invalid Target %s in namespace %s: %v", t.Name, t.Namespace, err)
-			return
+			return Target{}, fmt.Errorf("invalid Target %s in namespace %s: %v", t.Name, t.Namespace, err)
 		}
 	} else {
-		cfg = config.GetConfigOrDie()
+		var err error
+		cfg, err = config.GetConfig()
+		if err != nil {
+			return Target{}, err
+		}
 	}
 
 	c := Target{
-		Name:                 t.Name,
-		ClientConfig:         cfg,
-		Namespace:            t.Namespace,
-		Self:                 t.Spec.Self,
-		ExcludedLabelsRegexp: t.Spec.ExcludedLabelsRegexp,
+		Name:                       t.Name,
+		ClientConfig:               cfg,
+		Namespace:                  t.Namespace,
+		Self:                       t.Spec.Self,
+		ExcludedLabelsRegexp:       t.Spec.ExcludedLabelsRegexp,
+		Timeouts:                   t.Spec.Timeouts,
+		Weight:                     t.Spec.Weight,
+		Priority:                   t.Spec.Priority,
+		Tier:                       t.Spec.Tier,
+		PriorityClassMap:           t.Spec.PriorityClassMap,
+		DelegatePodTransformations: t.Spec.DelegatePodTransformations,
+		ServiceAccountRBAC:         t.Spec.ServiceAccountRBAC,
 	}
 	c.complete()
-	agentCfg.Targets = append(agentCfg.Targets, c)
+	return c, nil
 }
 
-func getConfigFromKubeconfigSecretOrDie(ctx context.Context, k *kubernetes.Clientset, namespace, name, key, context string) (*rest.Config, error) {
-	if key == "" {
-		key = "config"
+func getConfigFromKubeconfigSecret(s *corev1.Secret, key, context string) (*rest.Config, error) {
+	if s == nil {
+		return nil, fmt.Errorf("kubeconfig secret not found")
 	}
 
-	s, err := k.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
+	if key == "" {
+		key = "config"
 	}
 
 	cfg0, err := clientcmd.Load(s.Data[key])
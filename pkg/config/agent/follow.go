@@ -0,0 +1,131 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// FollowConfig lists the resources, besides config maps, secrets, services, ingresses and service accounts,
+// that follow proxy pods to target clusters, with the generic follow controller.
+type FollowConfig struct {
+	Resources []FollowedResource `json:"resources,omitempty"`
+}
+
+// FollowedResource is a namespaced kind of object copied to target clusters,
+// either because proxy pods refer to objects of that kind (ReferencedByProxyPods),
+// or because the objects are labeled (LabelSelector), in which case they're copied to all targets.
+type FollowedResource struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	// Resource is the plural resource name, which the Helm chart needs for RBAC, checked against discovery.
+	Resource string `json:"resource"`
+
+	ReferencedByProxyPods *ReferencedByProxyPods `json:"referencedByProxyPods,omitempty"`
+	LabelSelector         *metav1.LabelSelector  `json:"labelSelector,omitempty"`
+
+	// IgnoredFields are dot-separated paths of cluster-specific fields, e.g., "spec.volumeName",
+	// that aren't copied to target clusters, nor compared with the copies.
+	IgnoredFields []string `json:"ignoredFields,omitempty"`
+}
+
+// ReferencedByProxyPods lists JSONPath templates (as in kubectl -o jsonpath) evaluated against proxy pods,
+// e.g., "{.spec.volumes[*].persistentVolumeClaim.claimName}",
+// resulting in the names of the objects that they refer to, in their namespace.
+type ReferencedByProxyPods struct {
+	FieldPaths []string `json:"fieldPaths"`
+}
+
+func (r FollowedResource) GroupVersionKind() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: r.Group, Version: r.Version, Kind: r.Kind}
+}
+
+// LoadFollowConfig reads and validates a YAML follow configuration file. An empty path results in an empty configuration.
+func LoadFollowConfig(path string) (FollowConfig, error) {
+	var c FollowConfig
+	if path == "" {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(b, &c); err != nil {
+		return c, fmt.Errorf("cannot parse follow config: %v", err)
+	}
+	return c, c.validate()
+}
+
+func (c FollowConfig) validate() error {
+	seen := map[schema.GroupVersionKind]bool{}
+	for i, r := range c.Resources {
+		gvk := r.GroupVersionKind()
+		if r.Version == "" || r.Kind == "" || r.Resource == "" {
+			return fmt.Errorf("followed resource %d: version, kind and resource are required", i)
+		}
+		if seen[gvk] {
+			return fmt.Errorf("followed resource %d: %s already followed", i, gvk)
+		}
+		seen[gvk] = true
+		if (r.ReferencedByProxyPods == nil) == (r.LabelSelector == nil) {
+			return fmt.Errorf("followed resource %d (%s): exactly one of referencedByProxyPods and labelSelector must be set", i, gvk)
+		}
+		if p := r.ReferencedByProxyPods; p != nil {
+			if len(p.FieldPaths) == 0 {
+				return fmt.Errorf("followed resource %d (%s): referencedByProxyPods.fieldPaths cannot be empty", i, gvk)
+			}
+			for _, fp := range p.FieldPaths {
+				if err := jsonpath.New("").Parse(fp); err != nil {
+					return fmt.Errorf("followed resource %d (%s): invalid field path %q: %v", i, gvk, fp, err)
+				}
+			}
+		}
+		if s := r.LabelSelector; s != nil {
+			if _, err := metav1.LabelSelectorAsSelector(s); err != nil {
+				return fmt.Errorf("followed resource %d (%s): invalid label selector: %v", i, gvk, err)
+			}
+		}
+		for _, f := range r.IgnoredFields {
+			if err := validateIgnoredField(f); err != nil {
+				return fmt.Errorf("followed resource %d (%s): invalid ignored field %q: %v", i, gvk, f, err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateIgnoredField(f string) error {
+	path := strings.Split(f, ".")
+	for _, p := range path {
+		if p == "" {
+			return fmt.Errorf("empty path segment")
+		}
+	}
+	switch path[0] {
+	case "apiVersion", "kind", "metadata", "status":
+		return fmt.Errorf("%s isn't copied anyway", path[0])
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFollowConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		valid  bool
+	}{
+		{name: "referenced", valid: true, config: `
+resources:
+  - version: v1
+    kind: PersistentVolumeClaim
+    resource: persistentvolumeclaims
+    referencedByProxyPods:
+      fieldPaths: ["{.spec.volumes[*].persistentVolumeClaim.claimName}"]
+    ignoredFields: [spec.volumeName]
+`},
+		{name: "labeled", valid: true, config: `
+resources:
+  - group: policy
+    version: v1
+    kind: PodDisruptionBudget
+    resource: poddisruptionbudgets
+    labelSelector:
+      matchLabels:
+        follow: "true"
+`},
+		{name: "no rule", config: `
+resources:
+  - group: policy
+    version: v1
+    kind: PodDisruptionBudget
+    resource: poddisruptionbudgets
+`},
+		{name: "both rules", config: `
+resources:
+  - group: policy
+    version: v1
+    kind: PodDisruptionBudget
+    resource: poddisruptionbudgets
+    labelSelector: {}
+    referencedByProxyPods:
+      fieldPaths: ["{.metadata.name}"]
+`},
+		{name: "invalid field path", config: `
+resources:
+  - version: v1
+    kind: PersistentVolumeClaim
+    resource: persistentvolumeclaims
+    referencedByProxyPods:
+      fieldPaths: ["{.spec.volumes[*"]
+`},
+		{name: "duplicate", config: `
+resources:
+  - {group: policy, version: v1, kind: PodDisruptionBudget, resource: poddisruptionbudgets, labelSelector: {}}
+  - {group: policy, version: v1, kind: PodDisruptionBudget, resource: poddisruptionbudgets, labelSelector: {}}
+`},
+		{name: "no resource", config: `
+resources:
+  - {group: policy, version: v1, kind: PodDisruptionBudget, labelSelector: {}}
+`},
+		{name: "invalid ignored field", config: `
+resources:
+  - {version: v1, kind: PersistentVolumeClaim, resource: persistentvolumeclaims, labelSelector: {}, ignoredFields: [spec..volumeName]}
+`},
+		{name: "ignored metadata", config: `
+resources:
+  - {version: v1, kind: PersistentVolumeClaim, resource: persistentvolumeclaims, labelSelector: {}, ignoredFields: [metadata.labels]}
+`},
+		{name: "unknown field", config: `
+resources:
+  - {group: policy, version: v1, kind: PodDisruptionBudget, resource: poddisruptionbudgets, selector: {}}
+`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "follow-config")
+			require.NoError(t, os.WriteFile(path, []byte(tc.config), 0644))
+			_, err := LoadFollowConfig(path)
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+
+	c, err := LoadFollowConfig("")
+	require.NoError(t, err)
+	require.Empty(t, c.Resources)
+}
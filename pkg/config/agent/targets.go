@@ -0,0 +1,133 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// TargetHandler is notified when targets are added or removed at runtime.
+// A target whose spec or kubeconfig secret changes is removed, then added again.
+// Handlers must not block.
+type TargetHandler interface {
+	AddTarget(t Target)
+	RemoveTarget(t Target)
+}
+
+// TargetSet is the thread-safe set of current targets, keyed by virtual node name.
+// It is fed by the target controller, and fans out changes to registered handlers,
+// so that adding or removing a Target or ClusterTarget doesn't require a restart.
+type TargetSet struct {
+	mu       sync.RWMutex
+	targets  map[string]Target
+	handlers []TargetHandler
+
+	synced     chan struct{}
+	syncedOnce sync.Once
+}
+
+func NewTargetSet() *TargetSet {
+	return &TargetSet{
+		targets: map[string]Target{},
+		synced:  make(chan struct{}),
+	}
+}
+
+// AddHandler registers h, and calls h.AddTarget for every current target.
+func (s *TargetSet) AddHandler(h TargetHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, h)
+	for _, t := range s.targets {
+		h.AddTarget(t)
+	}
+}
+
+func (s *TargetSet) AddTarget(t Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets[t.VirtualNodeName] = t
+	for _, h := range s.handlers {
+		h.AddTarget(t)
+	}
+}
+
+func (s *TargetSet) RemoveTarget(t Target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.targets[t.VirtualNodeName]; !ok {
+		return
+	}
+	delete(s.targets, t.VirtualNodeName)
+	for _, h := range s.handlers {
+		h.RemoveTarget(t)
+	}
+}
+
+// SetSynced marks the set as synced, i.e., all targets that existed at startup have been added.
+func (s *TargetSet) SetSynced() {
+	s.syncedOnce.Do(func() { close(s.synced) })
+}
+
+// WaitForSync blocks until the set is synced or ctx is done, and returns whether it is synced.
+func (s *TargetSet) WaitForSync(ctx context.Context) bool {
+	select {
+	case <-s.synced:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *TargetSet) Get(virtualNodeName string) (Target, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.targets[virtualNodeName]
+	return t, ok
+}
+
+// List returns the current targets, sorted by virtual node name.
+func (s *TargetSet) List() []Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	l := make([]Target, 0, len(s.targets))
+	for _, t := range s.targets {
+		l = append(l, t)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].VirtualNodeName < l[j].VirtualNodeName })
+	return l
+}
+
+func (s *TargetSet) GetKnownFinalizers() []string {
+	var knownFinalizers []string
+	for _, target := range s.List() {
+		knownFinalizers = append(knownFinalizers, target.Finalizer)
+	}
+	return knownFinalizers
+}
+
+func (s *TargetSet) GetKnownFinalizersInNamespace(namespace string) []string {
+	var knownFinalizers []string
+	for _, target := range s.List() {
+		if target.Namespace == namespace {
+			knownFinalizers = append(knownFinalizers, target.Finalizer)
+		}
+	}
+	return knownFinalizers
+}
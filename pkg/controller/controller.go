@@ -212,20 +212,21 @@ func IndexByRemoteController(parentClusterName string) cache.IndexFunc {
 }
 
 func AddRemoteControllerReference(child metav1.Object, parent metav1.Object, parentClusterName string) {
+	// set labels and annotations after modifying them, because unstructured objects copy them
 	l := child.GetLabels()
 	if l == nil {
 		l = map[string]string{}
-		child.SetLabels(l)
 	}
 	l[common.LabelKeyParentUID] = string(parent.GetUID())
 	l[common.LabelKeyParentClusterName] = parentClusterName
+	child.SetLabels(l)
 	a := child.GetAnnotations()
 	if a == nil {
 		a = map[string]string{}
-		child.SetAnnotations(a)
 	}
 	a[common.AnnotationKeyParentNamespace] = parent.GetNamespace()
 	a[common.AnnotationKeyParentName] = parent.GetName()
+	child.SetAnnotations(a)
 }
 
 func ParentControlsChild(child metav1.Object, parent metav1.Object) bool {
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"time"
 
 	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
@@ -29,6 +30,7 @@ import (
 	customscheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
 	"github.com/go-test/deep"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -144,9 +146,70 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 		}
 	}
 
-	// TODO: support allowed pod spec updates: containers[*].image, initContainers[*].image, activeDeadlineSeconds, tolerations (only additions to tolerations)
-	// (and maintain that current)
-	// we can't just update the whole spec
+	// relay the proxy scheduler's permission to preempt to the candidate pod,
+	// whose update makes the candidate scheduler retry it sooner
+	if v, ok := podChaperon.Annotations[common.AnnotationKeyIsAllowedToPreempt]; ok && pod.Annotations[common.AnnotationKeyIsAllowedToPreempt] != v {
+		patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyIsAllowedToPreempt + `":"` + v + `"}}}`)
+		if _, err := c.kubeclientset.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return nil, fmt.Errorf("cannot patch pod: %v", err)
+		}
+	}
+
+	// apply allowed pod spec updates (pushed by the feedback controller from the proxy pod)
+	// only when the pod chaperon's spec changed, so we don't fight admission controllers of the target cluster
+	// that mutated the pod at creation (e.g., LimitRanger)
+	if generation := strconv.FormatInt(podChaperon.Generation, 10); pod.Annotations[common.AnnotationKeyPodChaperonGeneration] != generation {
+		_, observed := pod.Annotations[common.AnnotationKeyPodChaperonGeneration]
+		updatePod := func(update func(dst *corev1.PodSpec, src *corev1.PodSpec) bool) (*corev1.Pod, error) {
+			podCopy := pod.DeepCopy()
+			if podCopy.Annotations == nil {
+				podCopy.Annotations = map[string]string{}
+			}
+			podCopy.Annotations[common.AnnotationKeyPodChaperonGeneration] = generation
+			// pods created before generations were tracked are only annotated
+			if observed {
+				update(&podCopy.Spec, &podChaperon.Spec)
+			}
+			return c.kubeclientset.CoreV1().Pods(namespace).Update(ctx, podCopy, metav1.UpdateOptions{})
+		}
+
+		updatedPod, err := updatePod(delegatepod.UpdateMutableFields)
+		if errors.IsInvalid(err) {
+			// e.g., in-place resize not enabled in the target cluster, or resources defaulted by LimitRanger,
+			// the other updates (e.g., images) are still applied
+			utilruntime.HandleError(fmt.Errorf("cannot apply pod chaperon %s/%s resources update to pod, retrying without: %v", namespace, name, err))
+			updatedPod, err = updatePod(delegatepod.UpdateMutableFieldsExceptResources)
+		}
+		if err != nil {
+			if controller.IsOptimisticLockError(err) {
+				requeueAfter := time.Second
+				return &requeueAfter, nil
+			}
+			if !errors.IsInvalid(err) {
+				return nil, fmt.Errorf("cannot update pod: %v", err)
+			}
+			// don't retry until the next spec update
+			utilruntime.HandleError(fmt.Errorf("cannot apply pod chaperon %s/%s spec update to pod: %v", namespace, name, err))
+			patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyPodChaperonGeneration + `":"` + generation + `"}}}`)
+			if updatedPod, err = c.kubeclientset.CoreV1().Pods(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return nil, fmt.Errorf("cannot patch pod: %v", err)
+			}
+		}
+		pod = updatedPod
+	}
+
+	// ephemeral containers (e.g., added to the proxy pod by kubectl debug) are only ever added, so we don't need to track generations
+	podCopy := pod.DeepCopy()
+	if delegatepod.AddEphemeralContainers(&podCopy.Spec, podChaperon.Spec.EphemeralContainers) {
+		var err error
+		if pod, err = c.kubeclientset.CoreV1().Pods(namespace).UpdateEphemeralContainers(ctx, name, podCopy, metav1.UpdateOptions{}); err != nil {
+			if controller.IsOptimisticLockError(err) {
+				requeueAfter := time.Second
+				return &requeueAfter, nil
+			}
+			return nil, fmt.Errorf("cannot update pod ephemeral containers: %v", err)
+		}
+	}
 
 	diff := deep.Equal(podChaperon.Status, pod.Status)
 	needStatusUpdate := len(diff) > 0
@@ -196,6 +259,7 @@ func newPod(podChaperon *multiclusterv1alpha1.PodChaperon) *corev1.Pod {
 	for k, v := range podChaperon.Annotations {
 		annotations[k] = v
 	}
+	annotations[common.AnnotationKeyPodChaperonGeneration] = strconv.FormatInt(podChaperon.Generation, 10)
 	labels := make(map[string]string)
 	for k, v := range podChaperon.Labels {
 		labels[k] = v
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package chaperon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	customfake "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/fake"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+)
+
+func TestSpecUpdateWithoutResize(t *testing.T) {
+	ctx := context.Background()
+	cpu := func(q string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(q)}}
+	}
+
+	podChaperon := &multiclusterv1alpha1.PodChaperon{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "delegate", UID: "chaperon-uid", Generation: 1},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "a", Image: "a:1", Resources: cpu("100m")}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	pod := newPod(podChaperon)
+	pod.Status = podChaperon.Status
+
+	// the proxy pod's image and resources were updated
+	podChaperon = podChaperon.DeepCopy()
+	podChaperon.Generation = 2
+	podChaperon.Spec.Containers[0].Image = "a:2"
+	podChaperon.Spec.Containers[0].Resources = cpu("200m")
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	podChaperonIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, podIndexer.Add(pod))
+	require.NoError(t, podChaperonIndexer.Add(podChaperon))
+
+	// in-place resize isn't enabled in the target cluster
+	kubeClient := fake.NewSimpleClientset(pod)
+	kubeClient.PrependReactor("update", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		p := action.(k8stesting.UpdateAction).GetObject().(*corev1.Pod)
+		if !apiequality.Semantic.DeepEqual(p.Spec.Containers[0].Resources, pod.Spec.Containers[0].Resources) {
+			return true, nil, errors.NewInvalid(schema.GroupKind{Kind: "Pod"}, p.Name, field.ErrorList{field.Forbidden(field.NewPath("spec"), "pod updates may not change fields other than ...")})
+		}
+		return false, nil, nil
+	})
+
+	r := &reconciler{
+		kubeclientset:      kubeClient,
+		customclientset:    customfake.NewSimpleClientset(podChaperon),
+		podsLister:         corelisters.NewPodLister(podIndexer),
+		podChaperonsLister: listers.NewPodChaperonLister(podChaperonIndexer),
+	}
+
+	_, err := r.Handle("ns/delegate")
+	require.NoError(t, err)
+
+	// the image is updated anyway, and the generation observed
+	updated, err := kubeClient.CoreV1().Pods("ns").Get(ctx, "delegate", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "a:2", updated.Spec.Containers[0].Image)
+	require.True(t, apiequality.Semantic.DeepEqual(cpu("100m"), updated.Spec.Containers[0].Resources))
+	require.Equal(t, "2", updated.Annotations[common.AnnotationKeyPodChaperonGeneration])
+}
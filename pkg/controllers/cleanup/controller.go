@@ -18,6 +18,7 @@ package cleanup
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -26,58 +27,85 @@ import (
 	"admiralty.io/multicluster-scheduler/pkg/controller"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	networkinginformers "k8s.io/client-go/informers/networking/v1"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	networkinglisters "k8s.io/client-go/listers/networking/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 type reconciler struct {
-	kubeClient kubernetes.Interface
+	kubeClient    kubernetes.Interface
+	dynamicClient dynamic.Interface
 
 	podLister       corelisters.PodLister
 	serviceLister   corelisters.ServiceLister
 	ingressLister   networkinglisters.IngressLister
 	configMapLister corelisters.ConfigMapLister
 	secretLister    corelisters.SecretLister
+	saLister        corelisters.ServiceAccountLister
 
-	knownFinalizers map[string]bool
+	// followed resources, by key kind
+	followed map[string]followedResource
+
+	getKnownFinalizers func() []string
+}
+
+type followedResource struct {
+	gvr    schema.GroupVersionResource
+	lister cache.GenericLister
 }
 
+// NewController returns a controller that removes the finalizers of targets that no longer exist
+// from pods, followed objects, etc.
+// followedInformers are the informers of the resources followed by the generic follow controller, if any.
 func NewController(
 	kubeClient kubernetes.Interface,
+	dynamicClient dynamic.Interface,
 	podInformer coreinformers.PodInformer,
 	serviceInformer coreinformers.ServiceInformer,
 	ingressInformer networkinginformers.IngressInformer,
 	configMapInformer coreinformers.ConfigMapInformer,
 	secretInformer coreinformers.SecretInformer,
-	knownFinalizers []string) *controller.Controller {
+	serviceAccountInformer coreinformers.ServiceAccountInformer,
+	followedInformers map[schema.GroupVersionResource]informers.GenericInformer,
+	getKnownFinalizers func() []string) *controller.Controller {
 
 	r := &reconciler{
 		kubeClient:      kubeClient,
+		dynamicClient:   dynamicClient,
 		podLister:       podInformer.Lister(),
 		serviceLister:   serviceInformer.Lister(),
 		ingressLister:   ingressInformer.Lister(),
 		configMapLister: configMapInformer.Lister(),
 		secretLister:    secretInformer.Lister(),
-		knownFinalizers: map[string]bool{},
-	}
+		saLister:        serviceAccountInformer.Lister(),
+
+		followed: make(map[string]followedResource, len(followedInformers)),
 
-	for _, f := range knownFinalizers {
-		r.knownFinalizers[f] = true
+		getKnownFinalizers: getKnownFinalizers,
 	}
 
-	c := controller.New(
-		"cleanup",
-		r,
+	synced := []cache.InformerSynced{
 		podInformer.Informer().HasSynced,
 		serviceInformer.Informer().HasSynced,
 		ingressInformer.Informer().HasSynced,
 		configMapInformer.Informer().HasSynced,
 		secretInformer.Informer().HasSynced,
-	)
+		serviceAccountInformer.Informer().HasSynced,
+	}
+	for gvr, informer := range followedInformers {
+		r.followed[gvr.String()] = followedResource{gvr: gvr, lister: informer.Lister()}
+		synced = append(synced, informer.Informer().HasSynced)
+	}
+
+	c := controller.New("cleanup", r, synced...)
 
 	enqueue := func(kind string) func(o interface{}) {
 		return func(o interface{}) {
@@ -95,6 +123,10 @@ func NewController(
 	ingressInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueue("Ingress")))
 	configMapInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueue("ConfigMap")))
 	secretInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueue("Secret")))
+	serviceAccountInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueue("ServiceAccount")))
+	for gvr, informer := range followedInformers {
+		informer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueue(gvr.String())))
+	}
 
 	return c
 }
@@ -121,8 +153,19 @@ func (r reconciler) Handle(k interface{}) (requeueAfter *time.Duration, err erro
 		o, err = r.configMapLister.ConfigMaps(t.namespace).Get(t.name)
 	case "Secret":
 		o, err = r.secretLister.Secrets(t.namespace).Get(t.name)
+	case "ServiceAccount":
+		o, err = r.saLister.ServiceAccounts(t.namespace).Get(t.name)
 	default:
-		err = fmt.Errorf("unknown key kind %s", t.kind)
+		f, ok := r.followed[t.kind]
+		if !ok {
+			err = fmt.Errorf("unknown key kind %s", t.kind)
+			break
+		}
+		var obj runtime.Object
+		obj, err = f.lister.ByNamespace(t.namespace).Get(t.name)
+		if err == nil {
+			o = obj.(metav1.Object)
+		}
 	}
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -131,10 +174,19 @@ func (r reconciler) Handle(k interface{}) (requeueAfter *time.Duration, err erro
 		return nil, fmt.Errorf("cannot get %s: %v", t.kind, err)
 	}
 
+	// targets can be added and removed at runtime, so known finalizers are reevaluated every time
+	knownFinalizers := map[string]bool{}
+	for _, f := range r.getKnownFinalizers() {
+		knownFinalizers[f] = true
+	}
+
 	var unknownFinalizers []string
+	var remainingFinalizers []string
 	for _, f := range o.GetFinalizers() {
-		if strings.HasPrefix(f, common.KeyPrefix) && !r.knownFinalizers[f] {
+		if strings.HasPrefix(f, common.KeyPrefix) && !knownFinalizers[f] {
 			unknownFinalizers = append(unknownFinalizers, f)
+		} else {
+			remainingFinalizers = append(remainingFinalizers, f)
 		}
 	}
 
@@ -150,8 +202,15 @@ func (r reconciler) Handle(k interface{}) (requeueAfter *time.Duration, err erro
 			o, err = r.kubeClient.CoreV1().ConfigMaps(t.namespace).Patch(ctx, t.name, types.StrategicMergePatchType, []byte(patch(unknownFinalizers)), metav1.PatchOptions{})
 		case "Secret":
 			o, err = r.kubeClient.CoreV1().Secrets(t.namespace).Patch(ctx, t.name, types.StrategicMergePatchType, []byte(patch(unknownFinalizers)), metav1.PatchOptions{})
+		case "ServiceAccount":
+			o, err = r.kubeClient.CoreV1().ServiceAccounts(t.namespace).Patch(ctx, t.name, types.StrategicMergePatchType, []byte(patch(unknownFinalizers)), metav1.PatchOptions{})
 		default:
-			err = fmt.Errorf("unknown key kind %s", t.kind)
+			// custom resources don't support strategic merge patches
+			var p []byte
+			p, err = mergePatch(remainingFinalizers, o.GetResourceVersion())
+			if err == nil {
+				_, err = r.dynamicClient.Resource(r.followed[t.kind].gvr).Namespace(t.namespace).Patch(ctx, t.name, types.MergePatchType, p, metav1.PatchOptions{})
+			}
 		}
 		if err != nil {
 			return nil, fmt.Errorf("cannot patch %s: %v", t.kind, err)
@@ -164,3 +223,13 @@ func (r reconciler) Handle(k interface{}) (requeueAfter *time.Duration, err erro
 func patch(finalizers []string) string {
 	return `{"metadata":{"$deleteFromPrimitiveList/finalizers":["` + strings.Join(finalizers, `","`) + `"]}}`
 }
+
+// mergePatch replaces the finalizers of an object, if it hasn't changed since it was observed (optimistic concurrency)
+func mergePatch(finalizers []string, resourceVersion string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": resourceVersion,
+		},
+	})
+}
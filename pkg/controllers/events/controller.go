@@ -0,0 +1,239 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+)
+
+// the recorder's spam filter limits events per proxy pod,
+// to avoid flooding the source cluster with events from busy target clusters
+const (
+	eventsBurstSize = 25
+	eventsQPS       = 1. / 60.
+)
+
+// reconciler mirrors events involving delegate pods (and their pod chaperons) in a target cluster
+// onto the corresponding proxy pods in the source cluster
+type reconciler struct {
+	clusterName string
+	target      agent.Target
+
+	podsLister         corelisters.PodLister
+	eventsLister       corelisters.EventLister
+	podChaperonsLister listers.PodChaperonLister
+
+	recorder record.EventRecorder
+
+	// events that happened before startTime were already mirrored (or are too old to matter)
+	startTime time.Time
+
+	// mirroredCounts deduplicates events that are updated (e.g., on resync) without their count increasing
+	mirroredCountsMx sync.Mutex
+	mirroredCounts   map[types.UID]int32
+}
+
+// NewController returns a new events controller. Its event broadcaster is shut down when ctx is done,
+// i.e., when the target is removed or reloaded.
+func NewController(
+	ctx context.Context,
+	clusterName string,
+	target agent.Target,
+	kubeclientset kubernetes.Interface,
+	podInformer coreinformers.PodInformer,
+	targetEventInformer coreinformers.EventInformer,
+	targetPodChaperonInformer informers.PodChaperonInformer) *controller.Controller {
+
+	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize: eventsBurstSize,
+		QPS:       eventsQPS,
+	})
+	eventBroadcaster.StartStructuredLogging(4)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "admiralty"})
+	go func() {
+		<-ctx.Done()
+		eventBroadcaster.Shutdown()
+	}()
+
+	r := &reconciler{
+		clusterName: clusterName,
+		target:      target,
+
+		podsLister:         podInformer.Lister(),
+		eventsLister:       targetEventInformer.Lister(),
+		podChaperonsLister: targetPodChaperonInformer.Lister(),
+
+		recorder: recorder,
+
+		startTime:      time.Now(),
+		mirroredCounts: map[types.UID]int32{},
+	}
+
+	c := controller.New("events", r, podInformer.Informer().HasSynced, targetEventInformer.Informer().HasSynced, targetPodChaperonInformer.Informer().HasSynced)
+
+	enqueueDelegateEvent := func(obj interface{}) {
+		if e, ok := obj.(*corev1.Event); ok && involvesDelegate(e) {
+			c.EnqueueObject(obj)
+		}
+	}
+	targetEventInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueueDelegateEvent,
+		UpdateFunc: func(_, newObj interface{}) { enqueueDelegateEvent(newObj) },
+		DeleteFunc: r.forget,
+	})
+
+	return c
+}
+
+func involvesDelegate(e *corev1.Event) bool {
+	o := e.InvolvedObject
+	return o.Kind == "Pod" && o.APIVersion == "v1" ||
+		o.Kind == "PodChaperon" && o.APIVersion == "multicluster.admiralty.io/v1alpha1"
+}
+
+func (r *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
+	key := obj.(string)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	utilruntime.Must(err)
+
+	e, err := r.eventsLister.Events(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	count, lastTime := countAndLastTime(e)
+	if lastTime.Before(r.startTime) || !r.shouldMirror(e.UID, count) {
+		return nil, nil
+	}
+
+	// delegate pods are named after their pod chaperons
+	podChaperon, err := r.podChaperonsLister.PodChaperons(e.InvolvedObject.Namespace).Get(e.InvolvedObject.Name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// not a delegate pod, or already deleted
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !controller.IsRemoteControlled(podChaperon, r.clusterName) {
+		return nil, nil
+	}
+	if e.InvolvedObject.Kind == "PodChaperon" && e.InvolvedObject.UID != podChaperon.UID {
+		// old event involving a previous pod chaperon with the same name
+		return nil, nil
+	}
+
+	proxyNamespace, proxyName, err := cache.SplitMetaNamespaceKey(controller.ParentKey(podChaperon))
+	utilruntime.Must(err)
+	proxyPod, err := r.podsLister.Pods(proxyNamespace).Get(proxyName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if string(proxyPod.UID) != podChaperon.Labels[common.LabelKeyParentUID] {
+		return nil, nil
+	}
+
+	r.recorder.Event(proxyPod, e.Type, e.Reason, r.message(e))
+	r.setMirroredCount(e.UID, count)
+	return nil, nil
+}
+
+// message prefixes the original message with its origin, e.g., "kubelet in target cloud: ..."
+func (r *reconciler) message(e *corev1.Event) string {
+	component := e.Source.Component
+	if component == "" {
+		component = e.ReportingController
+	}
+	if component == "" {
+		return fmt.Sprintf("in target %s: %s", r.target.Name, e.Message)
+	}
+	return fmt.Sprintf("%s in target %s: %s", component, r.target.Name, e.Message)
+}
+
+// countAndLastTime supports both core/v1 events and events.k8s.io/v1 events (with series)
+func countAndLastTime(e *corev1.Event) (int32, time.Time) {
+	if e.Series != nil {
+		return e.Series.Count, e.Series.LastObservedTime.Time
+	}
+	if !e.LastTimestamp.IsZero() {
+		return e.Count, e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.Count, e.EventTime.Time
+	}
+	return e.Count, e.CreationTimestamp.Time
+}
+
+func (r *reconciler) shouldMirror(uid types.UID, count int32) bool {
+	r.mirroredCountsMx.Lock()
+	defer r.mirroredCountsMx.Unlock()
+	mirroredCount, ok := r.mirroredCounts[uid]
+	return !ok || count > mirroredCount
+}
+
+func (r *reconciler) setMirroredCount(uid types.UID, count int32) {
+	r.mirroredCountsMx.Lock()
+	defer r.mirroredCountsMx.Unlock()
+	r.mirroredCounts[uid] = count
+}
+
+func (r *reconciler) forget(obj interface{}) {
+	e, ok := obj.(*corev1.Event)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		e, ok = tombstone.Obj.(*corev1.Event)
+		if !ok {
+			return
+		}
+	}
+	klog.V(5).Infof("forgetting event %s/%s", e.Namespace, e.Name)
+	r.mirroredCountsMx.Lock()
+	defer r.mirroredCountsMx.Unlock()
+	delete(r.mirroredCounts, e.UID)
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+)
+
+func TestHandle(t *testing.T) {
+	now := time.Now()
+
+	proxyPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy", UID: "proxy-uid"}}
+	podChaperon := &v1alpha1.PodChaperon{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy-abcde", UID: "chaperon-uid"}}
+	controller.AddRemoteControllerReference(podChaperon, proxyPod, "source")
+	otherPodChaperon := &v1alpha1.PodChaperon{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}}
+	controller.AddRemoteControllerReference(otherPodChaperon, proxyPod, "other-source")
+
+	event := func(name string, kind string, involvedName string, count int32, lastTimestamp time.Time) *corev1.Event {
+		e := &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: name, UID: types.UID(name)},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Namespace: "ns", Name: involvedName},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			Message:        "Back-off pulling image",
+			Source:         corev1.EventSource{Component: "kubelet"},
+			Count:          count,
+			LastTimestamp:  metav1.NewTime(lastTimestamp),
+		}
+		if kind == "Pod" {
+			e.InvolvedObject.APIVersion = "v1"
+		} else {
+			e.InvolvedObject.APIVersion = "multicluster.admiralty.io/v1alpha1"
+			e.InvolvedObject.UID = podChaperon.UID
+		}
+		return e
+	}
+
+	podsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podsIndexer.Add(proxyPod))
+	podChaperonsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podChaperonsIndexer.Add(podChaperon))
+	require.NoError(t, podChaperonsIndexer.Add(otherPodChaperon))
+	eventsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	recorder := record.NewFakeRecorder(10)
+	r := &reconciler{
+		clusterName:        "source",
+		target:             agent.Target{Name: "cloud"},
+		podsLister:         corelisters.NewPodLister(podsIndexer),
+		eventsLister:       corelisters.NewEventLister(eventsIndexer),
+		podChaperonsLister: listers.NewPodChaperonLister(podChaperonsIndexer),
+		recorder:           recorder,
+		startTime:          now.Add(-time.Minute),
+		mirroredCounts:     map[types.UID]int32{},
+	}
+
+	handle := func(e *corev1.Event) {
+		require.NoError(t, eventsIndexer.Update(e))
+		_, err := r.Handle(e.Namespace + "/" + e.Name)
+		require.NoError(t, err)
+	}
+	requireRecorded := func(expected ...string) {
+		for _, e := range expected {
+			require.Equal(t, e, <-recorder.Events)
+		}
+		require.Empty(t, recorder.Events)
+	}
+
+	handle(event("pod", "Pod", podChaperon.Name, 1, now))
+	requireRecorded("Warning BackOff kubelet in target cloud: Back-off pulling image")
+
+	// resync or unrelated update
+	handle(event("pod", "Pod", podChaperon.Name, 1, now))
+	requireRecorded()
+
+	// aggregated by the target cluster
+	handle(event("pod", "Pod", podChaperon.Name, 2, now))
+	requireRecorded("Warning BackOff kubelet in target cloud: Back-off pulling image")
+
+	handle(event("chaperon", "PodChaperon", podChaperon.Name, 1, now))
+	requireRecorded("Warning BackOff kubelet in target cloud: Back-off pulling image")
+
+	// before the controller started
+	handle(event("old", "Pod", podChaperon.Name, 1, now.Add(-time.Hour)))
+	// not a delegate pod
+	handle(event("regular", "Pod", "regular", 1, now))
+	// delegate pod of another source cluster
+	handle(event("other", "Pod", otherPodChaperon.Name, 1, now))
+	requireRecorded()
+
+	r.forget(cache.DeletedFinalStateUnknown{Obj: event("pod", "Pod", podChaperon.Name, 2, now)})
+	require.NotContains(t, r.mirroredCounts, types.UID("pod"))
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package failover
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
+)
+
+const proxyPodsByNodeName = "proxyPodsByNodeName"
+
+var targetAvailable = corev1.PodConditionType(common.PodConditionTypeTargetAvailable)
+
+// Options configure how the failover controller reacts to unavailable targets.
+type Options struct {
+	// GracePeriod is how long a target's virtual node must be not ready
+	// before the proxy pods bound to it are marked as not ready (Unknown).
+	GracePeriod time.Duration
+	// Toleration is how long a target's virtual node must be not ready
+	// before the proxy pods bound to it are deleted (or evicted), so that their controllers recreate them elsewhere.
+	// Zero disables deletion.
+	Toleration time.Duration
+	// Evict makes the controller evict proxy pods, respecting pod disruption budgets, rather than delete them.
+	Evict bool
+}
+
+type reconciler struct {
+	kubeclientset kubernetes.Interface
+
+	podsLister  corelisters.PodLister
+	nodesLister corelisters.NodeLister
+
+	options Options
+	now     func() time.Time
+}
+
+// NewController returns a new failover controller, which fails over proxy pods bound to the virtual nodes of targets
+// that have been unavailable (not ready) for too long. It also removes the targets' finalizers from terminating proxy pods,
+// because the targets' feedback controllers can't delete their pod chaperons.
+func NewController(
+	kubeclientset kubernetes.Interface,
+	podInformer coreinformers.PodInformer,
+	nodeInformer coreinformers.NodeInformer,
+	options Options) *controller.Controller {
+
+	if options.Toleration > 0 && options.Toleration < options.GracePeriod {
+		// pods are always marked before they're deleted, which the feedback controller relies on
+		options.GracePeriod = options.Toleration
+	}
+
+	r := &reconciler{
+		kubeclientset: kubeclientset,
+
+		podsLister:  podInformer.Lister(),
+		nodesLister: nodeInformer.Lister(),
+
+		options: options,
+		now:     time.Now,
+	}
+
+	c := controller.New("failover", r, podInformer.Informer().HasSynced, nodeInformer.Informer().HasSynced)
+
+	podInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(func(obj interface{}) {
+		pod := obj.(*corev1.Pod)
+		if proxypod.IsProxy(pod) && pod.Spec.NodeName != "" {
+			c.EnqueueObject(obj)
+		}
+	}))
+
+	podIndexer := podInformer.Informer().GetIndexer()
+	utilruntime.Must(podInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+		proxyPodsByNodeName: func(obj interface{}) ([]string, error) {
+			pod, ok := obj.(*corev1.Pod)
+			if !ok || !proxypod.IsProxy(pod) || pod.Spec.NodeName == "" {
+				return nil, nil
+			}
+			return []string{pod.Spec.NodeName}, nil
+		},
+	}))
+
+	nodeInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(func(obj interface{}) {
+		node := obj.(*corev1.Node)
+		if node.Labels[common.LabelAndTaintKeyVirtualKubeletProvider] != common.VirtualKubeletProviderName {
+			return
+		}
+		pods, err := podIndexer.ByIndex(proxyPodsByNodeName, node.Name)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+		for _, pod := range pods {
+			c.EnqueueObject(pod)
+		}
+	}))
+
+	return c
+}
+
+func (r *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
+	ctx := context.Background()
+
+	key := obj.(string)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	utilruntime.Must(err)
+
+	pod, err := r.podsLister.Pods(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !proxypod.IsProxy(pod) || pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+
+	node, err := r.nodesLister.Get(pod.Spec.NodeName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// the pod garbage collector deletes pods bound to deleted nodes
+			return nil, nil
+		}
+		return nil, err
+	}
+	if node.Labels[common.LabelAndTaintKeyVirtualKubeletProvider] != common.VirtualKubeletProviderName {
+		return nil, nil
+	}
+
+	since, unavailable := unavailableSince(node)
+	if !unavailable {
+		if c := getPodCondition(pod.Status.Conditions, targetAvailable); c != nil && c.Status == corev1.ConditionFalse {
+			// the feedback controller will overwrite the status with the delegate pod's
+			podCopy := pod.DeepCopy()
+			setPodCondition(&podCopy.Status, corev1.PodCondition{Type: targetAvailable, Status: corev1.ConditionTrue, Reason: "TargetAvailable"}, r.now())
+			if _, err := r.kubeclientset.CoreV1().Pods(namespace).UpdateStatus(ctx, podCopy, metav1.UpdateOptions{}); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	elapsed := r.now().Sub(since)
+	if elapsed < r.options.GracePeriod {
+		return durationPtr(r.options.GracePeriod - elapsed), nil
+	}
+
+	if podCopy := pod.DeepCopy(); markUnavailable(&podCopy.Status, node, since, r.now()) {
+		if pod, err = r.kubeclientset.CoreV1().Pods(namespace).UpdateStatus(ctx, podCopy, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		// the target's feedback controller can't delete the pod chaperon, so we remove its finalizer in its stead;
+		// if the target comes back, its feedback controller will delete the orphaned pod chaperon
+		finalizer, ok := targetFinalizer(node)
+		if !ok {
+			return nil, nil
+		}
+		if hasFinalizer, j := controller.HasFinalizer(pod.Finalizers, finalizer); hasFinalizer {
+			podCopy := pod.DeepCopy()
+			podCopy.Finalizers = append(podCopy.Finalizers[:j], podCopy.Finalizers[j+1:]...)
+			if _, err := r.kubeclientset.CoreV1().Pods(namespace).Update(ctx, podCopy, metav1.UpdateOptions{}); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	if r.options.Toleration <= 0 {
+		return nil, nil
+	}
+	if elapsed < r.options.Toleration {
+		return durationPtr(r.options.Toleration - elapsed), nil
+	}
+
+	preconditions := metav1.NewUIDPreconditions(string(pod.UID))
+	if r.options.Evict {
+		eviction := &policyv1.Eviction{
+			ObjectMeta:    metav1.ObjectMeta{Namespace: namespace, Name: name},
+			DeleteOptions: &metav1.DeleteOptions{Preconditions: preconditions},
+		}
+		if err := r.kubeclientset.PolicyV1().Evictions(namespace).Evict(ctx, eviction); err != nil {
+			if errors.IsTooManyRequests(err) {
+				// disallowed by a pod disruption budget, try again later
+				return durationPtr(10 * time.Second), nil
+			}
+			if !errors.IsNotFound(err) {
+				return nil, fmt.Errorf("cannot evict proxy pod: %v", err)
+			}
+		}
+	} else {
+		if err := r.kubeclientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{Preconditions: preconditions}); err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("cannot delete proxy pod: %v", err)
+		}
+	}
+
+	return nil, nil
+}
+
+// unavailableSince returns when a virtual node's Ready condition became Unknown or False, if it is.
+// Virtual nodes without a Ready condition are considered available.
+func unavailableSince(node *corev1.Node) (time.Time, bool) {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			if c.Status == corev1.ConditionTrue {
+				return time.Time{}, false
+			}
+			return c.LastTransitionTime.Time, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// markUnavailable sets a proxy pod's TargetAvailable condition to False, and its Ready and ContainersReady conditions
+// to Unknown (as the node lifecycle controller would do for a regular pod), so it is removed from service endpoints,
+// and the feedback controller stops syncing its status from its delegate pod's last known status.
+// It returns whether the status changed.
+func markUnavailable(status *corev1.PodStatus, node *corev1.Node, since time.Time, now time.Time) bool {
+	msg := fmt.Sprintf("virtual node %s has been unavailable since %s", node.Name, since.Format(time.RFC3339))
+	changed := setPodCondition(status, corev1.PodCondition{Type: targetAvailable, Status: corev1.ConditionFalse, Reason: "TargetUnavailable", Message: msg}, now)
+	for _, t := range []corev1.PodConditionType{corev1.PodReady, corev1.ContainersReady} {
+		if setPodCondition(status, corev1.PodCondition{Type: t, Status: corev1.ConditionUnknown, Reason: "TargetUnavailable", Message: msg}, now) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func targetFinalizer(node *corev1.Node) (string, bool) {
+	if name, ok := node.Labels[common.LabelKeyClusterTargetName]; ok {
+		return agent.Finalizer("", name), true
+	}
+	namespace, ok1 := node.Labels[common.LabelKeyTargetNamespace]
+	name, ok2 := node.Labels[common.LabelKeyTargetName]
+	if ok1 && ok2 {
+		return agent.Finalizer(namespace, name), true
+	}
+	return "", false
+}
+
+func getPodCondition(conditions []corev1.PodCondition, t corev1.PodConditionType) *corev1.PodCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// setPodCondition sets or adds a condition, keeping its transition time if its status didn't change,
+// and returns whether anything changed
+func setPodCondition(status *corev1.PodStatus, c corev1.PodCondition, now time.Time) bool {
+	c.LastTransitionTime = metav1.Time{Time: now}
+	existing := getPodCondition(status.Conditions, c.Type)
+	if existing == nil {
+		status.Conditions = append(status.Conditions, c)
+		return true
+	}
+	if existing.Status == c.Status && existing.Reason == c.Reason && existing.Message == c.Message {
+		return false
+	}
+	if existing.Status == c.Status {
+		c.LastTransitionTime = existing.LastTransitionTime
+	}
+	*existing = c
+	return true
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+)
+
+func TestHandle(t *testing.T) {
+	now := time.Now()
+	finalizer := agent.Finalizer("ns", "cloud")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "admiralty-ns-cloud", Labels: map[string]string{
+			common.LabelAndTaintKeyVirtualKubeletProvider: common.VirtualKubeletProviderName,
+			common.LabelKeyTargetNamespace:                "ns",
+			common.LabelKeyTargetName:                     "cloud",
+		}},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, LastTransitionTime: metav1.NewTime(now.Add(-time.Minute))},
+		}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy", UID: "proxy-uid", Finalizers: []string{finalizer}},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: node.Name},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		}},
+	}
+
+	client := fake.NewSimpleClientset(pod)
+	podsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodesIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podsIndexer.Add(pod))
+	require.NoError(t, nodesIndexer.Add(node))
+	r := &reconciler{
+		kubeclientset: client,
+		podsLister:    corelisters.NewPodLister(podsIndexer),
+		nodesLister:   corelisters.NewNodeLister(nodesIndexer),
+		options:       Options{GracePeriod: 40 * time.Second, Toleration: 5 * time.Minute},
+		now:           func() time.Time { return now },
+	}
+	ctx := context.Background()
+	get := func() *corev1.Pod {
+		p, err := client.CoreV1().Pods("ns").Get(ctx, "proxy", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.NoError(t, podsIndexer.Update(p))
+		return p
+	}
+
+	// unavailable for longer than the grace period: marked, requeued until the toleration
+	requeueAfter, err := r.Handle("ns/proxy")
+	require.NoError(t, err)
+	require.Equal(t, 4*time.Minute, *requeueAfter)
+	p := get()
+	c := getPodCondition(p.Status.Conditions, targetAvailable)
+	require.NotNil(t, c)
+	require.Equal(t, corev1.ConditionFalse, c.Status)
+	require.Equal(t, corev1.ConditionUnknown, getPodCondition(p.Status.Conditions, corev1.PodReady).Status)
+
+	// unavailable for longer than the toleration: deleted
+	r.now = func() time.Time { return now.Add(5 * time.Minute) }
+	requeueAfter, err = r.Handle("ns/proxy")
+	require.NoError(t, err)
+	require.Nil(t, requeueAfter)
+	_, err = client.CoreV1().Pods("ns").Get(ctx, "proxy", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+
+	// terminating (e.g., deleted by the taint manager): the target's finalizer is removed
+	p.DeletionTimestamp = &metav1.Time{Time: now}
+	p.Finalizers = []string{finalizer, "other"}
+	client = fake.NewSimpleClientset(p)
+	r.kubeclientset = client
+	require.NoError(t, podsIndexer.Update(p))
+	_, err = r.Handle("ns/proxy")
+	require.NoError(t, err)
+	require.Equal(t, []string{"other"}, get().Finalizers)
+
+	// available again
+	node.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, nodesIndexer.Update(node))
+	requeueAfter, err = r.Handle("ns/proxy")
+	require.NoError(t, err)
+	require.Nil(t, requeueAfter)
+	require.Equal(t, corev1.ConditionTrue, getPodCondition(get().Status.Conditions, targetAvailable).Status)
+}
+
+func TestHandleGracePeriod(t *testing.T) {
+	now := time.Now()
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "admiralty-cloud", Labels: map[string]string{
+			common.LabelAndTaintKeyVirtualKubeletProvider: common.VirtualKubeletProviderName,
+		}},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: corev1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(-10 * time.Second))},
+		}},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: node.Name},
+	}
+	podsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	nodesIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podsIndexer.Add(pod))
+	require.NoError(t, nodesIndexer.Add(node))
+	client := fake.NewSimpleClientset(pod)
+	r := &reconciler{
+		kubeclientset: client,
+		podsLister:    corelisters.NewPodLister(podsIndexer),
+		nodesLister:   corelisters.NewNodeLister(nodesIndexer),
+		options:       Options{GracePeriod: 40 * time.Second},
+		now:           func() time.Time { return now },
+	}
+
+	requeueAfter, err := r.Handle("ns/proxy")
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, *requeueAfter)
+	require.Empty(t, client.Actions())
+}
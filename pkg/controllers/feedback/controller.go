@@ -33,11 +33,8 @@ import (
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
-	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/record"
-	"k8s.io/klog/v2"
 
 	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/common"
@@ -46,6 +43,7 @@ import (
 	customscheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
 	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
 )
 
@@ -56,6 +54,9 @@ const podChaperonByPodNamespacedName = "podChaperonByPodNamespacedName"
 type reconciler struct {
 	clusterName string
 	target      agent.Target
+	// transformer re-applies the target's delegate pod transformations to spec updates
+	transformer    delegatepod.Pipeline
+	transformerErr error
 
 	kubeclientset   kubernetes.Interface
 	customclientset clientset.Interface
@@ -76,14 +77,19 @@ func NewController(
 	podChaperonInformer informers.PodChaperonInformer) *controller.Controller {
 
 	utilruntime.Must(customscheme.AddToScheme(scheme.Scheme))
-	klog.V(4).Info("Creating event broadcaster")
-	eventBroadcaster := record.NewBroadcaster()
-	eventBroadcaster.StartLogging(klog.Infof)
-	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
+
+	// transformations that don't compile (see target.Validator), e.g., referring to custom transformers that aren't compiled in,
+	// only skip spec updates: the proxy scheduler doesn't make new candidates for such targets, but existing delegates are still handled
+	transformer, transformerErr := delegatepod.NewPipeline(target.DelegatePodTransformations)
+	if transformerErr != nil {
+		utilruntime.HandleError(fmt.Errorf("target %s: spec updates won't be pushed to delegate pods: %v", target.VirtualNodeName, transformerErr))
+	}
 
 	r := &reconciler{
-		clusterName: clusterName,
-		target:      target,
+		clusterName:    clusterName,
+		target:         target,
+		transformer:    transformer,
+		transformerErr: transformerErr,
 
 		kubeclientset:   kubeclientset,
 		customclientset: customclientset,
@@ -159,7 +165,16 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 	if proxyPodTerminating || virtualNodeName != "" && virtualNodeName != c.target.VirtualNodeName {
 		if candidate != nil {
 			if err := c.customclientset.MulticlusterV1alpha1().PodChaperons(namespace).Delete(ctx, candidate.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
-				return nil, err
+				if !proxyPodTerminating || !proxyPodHasFinalizer || !targetUnavailable(proxyPod) {
+					return nil, err
+				}
+				// the target doesn't answer and has been unavailable for a while (see the failover controller),
+				// so we let the proxy pod go; if the target comes back, the orphaned pod chaperon will be deleted (see above)
+				utilruntime.HandleError(fmt.Errorf("cannot delete pod chaperon %s/%s in unavailable target %s, removing finalizer from proxy pod %s anyway: %v", namespace, candidate.Name, c.target.VirtualNodeName, key, err))
+				if _, err := c.removeFinalizer(ctx, proxyPod, j); err != nil {
+					return nil, err
+				}
+				return nil, nil
 			}
 		} else if proxyPodHasFinalizer {
 			if proxyPod, err = c.removeFinalizer(ctx, proxyPod, j); err != nil {
@@ -200,7 +215,8 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 			// we can't group annotation and status updates into an update,
 			// because general update ignores status
 			filteredDelegateStatus := filterContainerStatus(&proxyPod.Spec, delegate.Status)
-			needStatusUpdate := deep.Equal(proxyPod.Status, filteredDelegateStatus) != nil
+			// while the target is unavailable, the delegate pod's status in the informer cache is stale
+			needStatusUpdate := !targetUnavailable(proxyPod) && deep.Equal(proxyPod.Status, filteredDelegateStatus) != nil
 			if needStatusUpdate {
 				podCopy := proxyPod.DeepCopy()
 				podCopy.Status = filteredDelegateStatus
@@ -211,13 +227,25 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 				}
 			}
 
-			needRemoteUpdate := delegate.Labels[common.LabelKeyParentClusterName] != c.clusterName
+			// push allowed spec updates (e.g., kubectl set image, in-place resize) to the delegate pod chaperon,
+			// whose controller applies them to the delegate pod
+			delegateCopy := delegate.DeepCopy()
+			specChanged := false
+			if c.transformerErr == nil {
+				specChanged, err = delegatepod.UpdateFromProxyPod(delegateCopy, proxyPod, c.transformer)
+				if err != nil {
+					// e.g., a transformation fails on the updated spec; retrying won't help until the proxy pod changes again
+					utilruntime.HandleError(fmt.Errorf("cannot compute delegate pod spec update for proxy pod %s, skipping it: %v", key, err))
+					delegateCopy = delegate.DeepCopy()
+					specChanged = false
+				}
+			}
+
+			needRemoteUpdate := specChanged || delegate.Labels[common.LabelKeyParentClusterName] != c.clusterName
 			if needRemoteUpdate {
-				delegateCopy := delegate.DeepCopy()
 				delegateCopy.Labels[common.LabelKeyParentClusterName] = c.clusterName
-				var err error
 				if delegate, err = c.customclientset.MulticlusterV1alpha1().PodChaperons(namespace).Update(ctx, delegateCopy, metav1.UpdateOptions{}); err != nil {
-					return nil, fmt.Errorf("cannot update candidate pod chaperon")
+					return nil, fmt.Errorf("cannot update candidate pod chaperon: %v", err)
 				}
 			}
 		} else {
@@ -288,6 +316,16 @@ func hasEphemeralContainerName(containers []corev1.EphemeralContainer) func(name
 	}
 }
 
+// targetUnavailable returns whether the failover controller marked the proxy pod's target as unavailable
+func targetUnavailable(proxyPod *corev1.Pod) bool {
+	for _, c := range proxyPod.Status.Conditions {
+		if c.Type == corev1.PodConditionType(common.PodConditionTypeTargetAvailable) {
+			return c.Status == corev1.ConditionFalse
+		}
+	}
+	return false
+}
+
 func (c *reconciler) removeFinalizer(ctx context.Context, pod *corev1.Pod, j int) (*corev1.Pod, error) {
 	podCopy := pod.DeepCopy()
 	podCopy.Finalizers = append(podCopy.Finalizers[:j], podCopy.Finalizers[j+1:]...)
@@ -0,0 +1,375 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
+)
+
+type reconciler struct {
+	clusterName string
+	target      agentconfig.Target
+
+	client       dynamic.NamespaceableResourceInterface
+	remoteClient dynamic.NamespaceableResourceInterface
+
+	lister       cache.GenericLister
+	remoteLister cache.GenericLister
+
+	ignoredFields [][]string
+
+	// either
+	podIndex  cache.Indexer
+	indexName string
+	// or
+	selector labels.Selector
+}
+
+// NewController returns a controller that copies objects of a followed resource to a target cluster,
+// where they're controlled by their originals, with the same finalizer and remote controller reference pattern
+// as the config map and secret follow controllers.
+func NewController(
+	clusterName string,
+	target agentconfig.Target,
+	resource agentconfig.FollowedResource,
+	gvr schema.GroupVersionResource,
+
+	client dynamic.Interface,
+	remoteClient dynamic.Interface,
+
+	podInformer coreinformers.PodInformer,
+	informer informers.GenericInformer,
+
+	remoteInformer informers.GenericInformer) *controller.Controller {
+
+	r := &reconciler{
+		clusterName: clusterName,
+		target:      target,
+
+		client:       client.Resource(gvr),
+		remoteClient: remoteClient.Resource(gvr),
+
+		lister:       informer.Lister(),
+		remoteLister: remoteInformer.Lister(),
+	}
+	for _, f := range resource.IgnoredFields {
+		r.ignoredFields = append(r.ignoredFields, strings.Split(f, "."))
+	}
+
+	synced := []cache.InformerSynced{
+		informer.Informer().HasSynced,
+		remoteInformer.Informer().HasSynced,
+	}
+	if p := resource.ReferencedByProxyPods; p != nil {
+		synced = append(synced, podInformer.Informer().HasSynced)
+	}
+
+	c := controller.New(gvr.GroupResource().String()+"-follow", r, synced...)
+
+	if p := resource.ReferencedByProxyPods; p != nil {
+		r.podIndex = podInformer.Informer().GetIndexer()
+		r.indexName = "proxyPodBy/" + gvr.String()
+		index := indexProxyPodByReferences(p.FieldPaths)
+		utilruntime.Must(podInformer.Informer().AddIndexers(map[string]cache.IndexFunc{r.indexName: index}))
+		podInformer.Informer().AddEventHandler(controller.HandleAllWith(func(obj interface{}) {
+			keys, _ := index(obj)
+			for _, key := range keys {
+				c.EnqueueKey(key)
+			}
+		}))
+	} else {
+		s, err := metav1.LabelSelectorAsSelector(resource.LabelSelector)
+		utilruntime.Must(err) // validated when loading the config
+		r.selector = s
+	}
+
+	informer.Informer().AddEventHandler(controller.HandleAddUpdateWith(c.EnqueueObject))
+
+	remoteInformer.Informer().AddEventHandler(controller.HandleAllWith(c.EnqueueRemoteController(clusterName)))
+
+	return c
+}
+
+// indexProxyPodByReferences returns an index function that evaluates JSONPath templates against proxy pods,
+// and indexes them by the namespaced names that they result in.
+func indexProxyPodByReferences(fieldPaths []string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		pod, ok := obj.(*corev1.Pod)
+		if !ok || !proxypod.IsProxy(pod) {
+			return nil, nil
+		}
+		u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+		if err != nil {
+			return nil, err
+		}
+		var keys []string
+		for _, fp := range fieldPaths {
+			// JSONPath objects aren't safe for concurrent use, and index functions may be called concurrently
+			j := jsonpath.New("").AllowMissingKeys(true)
+			if err := j.Parse(fp); err != nil {
+				return nil, err // validated when loading the config
+			}
+			results, err := j.FindResults(u)
+			if err != nil {
+				return nil, err
+			}
+			for _, values := range results {
+				for _, v := range values {
+					if name, ok := v.Interface().(string); ok && name != "" {
+						keys = append(keys, fmt.Sprintf("%s/%s", pod.Namespace, name))
+					}
+				}
+			}
+		}
+		return keys, nil
+	}
+}
+
+func (r reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
+	ctx := context.Background()
+
+	key := obj.(string)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	utilruntime.Must(err)
+
+	var remote *unstructured.Unstructured
+	remoteObj, err := r.remoteLister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+	} else {
+		remote = remoteObj.(*unstructured.Unstructured)
+	}
+
+	localObj, err := r.lister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if remote != nil && controller.IsRemoteControlled(remote, r.clusterName) {
+				if err := r.remoteClient.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+					return nil, fmt.Errorf("cannot delete orphaned object: %v", err)
+				}
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+	local := localObj.(*unstructured.Unstructured)
+
+	terminating := local.GetDeletionTimestamp() != nil
+
+	hasFinalizer, j := controller.HasFinalizer(local.GetFinalizers(), r.target.Finalizer)
+
+	shouldFollow := r.shouldFollow(local)
+
+	// eponymous remote objects that aren't controlled by the local object are left alone
+	if remote != nil && !controller.ParentControlsChild(remote, local) {
+		return nil, nil
+	}
+
+	if terminating {
+		if remote != nil {
+			if err := r.remoteClient.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		} else if hasFinalizer {
+			if _, err := r.removeFinalizer(ctx, local, j); err != nil {
+				return nil, err
+			}
+		}
+	} else if shouldFollow {
+		if !hasFinalizer {
+			local, err = r.addFinalizer(ctx, local)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if remote == nil {
+			gold := r.makeRemote(local)
+			_, err := r.remoteClient.Namespace(namespace).Create(ctx, gold, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				if errors.IsInvalid(err) {
+					// retrying won't help until the original changes; cluster-specific fields may need to be ignored
+					utilruntime.HandleError(fmt.Errorf("cannot copy %s to target %s: %v", key, r.target.Name, err))
+					return nil, nil
+				}
+				return nil, err
+			}
+		} else if !isSubset(r.content(local), remote.Object) ||
+			remote.GetLabels()[common.LabelKeyParentClusterName] != r.clusterName {
+
+			remoteCopy := remote.DeepCopy()
+			// fields set in the target cluster are preserved, some of which may be immutable
+			merge(remoteCopy.Object, r.content(local))
+			// add or update parent cluster name
+			// labels is non-nil because it includes parent UID
+			l := remoteCopy.GetLabels()
+			l[common.LabelKeyParentClusterName] = r.clusterName
+			remoteCopy.SetLabels(l)
+
+			if _, err := r.remoteClient.Namespace(namespace).Update(ctx, remoteCopy, metav1.UpdateOptions{}); err != nil {
+				if errors.IsInvalid(err) {
+					// e.g., an immutable field changed; retrying won't help until the original changes again
+					utilruntime.HandleError(fmt.Errorf("cannot update copy of %s in target %s: %v", key, r.target.Name, err))
+					return nil, nil
+				}
+				return nil, err
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// shouldFollow returns true if the object is labeled, or if a proxy pod referring to it is scheduled to the target,
+// or still being scheduled: target clusters may reject candidate pods whose references don't exist,
+// e.g., the VolumeBinding plugin finds candidate pods with missing persistent volume claims unschedulable
+func (r reconciler) shouldFollow(local *unstructured.Unstructured) bool {
+	if r.selector != nil {
+		return r.selector.Matches(labels.Set(local.GetLabels()))
+	}
+	objs, err := r.podIndex.ByIndex(r.indexName, fmt.Sprintf("%s/%s", local.GetNamespace(), local.GetName()))
+	utilruntime.Must(err)
+	for _, obj := range objs {
+		proxyPod := obj.(*corev1.Pod)
+		if clusterName := proxypod.GetScheduledClusterName(proxyPod); clusterName == "" || clusterName == r.target.VirtualNodeName {
+			return true
+		}
+	}
+	return false
+}
+
+func (r reconciler) addFinalizer(ctx context.Context, local *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	localCopy := local.DeepCopy()
+	localCopy.SetFinalizers(append(localCopy.GetFinalizers(), r.target.Finalizer))
+	l := localCopy.GetLabels()
+	if l == nil {
+		l = map[string]string{}
+	}
+	l[common.LabelKeyHasFinalizer] = "true"
+	localCopy.SetLabels(l)
+	return r.client.Namespace(local.GetNamespace()).Update(ctx, localCopy, metav1.UpdateOptions{})
+}
+
+func (r reconciler) removeFinalizer(ctx context.Context, local *unstructured.Unstructured, j int) (*unstructured.Unstructured, error) {
+	localCopy := local.DeepCopy()
+	f := localCopy.GetFinalizers()
+	localCopy.SetFinalizers(append(f[:j], f[j+1:]...))
+	return r.client.Namespace(local.GetNamespace()).Update(ctx, localCopy, metav1.UpdateOptions{})
+}
+
+func (r reconciler) makeRemote(local *unstructured.Unstructured) *unstructured.Unstructured {
+	gold := &unstructured.Unstructured{Object: r.content(local)}
+	gold.SetAPIVersion(local.GetAPIVersion())
+	gold.SetKind(local.GetKind())
+	gold.SetName(local.GetName())
+	l := local.GetLabels() // copied
+	delete(l, common.LabelKeyHasFinalizer)
+	gold.SetLabels(l)
+	gold.SetAnnotations(local.GetAnnotations()) // copied
+	controller.AddRemoteControllerReference(gold, local, r.clusterName)
+	return gold
+}
+
+// content returns a deep copy of the fields of an object that are copied to target clusters,
+// i.e., all but its type, metadata, status and ignored fields
+func (r reconciler) content(u *unstructured.Unstructured) map[string]interface{} {
+	c := make(map[string]interface{}, len(u.Object))
+	for k, v := range u.Object {
+		switch k {
+		case "apiVersion", "kind", "metadata", "status":
+		default:
+			c[k] = runtime.DeepCopyJSONValue(v)
+		}
+	}
+	for _, f := range r.ignoredFields {
+		unstructured.RemoveNestedField(c, f...)
+	}
+	return c
+}
+
+// merge sets the fields set in src to the same values in dst, recursively in objects (lists are replaced),
+// so fields set in dst only are preserved, consistently with isSubset
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if vm, ok := v.(map[string]interface{}); ok {
+			if dm, ok := dst[k].(map[string]interface{}); ok {
+				merge(dm, vm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// isSubset returns true if every field set in a is set to the same value in b.
+// Remote objects may have more fields set than their originals, by defaulting or by controllers in target clusters
+// (e.g., the volume name of a bound persistent volume claim), which shouldn't trigger updates,
+// but fields removed from the originals aren't removed from the remote objects either.
+func isSubset(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		bm, ok := b.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range a {
+			bv, ok := bm[k]
+			if !ok || !isSubset(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bs, ok := b.([]interface{})
+		if !ok || len(a) != len(bs) {
+			return false
+		}
+		for i := range a {
+			if !isSubset(a[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package generic
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+)
+
+var pvcGVR = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+
+func newPVC(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion("v1")
+	u.SetKind("PersistentVolumeClaim")
+	u.SetNamespace("ns")
+	u.SetName(name)
+	u.SetUID("pvc-uid")
+	return u
+}
+
+func TestFollowReferenced(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	pvc := newPVC("data", map[string]interface{}{"accessModes": []interface{}{"ReadWriteOnce"}})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec: corev1.PodSpec{
+			SchedulerName: common.ProxySchedulerName,
+			NodeName:      target.VirtualNodeName,
+			Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+			}}},
+		},
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		"proxyPodBy": indexProxyPodByReferences([]string{"{.spec.volumes[*].persistentVolumeClaim.claimName}"}),
+	})
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podIndexer.Add(pod))
+	require.NoError(t, indexer.Add(pvc))
+
+	listKinds := map[schema.GroupVersionResource]string{pvcGVR: "PersistentVolumeClaimList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, pvc)
+	remoteClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	r := reconciler{
+		clusterName:  "source",
+		target:       target,
+		client:       client.Resource(pvcGVR),
+		remoteClient: remoteClient.Resource(pvcGVR),
+		lister:       cache.NewGenericLister(indexer, pvcGVR.GroupResource()),
+		remoteLister: cache.NewGenericLister(remoteIndexer, pvcGVR.GroupResource()),
+		podIndex:     podIndexer,
+		indexName:    "proxyPodBy",
+	}
+
+	_, err := r.Handle("ns/data")
+	require.NoError(t, err)
+	remotePVC, err := remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, controller.IsRemoteControlled(remotePVC, "source"))
+	require.Equal(t, pvc.Object["spec"], remotePVC.Object["spec"])
+	require.NotContains(t, remotePVC.GetLabels(), common.LabelKeyHasFinalizer)
+	localPVC, err := client.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{target.Finalizer}, localPVC.GetFinalizers())
+
+	// fields set in the target cluster don't trigger updates
+	require.NoError(t, unstructured.SetNestedField(remotePVC.Object, "pv-1", "spec", "volumeName"))
+	require.NoError(t, remoteIndexer.Add(remotePVC))
+	require.NoError(t, indexer.Update(localPVC))
+	remoteClient.ClearActions()
+	_, err = r.Handle("ns/data")
+	require.NoError(t, err)
+	require.Empty(t, remoteClient.Actions())
+
+	// local object terminating: remote object deleted, then finalizer removed
+	now := metav1.Now()
+	localPVC.SetDeletionTimestamp(&now)
+	require.NoError(t, indexer.Update(localPVC))
+	_, err = r.Handle("ns/data")
+	require.NoError(t, err)
+	_, err = remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+	require.NoError(t, remoteIndexer.Delete(remotePVC))
+	_, err = r.Handle("ns/data")
+	require.NoError(t, err)
+	localPVC, err = client.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, localPVC.GetFinalizers())
+}
+
+func TestFollowReferencedByPendingProxyPod(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	pvc := newPVC("data", map[string]interface{}{"accessModes": []interface{}{"ReadWriteOnce"}})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec: corev1.PodSpec{
+			SchedulerName: common.ProxySchedulerName,
+			Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data"},
+			}}},
+		},
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		"proxyPodBy": indexProxyPodByReferences([]string{"{.spec.volumes[*].persistentVolumeClaim.claimName}"}),
+	})
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podIndexer.Add(pod))
+	require.NoError(t, indexer.Add(pvc))
+
+	listKinds := map[schema.GroupVersionResource]string{pvcGVR: "PersistentVolumeClaimList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, pvc)
+	remoteClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	r := reconciler{
+		clusterName:  "source",
+		target:       target,
+		client:       client.Resource(pvcGVR),
+		remoteClient: remoteClient.Resource(pvcGVR),
+		lister:       cache.NewGenericLister(indexer, pvcGVR.GroupResource()),
+		remoteLister: cache.NewGenericLister(remoteIndexer, pvcGVR.GroupResource()),
+		podIndex:     podIndexer,
+		indexName:    "proxyPodBy",
+	}
+
+	// the proxy pod isn't bound yet: the candidate pod needs the copy to be schedulable
+	require.True(t, r.shouldFollow(pvc))
+	_, err := r.Handle("ns/data")
+	require.NoError(t, err)
+	remotePVC, err := remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, controller.IsRemoteControlled(remotePVC, "source"))
+
+	// once the proxy pod is bound to another target, objects aren't copied to this one anymore
+	pod.Spec.NodeName = "admiralty-ns-other"
+	require.NoError(t, podIndexer.Update(pod))
+	require.False(t, r.shouldFollow(pvc))
+}
+
+func TestFollowLabeled(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	labeled := newPVC("labeled", map[string]interface{}{})
+	labeled.SetLabels(map[string]string{"follow": "true"})
+	unlabeled := newPVC("unlabeled", map[string]interface{}{})
+	// eponymous object in the target cluster, not controlled by the local object
+	uncontrolled := newPVC("labeled", map[string]interface{}{"volumeName": "pv-1"})
+	uncontrolled.SetUID("remote-uid")
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(labeled))
+	require.NoError(t, indexer.Add(unlabeled))
+
+	listKinds := map[schema.GroupVersionResource]string{pvcGVR: "PersistentVolumeClaimList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, labeled, unlabeled)
+	remoteClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	r := reconciler{
+		clusterName:  "source",
+		target:       target,
+		client:       client.Resource(pvcGVR),
+		remoteClient: remoteClient.Resource(pvcGVR),
+		lister:       cache.NewGenericLister(indexer, pvcGVR.GroupResource()),
+		remoteLister: cache.NewGenericLister(remoteIndexer, pvcGVR.GroupResource()),
+		selector:     labels.SelectorFromSet(labels.Set{"follow": "true"}),
+	}
+
+	_, err := r.Handle("ns/unlabeled")
+	require.NoError(t, err)
+	require.Empty(t, remoteClient.Actions())
+
+	_, err = r.Handle("ns/labeled")
+	require.NoError(t, err)
+	_, err = remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "labeled", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	client.ClearActions()
+	remoteClient.ClearActions()
+	require.NoError(t, remoteIndexer.Add(uncontrolled))
+	_, err = r.Handle("ns/labeled")
+	require.NoError(t, err)
+	require.Empty(t, client.Actions())
+	require.Empty(t, remoteClient.Actions())
+}
+
+func TestFollowIgnoredFields(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	// bound in the source cluster
+	pvc := newPVC("data", map[string]interface{}{"accessModes": []interface{}{"ReadWriteOnce"}, "volumeName": "pv-source"})
+	pvc.SetLabels(map[string]string{"follow": "true"})
+	pvc.SetFinalizers([]string{target.Finalizer})
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(pvc))
+
+	listKinds := map[schema.GroupVersionResource]string{pvcGVR: "PersistentVolumeClaimList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, pvc)
+	remoteClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds)
+	r := reconciler{
+		clusterName:   "source",
+		target:        target,
+		client:        client.Resource(pvcGVR),
+		remoteClient:  remoteClient.Resource(pvcGVR),
+		lister:        cache.NewGenericLister(indexer, pvcGVR.GroupResource()),
+		remoteLister:  cache.NewGenericLister(remoteIndexer, pvcGVR.GroupResource()),
+		selector:      labels.SelectorFromSet(labels.Set{"follow": "true"}),
+		ignoredFields: [][]string{{"spec", "volumeName"}},
+	}
+
+	// the volume name of the source cluster isn't copied
+	_, err := r.Handle("ns/data")
+	require.NoError(t, err)
+	remotePVC, err := remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, found, err := unstructured.NestedString(remotePVC.Object, "spec", "volumeName")
+	require.NoError(t, err)
+	require.False(t, found)
+	require.Equal(t, "pv-source", pvc.Object["spec"].(map[string]interface{})["volumeName"]) // original untouched
+
+	// bound in the target cluster: no update
+	require.NoError(t, unstructured.SetNestedField(remotePVC.Object, "pv-target", "spec", "volumeName"))
+	require.NoError(t, remoteIndexer.Add(remotePVC))
+	remoteClient.ClearActions()
+	_, err = r.Handle("ns/data")
+	require.NoError(t, err)
+	require.Empty(t, remoteClient.Actions())
+
+	// original changed: fields set in the target cluster are preserved
+	pvcCopy := pvc.DeepCopy()
+	require.NoError(t, unstructured.SetNestedField(pvcCopy.Object, "10Gi", "spec", "resources", "requests", "storage"))
+	require.NoError(t, indexer.Update(pvcCopy))
+	_, err = r.Handle("ns/data")
+	require.NoError(t, err)
+	remotePVC, err = remoteClient.Resource(pvcGVR).Namespace("ns").Get(ctx, "data", metav1.GetOptions{})
+	require.NoError(t, err)
+	volumeName, _, _ := unstructured.NestedString(remotePVC.Object, "spec", "volumeName")
+	require.Equal(t, "pv-target", volumeName)
+	storage, _, _ := unstructured.NestedString(remotePVC.Object, "spec", "resources", "requests", "storage")
+	require.Equal(t, "10Gi", storage)
+	require.NoError(t, remoteIndexer.Update(remotePVC))
+
+	// invalid update, e.g., of an immutable field: not retried
+	pvcCopy = pvcCopy.DeepCopy()
+	require.NoError(t, unstructured.SetNestedSlice(pvcCopy.Object, []interface{}{"ReadWriteMany"}, "spec", "accessModes"))
+	require.NoError(t, indexer.Update(pvcCopy))
+	remoteClient.PrependReactor("update", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewInvalid(schema.GroupKind{Kind: "PersistentVolumeClaim"}, "data", field.ErrorList{field.Forbidden(field.NewPath("spec"), "immutable")})
+	})
+	requeueAfter, err := r.Handle("ns/data")
+	require.NoError(t, err)
+	require.Nil(t, requeueAfter)
+}
@@ -0,0 +1,467 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package follow
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	rbacinformers "k8s.io/client-go/informers/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
+)
+
+const proxyPodByServiceAccount = "proxyPodByServiceAccount"
+
+// rbacKey is the work item to mirror the role bindings (and roles) of the followed service accounts in a namespace,
+// whereas service accounts are keyed by namespace/name strings
+type rbacKey struct {
+	namespace string
+}
+
+type serviceAccountReconciler struct {
+	clusterName string
+	target      agentconfig.Target
+
+	kubeclientset kubernetes.Interface
+	remoteClient  kubernetes.Interface
+
+	podLister            corelisters.PodLister
+	serviceAccountLister corelisters.ServiceAccountLister
+	roleBindingLister    rbaclisters.RoleBindingLister // nil unless the target mirrors RBAC
+	roleLister           rbaclisters.RoleLister        // nil unless the target mirrors roles
+
+	remoteServiceAccountLister corelisters.ServiceAccountLister
+	remoteRoleBindingLister    rbaclisters.RoleBindingLister // nil unless the target mirrors RBAC
+	remoteRoleLister           rbaclisters.RoleLister        // nil unless the target mirrors RBAC, to clean up roles mirrored before
+
+	podIndex cache.Indexer
+}
+
+// NewServiceAccountController returns a controller that creates eponymous service accounts in the target cluster
+// for the service accounts of proxy pods, so that the target cluster mints tokens for their delegate pods.
+// Depending on the target's ServiceAccountRBAC, it also mirrors the role bindings (and roles) of the service accounts.
+// The (remote) role binding and role informers are only used (and started) if needed.
+func NewServiceAccountController(
+	clusterName string,
+	target agentconfig.Target,
+
+	kubeclientset kubernetes.Interface,
+	remoteClient kubernetes.Interface,
+
+	podInformer coreinformers.PodInformer,
+	serviceAccountInformer coreinformers.ServiceAccountInformer,
+	roleBindingInformer rbacinformers.RoleBindingInformer,
+	roleInformer rbacinformers.RoleInformer,
+
+	remoteServiceAccountInformer coreinformers.ServiceAccountInformer,
+	remoteRoleBindingInformer rbacinformers.RoleBindingInformer,
+	remoteRoleInformer rbacinformers.RoleInformer) *controller.Controller {
+
+	r := &serviceAccountReconciler{
+		clusterName: clusterName,
+		target:      target,
+
+		kubeclientset: kubeclientset,
+		remoteClient:  remoteClient,
+
+		podLister:            podInformer.Lister(),
+		serviceAccountLister: serviceAccountInformer.Lister(),
+
+		remoteServiceAccountLister: remoteServiceAccountInformer.Lister(),
+
+		podIndex: podInformer.Informer().GetIndexer(),
+	}
+
+	synced := []cache.InformerSynced{podInformer.Informer().HasSynced, serviceAccountInformer.Informer().HasSynced, remoteServiceAccountInformer.Informer().HasSynced}
+	mirrorRBAC := target.ServiceAccountRBAC == v1alpha1.ServiceAccountRBACRoleBindings || target.ServiceAccountRBAC == v1alpha1.ServiceAccountRBACRoleBindingsAndRoles
+	if mirrorRBAC {
+		r.roleBindingLister = roleBindingInformer.Lister()
+		r.remoteRoleBindingLister = remoteRoleBindingInformer.Lister()
+		r.remoteRoleLister = remoteRoleInformer.Lister()
+		synced = append(synced, roleBindingInformer.Informer().HasSynced, remoteRoleBindingInformer.Informer().HasSynced, remoteRoleInformer.Informer().HasSynced)
+	}
+	if target.ServiceAccountRBAC == v1alpha1.ServiceAccountRBACRoleBindingsAndRoles {
+		r.roleLister = roleInformer.Lister()
+		synced = append(synced, roleInformer.Informer().HasSynced)
+	}
+
+	c := controller.New("service-accounts-follow", r, synced...)
+
+	enqueueRBAC := func(obj interface{}) {
+		if !mirrorRBAC {
+			return
+		}
+		if m, err := meta.Accessor(obj); err == nil {
+			c.EnqueueKey(rbacKey{namespace: m.GetNamespace()})
+		}
+	}
+
+	serviceAccountInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(c.EnqueueObject))
+	serviceAccountInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueueRBAC))
+
+	remoteServiceAccountInformer.Informer().AddEventHandler(controller.HandleAllWith(c.EnqueueRemoteController(clusterName)))
+
+	enqueuePod := func(obj interface{}) {
+		keys, _ := indexProxyPodByServiceAccount(obj)
+		for _, key := range keys {
+			c.EnqueueKey(key)
+		}
+		if len(keys) > 0 {
+			enqueueRBAC(obj)
+		}
+	}
+	podHandler := controller.HandleAllWith(enqueuePod)
+	// most pod updates, e.g., status updates, don't change whether their service accounts are followed
+	podHandler.UpdateFunc = func(old, new interface{}) {
+		if r.followedServiceAccount(old) != r.followedServiceAccount(new) {
+			enqueuePod(new)
+		}
+	}
+	podInformer.Informer().AddEventHandler(podHandler)
+	utilruntime.Must(podInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+		proxyPodByServiceAccount: indexProxyPodByServiceAccount,
+	}))
+
+	if mirrorRBAC {
+		enqueueRemoteRBAC := func(obj interface{}) {
+			if m, err := meta.Accessor(obj); err == nil && controller.IsRemoteControlled(m, clusterName) {
+				enqueueRBAC(obj)
+			}
+		}
+		roleBindingInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueueRBAC))
+		remoteRoleBindingInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueueRemoteRBAC))
+		remoteRoleInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueueRemoteRBAC))
+	}
+	if r.roleLister != nil {
+		roleInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueueRBAC))
+	}
+
+	return c
+}
+
+func indexProxyPodByServiceAccount(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !proxypod.IsProxy(pod) {
+		return nil, nil
+	}
+	name := pod.Spec.ServiceAccountName
+	if name == "" {
+		name = "default"
+	}
+	return []string{fmt.Sprintf("%s/%s", pod.Namespace, name)}, nil
+}
+
+// followedServiceAccount returns the key of the service account of a proxy pod
+// if the pod makes it follow to the target (see shouldFollow), or an empty string
+func (r serviceAccountReconciler) followedServiceAccount(obj interface{}) string {
+	keys, _ := indexProxyPodByServiceAccount(obj)
+	if len(keys) == 0 {
+		return ""
+	}
+	if clusterName := proxypod.GetScheduledClusterName(obj.(*corev1.Pod)); clusterName != "" && clusterName != r.target.VirtualNodeName {
+		return ""
+	}
+	return keys[0]
+}
+
+func (r serviceAccountReconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
+	ctx := context.Background()
+
+	if key, ok := obj.(rbacKey); ok {
+		return nil, r.mirrorRBAC(ctx, key.namespace)
+	}
+
+	key := obj.(string)
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	utilruntime.Must(err)
+
+	remoteServiceAccount, err := r.remoteServiceAccountLister.ServiceAccounts(namespace).Get(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	serviceAccount, err := r.serviceAccountLister.ServiceAccounts(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if remoteServiceAccount != nil && controller.IsRemoteControlled(remoteServiceAccount, r.clusterName) {
+				if err := r.remoteClient.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+					return nil, fmt.Errorf("cannot delete orphaned service account: %v", err)
+				}
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	terminating := serviceAccount.DeletionTimestamp != nil
+
+	hasFinalizer, j := controller.HasFinalizer(serviceAccount.Finalizers, r.target.Finalizer)
+
+	shouldFollow := r.shouldFollow(namespace, name)
+
+	// eponymous service accounts that we don't control, e.g., default service accounts, are left alone
+	if remoteServiceAccount != nil && !controller.ParentControlsChild(remoteServiceAccount, serviceAccount) {
+		return nil, nil
+	}
+
+	if terminating {
+		if remoteServiceAccount != nil {
+			if err := r.remoteClient.CoreV1().ServiceAccounts(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		} else if hasFinalizer {
+			if _, err := r.removeFinalizer(ctx, serviceAccount, j); err != nil {
+				return nil, err
+			}
+		}
+	} else if shouldFollow {
+		if !hasFinalizer {
+			serviceAccount, err = r.addFinalizer(ctx, serviceAccount)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if remoteServiceAccount == nil {
+			gold := r.makeRemoteServiceAccount(serviceAccount)
+			_, err := r.remoteClient.CoreV1().ServiceAccounts(namespace).Create(ctx, gold, metav1.CreateOptions{})
+			if err != nil && !errors.IsAlreadyExists(err) {
+				return nil, err
+			}
+		} else if !reflect.DeepEqual(remoteServiceAccount.AutomountServiceAccountToken, serviceAccount.AutomountServiceAccountToken) ||
+			!reflect.DeepEqual(remoteServiceAccount.ImagePullSecrets, serviceAccount.ImagePullSecrets) ||
+			remoteServiceAccount.Labels[common.LabelKeyParentClusterName] != r.clusterName {
+
+			remoteServiceAccountCopy := remoteServiceAccount.DeepCopy()
+			remoteServiceAccountCopy.AutomountServiceAccountToken = serviceAccount.AutomountServiceAccountToken
+			remoteServiceAccountCopy.ImagePullSecrets = serviceAccount.ImagePullSecrets
+			// labels is non-nil because it includes parent UID
+			remoteServiceAccountCopy.Labels[common.LabelKeyParentClusterName] = r.clusterName
+
+			if _, err := r.remoteClient.CoreV1().ServiceAccounts(namespace).Update(ctx, remoteServiceAccountCopy, metav1.UpdateOptions{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// TODO? cleanup remote service accounts that aren't referred to by proxy pods
+
+	return nil, nil
+}
+
+// shouldFollow returns true if a proxy pod using the service account is scheduled to the target,
+// or still being scheduled: the target cluster rejects candidate pods whose service accounts don't exist
+func (r serviceAccountReconciler) shouldFollow(namespace, name string) bool {
+	objs, err := r.podIndex.ByIndex(proxyPodByServiceAccount, fmt.Sprintf("%s/%s", namespace, name))
+	utilruntime.Must(err)
+	for _, obj := range objs {
+		proxyPod := obj.(*corev1.Pod)
+		if clusterName := proxypod.GetScheduledClusterName(proxyPod); clusterName == "" || clusterName == r.target.VirtualNodeName {
+			return true
+		}
+	}
+	return false
+}
+
+// isFollowed returns true if the service account exists, isn't terminating, and should follow proxy pods to the target
+func (r serviceAccountReconciler) isFollowed(namespace, name string) bool {
+	sa, err := r.serviceAccountLister.ServiceAccounts(namespace).Get(name)
+	if err != nil {
+		return false
+	}
+	return sa.DeletionTimestamp == nil && r.shouldFollow(namespace, name)
+}
+
+func (r serviceAccountReconciler) addFinalizer(ctx context.Context, serviceAccount *corev1.ServiceAccount) (*corev1.ServiceAccount, error) {
+	serviceAccountCopy := serviceAccount.DeepCopy()
+	serviceAccountCopy.Finalizers = append(serviceAccountCopy.Finalizers, r.target.Finalizer)
+	if serviceAccountCopy.Labels == nil {
+		serviceAccountCopy.Labels = map[string]string{}
+	}
+	serviceAccountCopy.Labels[common.LabelKeyHasFinalizer] = "true"
+	return r.kubeclientset.CoreV1().ServiceAccounts(serviceAccount.Namespace).Update(ctx, serviceAccountCopy, metav1.UpdateOptions{})
+}
+
+func (r serviceAccountReconciler) removeFinalizer(ctx context.Context, serviceAccount *corev1.ServiceAccount, j int) (*corev1.ServiceAccount, error) {
+	serviceAccountCopy := serviceAccount.DeepCopy()
+	serviceAccountCopy.Finalizers = append(serviceAccountCopy.Finalizers[:j], serviceAccountCopy.Finalizers[j+1:]...)
+	return r.kubeclientset.CoreV1().ServiceAccounts(serviceAccount.Namespace).Update(ctx, serviceAccountCopy, metav1.UpdateOptions{})
+}
+
+func (r serviceAccountReconciler) makeRemoteServiceAccount(serviceAccount *corev1.ServiceAccount) *corev1.ServiceAccount {
+	gold := &corev1.ServiceAccount{}
+	gold.Name = serviceAccount.Name
+	gold.Labels = copyStringMap(serviceAccount.Labels)
+	delete(gold.Labels, common.LabelKeyHasFinalizer)
+	gold.Annotations = copyStringMap(serviceAccount.Annotations)
+	controller.AddRemoteControllerReference(gold, serviceAccount, r.clusterName)
+	gold.AutomountServiceAccountToken = serviceAccount.AutomountServiceAccountToken
+	// token secrets aren't copied: the target cluster mints its own tokens
+	gold.ImagePullSecrets = serviceAccount.ImagePullSecrets
+	return gold
+}
+
+// mirrorRBAC reconciles the role bindings (and roles) that we control in a namespace of the target cluster
+// with the role bindings that bind followed service accounts (and the roles they refer to).
+// Other subjects, e.g., users and groups, which are identities of the source cluster, aren't mirrored.
+// Remote role bindings and roles that we don't control are left alone.
+func (r serviceAccountReconciler) mirrorRBAC(ctx context.Context, namespace string) error {
+	roleBindings, err := r.roleBindingLister.RoleBindings(namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	desiredRoleBindings := map[string]*rbacv1.RoleBinding{}
+	desiredRoles := map[string]*rbacv1.Role{}
+	for _, rb := range roleBindings {
+		if rb.DeletionTimestamp != nil {
+			continue
+		}
+		var subjects []rbacv1.Subject
+		for _, s := range rb.Subjects {
+			if s.Kind == rbacv1.ServiceAccountKind && r.isFollowed(s.Namespace, s.Name) {
+				subjects = append(subjects, s)
+			}
+		}
+		if len(subjects) == 0 {
+			continue
+		}
+		gold := &rbacv1.RoleBinding{}
+		gold.Name = rb.Name
+		gold.Labels = copyStringMap(rb.Labels)
+		gold.Annotations = copyStringMap(rb.Annotations)
+		controller.AddRemoteControllerReference(gold, rb, r.clusterName)
+		gold.RoleRef = rb.RoleRef
+		gold.Subjects = subjects
+		desiredRoleBindings[gold.Name] = gold
+
+		if r.roleLister != nil && rb.RoleRef.Kind == "Role" {
+			role, err := r.roleLister.Roles(namespace).Get(rb.RoleRef.Name)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					// binding a missing role doesn't grant anything, here or there
+					continue
+				}
+				return err
+			}
+			gold := &rbacv1.Role{}
+			gold.Name = role.Name
+			gold.Labels = copyStringMap(role.Labels)
+			gold.Annotations = copyStringMap(role.Annotations)
+			controller.AddRemoteControllerReference(gold, role, r.clusterName)
+			gold.Rules = role.Rules
+			desiredRoles[gold.Name] = gold
+		}
+	}
+
+	selector := labels.SelectorFromSet(labels.Set{common.LabelKeyParentClusterName: r.clusterName})
+
+	// roles first, so mirrored role bindings never refer to outdated roles
+	remoteRoles, err := r.remoteRoleLister.Roles(namespace).List(selector)
+	if err != nil {
+		return fmt.Errorf("cannot list remote roles: %v", err)
+	}
+	for _, remote := range remoteRoles {
+		gold, ok := desiredRoles[remote.Name]
+		if !ok {
+			if err := r.remoteClient.RbacV1().Roles(namespace).Delete(ctx, remote.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("cannot delete remote role: %v", err)
+			}
+			continue
+		}
+		delete(desiredRoles, remote.Name)
+		if !reflect.DeepEqual(remote.Rules, gold.Rules) || !reflect.DeepEqual(remote.Labels, gold.Labels) {
+			remoteCopy := remote.DeepCopy()
+			remoteCopy.Labels = gold.Labels
+			remoteCopy.Annotations = gold.Annotations
+			remoteCopy.Rules = gold.Rules
+			if _, err := r.remoteClient.RbacV1().Roles(namespace).Update(ctx, remoteCopy, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("cannot update remote role: %v", err)
+			}
+		}
+	}
+	for _, gold := range desiredRoles {
+		if _, err := r.remoteClient.RbacV1().Roles(namespace).Create(ctx, gold, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("cannot create remote role: %v", err)
+		}
+	}
+
+	remoteRoleBindings, err := r.remoteRoleBindingLister.RoleBindings(namespace).List(selector)
+	if err != nil {
+		return fmt.Errorf("cannot list remote role bindings: %v", err)
+	}
+	for _, remote := range remoteRoleBindings {
+		gold, ok := desiredRoleBindings[remote.Name]
+		if ok && remote.RoleRef == gold.RoleRef {
+			delete(desiredRoleBindings, remote.Name)
+			if !reflect.DeepEqual(remote.Subjects, gold.Subjects) || !reflect.DeepEqual(remote.Labels, gold.Labels) {
+				remoteCopy := remote.DeepCopy()
+				remoteCopy.Labels = gold.Labels
+				remoteCopy.Annotations = gold.Annotations
+				remoteCopy.Subjects = gold.Subjects
+				if _, err := r.remoteClient.RbacV1().RoleBindings(namespace).Update(ctx, remoteCopy, metav1.UpdateOptions{}); err != nil {
+					return fmt.Errorf("cannot update remote role binding: %v", err)
+				}
+			}
+			continue
+		}
+		// not desired anymore, or role ref changed, which is immutable (recreated below)
+		if err := r.remoteClient.RbacV1().RoleBindings(namespace).Delete(ctx, remote.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("cannot delete remote role binding: %v", err)
+		}
+	}
+	for _, gold := range desiredRoleBindings {
+		if _, err := r.remoteClient.RbacV1().RoleBindings(namespace).Create(ctx, gold, metav1.CreateOptions{}); err != nil && !errors.IsAlreadyExists(err) {
+			if errors.IsForbidden(err) && gold.RoleRef.Kind == "ClusterRole" {
+				// the target cluster only lets sources bind the cluster roles that it allows (see the Helm chart value
+				// sourceController.serviceAccountRBACMirroringClusterRoles); other role bindings are still mirrored,
+				// and this one is retried on resync, in case the target cluster allows it later
+				utilruntime.HandleError(fmt.Errorf("cannot mirror role binding %s/%s to cluster role %s: %v", namespace, gold.Name, gold.RoleRef.Name, err))
+				continue
+			}
+			return fmt.Errorf("cannot create remote role binding: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
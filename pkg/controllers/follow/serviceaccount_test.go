@@ -0,0 +1,261 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package follow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	rbaclisters "k8s.io/client-go/listers/rbac/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+)
+
+func TestServiceAccountFollow(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "argo", UID: "sa-uid"}}
+	other := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other", UID: "other-uid"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName, ServiceAccountName: "argo"},
+	}
+	otherPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-proxy"},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName, ServiceAccountName: "other", NodeName: "admiralty-ns-on-prem"},
+	}
+	role := &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "workflow", UID: "role-uid"},
+		Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get"}}},
+	}
+	rb := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "workflow", UID: "rb-uid"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "workflow"},
+		Subjects: []rbacv1.Subject{
+			{Kind: rbacv1.ServiceAccountKind, Namespace: "ns", Name: "argo"},
+			{Kind: rbacv1.ServiceAccountKind, Namespace: "ns", Name: "other"}, // not followed to this target
+			{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"},
+		},
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{proxyPodByServiceAccount: indexProxyPodByServiceAccount})
+	saIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	rbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	roleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteSAIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteRBIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteRoleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, o := range []struct {
+		indexer cache.Indexer
+		obj     interface{}
+	}{{podIndexer, pod}, {podIndexer, otherPod}, {saIndexer, sa}, {saIndexer, other}, {rbIndexer, rb}, {roleIndexer, role}} {
+		require.NoError(t, o.indexer.Add(o.obj))
+	}
+
+	client := fake.NewSimpleClientset(sa, other)
+	remoteClient := fake.NewSimpleClientset()
+	r := serviceAccountReconciler{
+		clusterName:                "source",
+		target:                     target,
+		kubeclientset:              client,
+		remoteClient:               remoteClient,
+		podLister:                  corelisters.NewPodLister(podIndexer),
+		serviceAccountLister:       corelisters.NewServiceAccountLister(saIndexer),
+		roleBindingLister:          rbaclisters.NewRoleBindingLister(rbIndexer),
+		roleLister:                 rbaclisters.NewRoleLister(roleIndexer),
+		remoteServiceAccountLister: corelisters.NewServiceAccountLister(remoteSAIndexer),
+		remoteRoleBindingLister:    rbaclisters.NewRoleBindingLister(remoteRBIndexer),
+		remoteRoleLister:           rbaclisters.NewRoleLister(remoteRoleIndexer),
+		podIndex:                   podIndexer,
+	}
+
+	// only changes to whether pods make their service accounts follow them matter
+	require.Equal(t, "ns/argo", r.followedServiceAccount(pod))
+	require.Equal(t, "", r.followedServiceAccount(otherPod))
+	podCopy := pod.DeepCopy()
+	podCopy.Spec.NodeName = target.VirtualNodeName
+	require.Equal(t, "ns/argo", r.followedServiceAccount(podCopy))
+	podCopy.Spec.NodeName = "admiralty-ns-on-prem"
+	require.Equal(t, "", r.followedServiceAccount(podCopy))
+
+	// pending proxy pod: followed, in case a candidate is created in this target
+	_, err := r.Handle("ns/argo")
+	require.NoError(t, err)
+	remoteSA, err := remoteClient.CoreV1().ServiceAccounts("ns").Get(ctx, "argo", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, controller.IsRemoteControlled(remoteSA, "source"))
+	localSA, err := client.CoreV1().ServiceAccounts("ns").Get(ctx, "argo", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{target.Finalizer}, localSA.Finalizers)
+
+	// scheduled to another target: not followed
+	_, err = r.Handle("ns/other")
+	require.NoError(t, err)
+	_, err = remoteClient.CoreV1().ServiceAccounts("ns").Get(ctx, "other", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+
+	// only followed service account subjects are mirrored
+	_, err = r.Handle(rbacKey{namespace: "ns"})
+	require.NoError(t, err)
+	remoteRB, err := remoteClient.RbacV1().RoleBindings("ns").Get(ctx, "workflow", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, rb.RoleRef, remoteRB.RoleRef)
+	require.Equal(t, []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ns", Name: "argo"}}, remoteRB.Subjects)
+	remoteRole, err := remoteClient.RbacV1().Roles("ns").Get(ctx, "workflow", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, role.Rules, remoteRole.Rules)
+	require.NoError(t, remoteRBIndexer.Add(remoteRB))
+	require.NoError(t, remoteRoleIndexer.Add(remoteRole))
+
+	// up to date: nothing to do
+	remoteClient.ClearActions()
+	_, err = r.Handle(rbacKey{namespace: "ns"})
+	require.NoError(t, err)
+	require.Empty(t, remoteClient.Actions())
+
+	// role binding deleted: mirrored role binding and role deleted
+	require.NoError(t, rbIndexer.Delete(rb))
+	_, err = r.Handle(rbacKey{namespace: "ns"})
+	require.NoError(t, err)
+	_, err = remoteClient.RbacV1().RoleBindings("ns").Get(ctx, "workflow", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+	_, err = remoteClient.RbacV1().Roles("ns").Get(ctx, "workflow", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+
+	// service account terminating: remote service account deleted, then finalizer removed
+	require.NoError(t, remoteSAIndexer.Add(remoteSA))
+	localSA.DeletionTimestamp = &metav1.Time{}
+	require.NoError(t, saIndexer.Update(localSA))
+	_, err = r.Handle("ns/argo")
+	require.NoError(t, err)
+	_, err = remoteClient.CoreV1().ServiceAccounts("ns").Get(ctx, "argo", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+	require.NoError(t, remoteSAIndexer.Delete(remoteSA))
+	_, err = r.Handle("ns/argo")
+	require.NoError(t, err)
+	localSA, err = client.CoreV1().ServiceAccounts("ns").Get(ctx, "argo", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, localSA.Finalizers)
+}
+
+func TestServiceAccountFollowIgnoresUncontrolled(t *testing.T) {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default", UID: "sa-uid"}}
+	remoteSA := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "default", UID: "remote-uid"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName},
+	}
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{proxyPodByServiceAccount: indexProxyPodByServiceAccount})
+	saIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	remoteSAIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podIndexer.Add(pod))
+	require.NoError(t, saIndexer.Add(sa))
+	require.NoError(t, remoteSAIndexer.Add(remoteSA))
+
+	client := fake.NewSimpleClientset(sa)
+	remoteClient := fake.NewSimpleClientset(remoteSA)
+	r := serviceAccountReconciler{
+		clusterName:                "source",
+		kubeclientset:              client,
+		remoteClient:               remoteClient,
+		podLister:                  corelisters.NewPodLister(podIndexer),
+		serviceAccountLister:       corelisters.NewServiceAccountLister(saIndexer),
+		remoteServiceAccountLister: corelisters.NewServiceAccountLister(remoteSAIndexer),
+		podIndex:                   podIndexer,
+	}
+
+	_, err := r.Handle("ns/default")
+	require.NoError(t, err)
+	require.Empty(t, client.Actions())
+	require.Empty(t, remoteClient.Actions())
+}
+
+func TestServiceAccountFollowClusterRoleNotAllowed(t *testing.T) {
+	ctx := context.Background()
+	target := agentconfig.Target{VirtualNodeName: "admiralty-ns-cloud", Finalizer: "multicluster.admiralty.io/cloud"}
+
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "argo", UID: "sa-uid"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy"},
+		Spec:       corev1.PodSpec{SchedulerName: common.ProxySchedulerName, ServiceAccountName: "argo"},
+	}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ns", Name: "argo"}}
+	admin := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "admin", UID: "admin-uid"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin"},
+		Subjects:   subjects,
+	}
+	view := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "view", UID: "view-uid"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+		Subjects:   subjects,
+	}
+
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{proxyPodByServiceAccount: indexProxyPodByServiceAccount})
+	saIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	rbIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, podIndexer.Add(pod))
+	require.NoError(t, saIndexer.Add(sa))
+	require.NoError(t, rbIndexer.Add(admin))
+	require.NoError(t, rbIndexer.Add(view))
+
+	// the target cluster only lets sources bind the view cluster role
+	remoteClient := fake.NewSimpleClientset()
+	remoteClient.PrependReactor("create", "rolebindings", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		rb := action.(k8stesting.CreateAction).GetObject().(*rbacv1.RoleBinding)
+		if rb.RoleRef.Name != "view" {
+			return true, nil, errors.NewForbidden(rbacv1.Resource("rolebindings"), rb.Name, fmt.Errorf("cannot bind cluster role %s", rb.RoleRef.Name))
+		}
+		return false, nil, nil
+	})
+
+	r := serviceAccountReconciler{
+		clusterName:                "source",
+		target:                     target,
+		kubeclientset:              fake.NewSimpleClientset(sa),
+		remoteClient:               remoteClient,
+		podLister:                  corelisters.NewPodLister(podIndexer),
+		serviceAccountLister:       corelisters.NewServiceAccountLister(saIndexer),
+		roleBindingLister:          rbaclisters.NewRoleBindingLister(rbIndexer),
+		remoteServiceAccountLister: corelisters.NewServiceAccountLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		remoteRoleBindingLister:    rbaclisters.NewRoleBindingLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		remoteRoleLister:           rbaclisters.NewRoleLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		podIndex:                   podIndexer,
+	}
+
+	// the forbidden role binding is skipped, the allowed one is still mirrored
+	_, err := r.Handle(rbacKey{namespace: "ns"})
+	require.NoError(t, err)
+	_, err = remoteClient.RbacV1().RoleBindings("ns").Get(ctx, "view", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, err = remoteClient.RbacV1().RoleBindings("ns").Get(ctx, "admin", metav1.GetOptions{})
+	require.True(t, errors.IsNotFound(err))
+}
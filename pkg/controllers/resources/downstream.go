@@ -34,6 +34,7 @@ import (
 	"admiralty.io/multicluster-scheduler/pkg/common"
 	"admiralty.io/multicluster-scheduler/pkg/controller"
 	clientset "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	"admiralty.io/multicluster-scheduler/pkg/model/virtualnode"
 )
 
 type downstream struct {
@@ -108,12 +109,18 @@ func (r downstream) Handle(_ interface{}) (requeueAfter *time.Duration, err erro
 		}
 	}
 
+	now := time.Now()
+	requeueAfter = new(time.Duration)
+	*requeueAfter = virtualnode.ClusterSummaryHeartbeatPeriod
+
 	actual, err := r.customclientset.MulticlusterV1alpha1().ClusterSummaries().Get(ctx, singletonName, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
+			conditions, _ := virtualnode.AggregateConditions(nil, nodes, now)
 			gold := &v1alpha1.ClusterSummary{
 				Allocatable: allocatable,
 				Capacity:    capacity,
+				Conditions:  conditions,
 			}
 			gold.Labels = l
 			gold.Name = singletonName
@@ -121,19 +128,26 @@ func (r downstream) Handle(_ interface{}) (requeueAfter *time.Duration, err erro
 			if err != nil {
 				return nil, err
 			}
+			return requeueAfter, nil
 		}
+		return nil, err
 	}
 
-	if !reflect.DeepEqual(capacity, actual.Capacity) || !reflect.DeepEqual(allocatable, actual.Allocatable) || !labels.Equals(l, actual.Labels) {
+	// conditions' heartbeats are refreshed periodically (even if nothing else changed),
+	// so source clusters can tell whether this controller is still running
+	conditions, conditionsChanged := virtualnode.AggregateConditions(actual.Conditions, nodes, now)
+
+	if conditionsChanged || !reflect.DeepEqual(capacity, actual.Capacity) || !reflect.DeepEqual(allocatable, actual.Allocatable) || !labels.Equals(l, actual.Labels) {
 		actualCopy := actual.DeepCopy()
 		actualCopy.Allocatable = allocatable
 		actualCopy.Capacity = capacity
 		actualCopy.Labels = l
+		actualCopy.Conditions = conditions
 		actual, err = r.customclientset.MulticlusterV1alpha1().ClusterSummaries().Update(ctx, actualCopy, v1.UpdateOptions{})
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return nil, nil
+	return requeueAfter, nil
 }
@@ -25,6 +25,8 @@ import (
 
 	"admiralty.io/multicluster-scheduler/pkg/config/agent"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
@@ -36,6 +38,7 @@ import (
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/kubernetes/pkg/apis/core/v1/helper"
 
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/common"
 	"admiralty.io/multicluster-scheduler/pkg/controller"
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
@@ -54,6 +57,8 @@ type upstream struct {
 
 	nodeLister           corelisters.NodeLister
 	clusterSummaryLister listers.ClusterSummaryLister
+	clusterTargetLister  listers.ClusterTargetLister
+	targetLister         listers.TargetLister
 	nodeStatusUpdater    NodeStatusUpdater
 
 	compiledExcludedLabelsRegexp *regexp.Regexp
@@ -64,6 +69,8 @@ func NewUpstreamController(
 	kubeclientset kubernetes.Interface,
 	nodeInformer coreinformers.NodeInformer,
 	clusterSummaryInformer informers.ClusterSummaryInformer,
+	clusterTargetInformer informers.ClusterTargetInformer,
+	targetInformer informers.TargetInformer,
 	nodeStatusUpdater NodeStatusUpdater,
 ) *controller.Controller {
 
@@ -72,10 +79,16 @@ func NewUpstreamController(
 		kubeclientset:        kubeclientset,
 		nodeLister:           nodeInformer.Lister(),
 		clusterSummaryLister: clusterSummaryInformer.Lister(),
+		clusterTargetLister:  clusterTargetInformer.Lister(),
+		targetLister:         targetInformer.Lister(),
 		nodeStatusUpdater:    nodeStatusUpdater,
 	}
 
-	c := controller.New("cluster-resources-upstream", r, nodeInformer.Informer().HasSynced, clusterSummaryInformer.Informer().HasSynced)
+	c := controller.New("cluster-resources-upstream", r,
+		nodeInformer.Informer().HasSynced,
+		clusterSummaryInformer.Informer().HasSynced,
+		clusterTargetInformer.Informer().HasSynced,
+		targetInformer.Informer().HasSynced)
 
 	// node informer doesn't use field selector on metadata.name == targetName
 	// because we use its cache to list other nodes to infer multi-huge-page support
@@ -89,13 +102,20 @@ func NewUpstreamController(
 	clusterSummaryInformer.Informer().AddEventHandler(controller.HandleAllWith(func(_ interface{}) {
 		c.EnqueueKey(target.VirtualNodeName)
 	}))
+	// the target status controller probes the target cluster; react to its Reachable condition
+	enqueueIfTarget := func(obj interface{}) {
+		if m, err := meta.Accessor(obj); err == nil && m.GetNamespace() == target.Namespace && m.GetName() == target.Name {
+			c.EnqueueKey(target.VirtualNodeName)
+		}
+	}
+	clusterTargetInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueueIfTarget))
+	targetInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(enqueueIfTarget))
 
 	if target.ExcludedLabelsRegexp != nil {
 		var err error
 		r.compiledExcludedLabelsRegexp, err = regexp.Compile(*target.ExcludedLabelsRegexp)
 		if err != nil {
-			// don't crash if regexp cannot be compiled
-			// TODO reject Target at admission
+			// don't crash, aggregate all labels instead (see target.Validator)
 			utilruntime.HandleError(fmt.Errorf("cannot compile excluded aggregated labels regexp for target %s: %v", target.VirtualNodeName, err))
 		}
 	}
@@ -103,51 +123,55 @@ func NewUpstreamController(
 	return c
 }
 
+// resyncPeriod is how often the virtual node's conditions and taints are recomputed, even if nothing changes in the source cluster,
+// e.g., to taint it once its target has been unreachable for virtualnode.UnreachableGracePeriod, or to detect a stale cluster summary
+const resyncPeriod = 30 * time.Second
+
 func (r upstream) Handle(key interface{}) (requeueAfter *time.Duration, err error) {
 	ctx := context.Background()
 	targetName := key.(string)
 
-	clusterSummary, err := r.clusterSummaryLister.Get(singletonName)
+	virtualNode, err := r.nodeLister.Get(targetName)
 	if err != nil {
 		return nil, err
 	}
 
-	// if HugePageStorageMediumSize is enabled in target cluster but not in source cluster,
-	// virtual node status update would fail if we included multiple huge page sizes in capacity or allocatable
-	// in that case, we purge all page sizes (because there's no reason to keep one over the others)
-	// huge pages requests are still respected, thanks to our proxy-candidate scheduling algorithm
-	// TODO: find a good way to e2e test this, because kind inherits huge page sizes from host,
-	// so we can't have two kind clusters with different huge page sizes on test host.
-
-	// hack to infer HugePageStorageMediumSize Kubernetes feature flag from other nodes
-	sel, err := labels.Parse(common.LabelAndTaintKeyVirtualKubeletProvider + "!=" + common.VirtualKubeletProviderName)
-	nodes, err := r.nodeLister.List(sel)
-	nodesHaveMultipleHugePageSizes := false
-	for _, n := range nodes {
-		if hasMultipleHugePageSizes(n.Status.Capacity) || hasMultipleHugePageSizes(n.Status.Allocatable) {
-			nodesHaveMultipleHugePageSizes = true
-			break
-		}
+	// the target cluster is probed by the target status controller, we don't probe it again here
+	reachableCondition, err := r.getReachableCondition()
+	if err != nil {
+		return nil, err
 	}
-	clusterSummaryHasMultipleHugePageSizes := hasMultipleHugePageSizes(clusterSummary.Capacity) || hasMultipleHugePageSizes(clusterSummary.Allocatable)
-	if clusterSummaryHasMultipleHugePageSizes && !nodesHaveMultipleHugePageSizes {
-		clusterSummary = clusterSummary.DeepCopy()
-		purgeHugePageResources(clusterSummary.Capacity)
-		purgeHugePageResources(clusterSummary.Allocatable)
+	now := time.Now()
+	reachable, reachableErr := virtualnode.TargetReachable(reachableCondition, now)
+	if reachable && reachableErr != nil {
+		utilruntime.HandleError(fmt.Errorf("target %s unreachable since %s, within grace period: %v",
+			r.target.VirtualNodeName, reachableCondition.LastTransitionTime.Format(time.RFC3339), reachableErr))
 	}
 
-	virtualNode, err := r.nodeLister.Get(targetName)
+	// if the target cluster is unreachable, the informer's cache holds its last known resources and labels, which we keep advertising
+	knownClusterSummary, err := r.clusterSummaryLister.Get(singletonName)
 	if err != nil {
-		return nil, err
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		knownClusterSummary = nil
 	}
 
-	l := r.reconcileLabels(clusterSummary.Labels)
+	conditions, conditionsChanged := virtualnode.Conditions(virtualNode.Status.Conditions, reachable, reachableErr, knownClusterSummary, now)
+	taints, taintsChanged := virtualnode.Taints(virtualNode.Spec.Taints, conditions, now)
 
-	// we can't group status update with label update because status update POSTs to the status subresource
+	l := virtualNode.Labels
+	if knownClusterSummary != nil {
+		knownClusterSummary = r.purgeHugePageResourcesIfUnsupported(knownClusterSummary)
+		l = r.reconcileLabels(knownClusterSummary.Labels)
+	}
+
+	// we can't group status update with label and taint updates because status update POSTs to the status subresource
 	// also, we use patch, not update, because for some reason EKS cloud controller deletes nodes if we use update
-	if !labels.Equals(virtualNode.Labels, l) {
+	if !labels.Equals(virtualNode.Labels, l) || taintsChanged {
 		actualCopy := virtualNode.DeepCopy()
 		actualCopy.Labels = l
+		actualCopy.Spec.Taints = taints
 
 		oldData, err := json.Marshal(virtualNode)
 		if err != nil {
@@ -170,22 +194,87 @@ func (r upstream) Handle(key interface{}) (requeueAfter *time.Duration, err erro
 		}
 	}
 
-	if !reflect.DeepEqual(virtualNode.Status.Capacity, clusterSummary.Capacity) ||
-		!reflect.DeepEqual(virtualNode.Status.Allocatable, clusterSummary.Allocatable) {
+	resourcesChanged := knownClusterSummary != nil &&
+		(!reflect.DeepEqual(virtualNode.Status.Capacity, knownClusterSummary.Capacity) ||
+			!reflect.DeepEqual(virtualNode.Status.Allocatable, knownClusterSummary.Allocatable))
+	if resourcesChanged || conditionsChanged {
 		actualCopy := virtualNode.DeepCopy()
-		actualCopy.Status.Allocatable = clusterSummary.Allocatable
-		actualCopy.Status.Capacity = clusterSummary.Capacity
+		if knownClusterSummary != nil {
+			actualCopy.Status.Allocatable = knownClusterSummary.Allocatable
+			actualCopy.Status.Capacity = knownClusterSummary.Capacity
+		}
+		actualCopy.Status.Conditions = conditions
 		// we use nodeStatusUpdater instead of kubeclientset because VK needs to update its internal representation
 		// otherwise it would override our changes
 		r.nodeStatusUpdater.UpdateNodeStatus(actualCopy)
 		// VK doesn't surface errors, so we have no way to requeue if transient error, TODO? fix upstream
 	}
 
-	return nil, nil
+	requeueAfter = new(time.Duration)
+	*requeueAfter = resyncPeriod
+	return requeueAfter, nil
+}
+
+// getReachableCondition returns the Reachable condition of the Target or ClusterTarget status, if any
+func (r upstream) getReachableCondition() (*metav1.Condition, error) {
+	var conditions []metav1.Condition
+	if r.target.Namespace == "" {
+		t, err := r.clusterTargetLister.Get(r.target.Name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil // being removed
+			}
+			return nil, err
+		}
+		conditions = t.Status.Conditions
+	} else {
+		t, err := r.targetLister.Targets(r.target.Namespace).Get(r.target.Name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil // being removed
+			}
+			return nil, err
+		}
+		conditions = t.Status.Conditions
+	}
+	return meta.FindStatusCondition(conditions, v1alpha1.TargetConditionReachable), nil
+}
+
+// purgeHugePageResourcesIfUnsupported returns a copy of the cluster summary without huge page resources
+// if the source cluster doesn't support multiple huge page sizes but the cluster summary has some
+func (r upstream) purgeHugePageResourcesIfUnsupported(clusterSummary *v1alpha1.ClusterSummary) *v1alpha1.ClusterSummary {
+	// if HugePageStorageMediumSize is enabled in target cluster but not in source cluster,
+	// virtual node status update would fail if we included multiple huge page sizes in capacity or allocatable
+	// in that case, we purge all page sizes (because there's no reason to keep one over the others)
+	// huge pages requests are still respected, thanks to our proxy-candidate scheduling algorithm
+	// TODO: find a good way to e2e test this, because kind inherits huge page sizes from host,
+	// so we can't have two kind clusters with different huge page sizes on test host.
+
+	// hack to infer HugePageStorageMediumSize Kubernetes feature flag from other nodes
+	sel, err := labels.Parse(common.LabelAndTaintKeyVirtualKubeletProvider + "!=" + common.VirtualKubeletProviderName)
+	utilruntime.Must(err)
+	nodes, err := r.nodeLister.List(sel)
+	if err != nil {
+		utilruntime.HandleError(err)
+	}
+	nodesHaveMultipleHugePageSizes := false
+	for _, n := range nodes {
+		if hasMultipleHugePageSizes(n.Status.Capacity) || hasMultipleHugePageSizes(n.Status.Allocatable) {
+			nodesHaveMultipleHugePageSizes = true
+			break
+		}
+	}
+	clusterSummaryHasMultipleHugePageSizes := hasMultipleHugePageSizes(clusterSummary.Capacity) || hasMultipleHugePageSizes(clusterSummary.Allocatable)
+	if clusterSummaryHasMultipleHugePageSizes && !nodesHaveMultipleHugePageSizes {
+		clusterSummary = clusterSummary.DeepCopy()
+		purgeHugePageResources(clusterSummary.Capacity)
+		purgeHugePageResources(clusterSummary.Allocatable)
+	}
+	return clusterSummary
 }
 
 func (r upstream) reconcileLabels(clusterSummaryLabels map[string]string) map[string]string {
-	l := virtualnode.BaseLabels(r.target.Namespace, r.target.Name)
+	l := virtualnode.BaseLabels(r.target)
 	for k, v := range clusterSummaryLabels {
 		regExp := r.compiledExcludedLabelsRegexp
 		if regExp == nil || !regExp.MatchString(fmt.Sprintf("%s=%s", k, v)) {
@@ -19,16 +19,24 @@ package resources
 import (
 	"regexp"
 	"testing"
+	"time"
 
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/model/virtualnode"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func Test_upstream_reconcileLabels(t *testing.T) {
 	clusterSummaryLabels := map[string]string{"k1": "v1", "k2": "v2", "prefix.io/k1": "v1"}
 	addBaseLabels := func(m map[string]string) map[string]string {
-		l := virtualnode.BaseLabels("target-namespace", "target-name")
+		l := virtualnode.BaseLabels(agent.Target{Name: "target-name", Namespace: "target-namespace"})
 		for k, v := range m {
 			l[k] = v
 		}
@@ -74,3 +82,82 @@ func Test_upstream_reconcileLabels(t *testing.T) {
 		})
 	}
 }
+
+type fakeNodeStatusUpdater struct {
+	node *corev1.Node
+}
+
+func (u *fakeNodeStatusUpdater) UpdateNodeStatus(node *corev1.Node) {
+	u.node = node
+}
+
+func TestUpstreamReachability(t *testing.T) {
+	target := agent.Target{Name: "cloud", Namespace: "ns", VirtualNodeName: "admiralty-ns-cloud"}
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name        string
+		reachable   *metav1.Condition
+		wantReady   corev1.ConditionStatus
+		wantTainted bool
+	}{{
+		name:      "not probed yet",
+		wantReady: corev1.ConditionTrue,
+	}, {
+		name: "unreachable within grace period",
+		reachable: &metav1.Condition{Type: v1alpha1.TargetConditionReachable, Status: metav1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(now.Add(-virtualnode.UnreachableGracePeriod / 2))},
+		wantReady: corev1.ConditionTrue,
+	}, {
+		name: "unreachable",
+		reachable: &metav1.Condition{Type: v1alpha1.TargetConditionReachable, Status: metav1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(now.Add(-2 * virtualnode.UnreachableGracePeriod))},
+		wantReady:   corev1.ConditionUnknown,
+		wantTainted: true,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: target.VirtualNodeName}}
+			tgt := &v1alpha1.Target{ObjectMeta: metav1.ObjectMeta{Namespace: target.Namespace, Name: target.Name}}
+			if tc.reachable != nil {
+				tgt.Status.Conditions = []metav1.Condition{*tc.reachable}
+			}
+
+			nodeIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, nodeIndexer.Add(node))
+			targetIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			require.NoError(t, targetIndexer.Add(tgt))
+			kubeClient := fake.NewSimpleClientset(node)
+			u := &fakeNodeStatusUpdater{}
+			r := upstream{
+				target:               target,
+				kubeclientset:        kubeClient,
+				nodeLister:           corelisters.NewNodeLister(nodeIndexer),
+				clusterSummaryLister: listers.NewClusterSummaryLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				clusterTargetLister:  listers.NewClusterTargetLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+				targetLister:         listers.NewTargetLister(targetIndexer),
+				nodeStatusUpdater:    u,
+			}
+
+			_, err := r.Handle(target.VirtualNodeName)
+			require.NoError(t, err)
+			require.NotNil(t, u.node)
+			var ready corev1.ConditionStatus
+			for _, c := range u.node.Status.Conditions {
+				if c.Type == corev1.NodeReady {
+					ready = c.Status
+				}
+			}
+			require.Equal(t, tc.wantReady, ready)
+
+			patched, err := kubeClient.Tracker().Get(corev1.SchemeGroupVersion.WithResource("nodes"), "", target.VirtualNodeName)
+			require.NoError(t, err)
+			tainted := false
+			for _, taint := range patched.(*corev1.Node).Spec.Taints {
+				if taint.Key == corev1.TaintNodeUnreachable && taint.Effect == corev1.TaintEffectNoExecute {
+					tainted = true
+				}
+			}
+			require.Equal(t, tc.wantTainted, tainted)
+		})
+	}
+}
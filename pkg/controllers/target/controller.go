@@ -17,34 +17,33 @@
 package target
 
 import (
-	"context"
 	"fmt"
 	"reflect"
 	"sync"
 	"time"
 
-	"admiralty.io/multicluster-scheduler/pkg/common"
-	"github.com/hashicorp/go-multierror"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	coreinformers "k8s.io/client-go/informers/core/v1"
-	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 
 	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
 	"admiralty.io/multicluster-scheduler/pkg/controller"
 	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
 	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
 )
 
-type reconciler struct {
-	kubeClient kubernetes.Interface
+// Handler is notified of target changes. agent.TargetSet implements it.
+type Handler interface {
+	agent.TargetHandler
+	SetSynced()
+}
 
+type reconciler struct {
 	clusterTargetLister listers.ClusterTargetLister
 	targetLister        listers.TargetLister
 	secretLister        corelisters.SecretLister
@@ -52,13 +51,12 @@ type reconciler struct {
 	clusterTargetIndex cache.Indexer
 	targetIndex        cache.Indexer
 
-	installNamespace                string
-	controllerManagerDeploymentName string
-	proxySchedulerDeploymentName    string
+	handler Handler
 
 	mu                   sync.Mutex
 	targetSpecs          map[string]interface{}
 	kubeconfigSecretData map[string]interface{}
+	targets              map[string]agent.Target
 }
 
 const (
@@ -66,19 +64,16 @@ const (
 	targetByKubeconfigSecret        = "targetByKubeconfigSecret"
 )
 
+// NewController returns a controller that watches Targets, ClusterTargets and their kubeconfig secrets,
+// and notifies handler when targets are added, removed or changed (removed, then added again).
 func NewController(
-	kubeClient kubernetes.Interface,
-	installNamespace string,
-	controllerManagerDeploymentName string,
-	proxySchedulerDeploymentName string,
 	clusterTargetInformer informers.ClusterTargetInformer,
 	targetInformer informers.TargetInformer,
 	secretInformer coreinformers.SecretInformer,
+	handler Handler,
 ) *controller.Controller {
 
 	r := &reconciler{
-		kubeClient: kubeClient,
-
 		clusterTargetLister: clusterTargetInformer.Lister(),
 		targetLister:        targetInformer.Lister(),
 		secretLister:        secretInformer.Lister(),
@@ -86,58 +81,81 @@ func NewController(
 		clusterTargetIndex: clusterTargetInformer.Informer().GetIndexer(),
 		targetIndex:        targetInformer.Informer().GetIndexer(),
 
-		installNamespace:                installNamespace,
-		controllerManagerDeploymentName: controllerManagerDeploymentName,
-		proxySchedulerDeploymentName:    proxySchedulerDeploymentName,
+		handler: handler,
+
+		targets: map[string]agent.Target{},
 	}
 
-	c := controller.New("source", r,
+	c := controller.New("target", r,
 		clusterTargetInformer.Informer().HasSynced,
 		targetInformer.Informer().HasSynced,
 		secretInformer.Informer().HasSynced)
 
-	clusterTargetInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(c.EnqueueObject))
-	targetInformer.Informer().AddEventHandler(controller.HandleAddUpdateWith(c.EnqueueObject))
+	// the reconciler always considers all targets, so we only need a single key,
+	// which we enqueue once to initialize the target set, even if there are no targets
+	enqueue := func(_ interface{}) {
+		c.EnqueueKey(singletonKey)
+	}
+	c.EnqueueKey(singletonKey)
 
-	utilruntime.Must(clusterTargetInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
-		clusterTargetByKubeconfigSecret: func(obj interface{}) ([]string, error) {
-			ct := obj.(*multiclusterv1alpha1.ClusterTarget)
-			if s := ct.Spec.KubeconfigSecret; s != nil {
-				return []string{fmt.Sprintf("%s/%s", s.Namespace, s.Name)}, nil
-			}
-			return nil, nil
-		},
-	}))
-	utilruntime.Must(targetInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
-		targetByKubeconfigSecret: func(obj interface{}) ([]string, error) {
-			t := obj.(*multiclusterv1alpha1.Target)
-			if s := t.Spec.KubeconfigSecret; s != nil {
-				return []string{fmt.Sprintf("%s/%s", t.Namespace, s.Name)}, nil
-			}
-			return nil, nil
-		},
-	}))
+	clusterTargetInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueue))
+	targetInformer.Informer().AddEventHandler(controller.HandleAllWith(enqueue))
+
+	addKubeconfigSecretIndexers(clusterTargetInformer, targetInformer)
 
 	secretInformer.Informer().AddEventHandler(controller.HandleAllWith(func(obj interface{}) {
-		secret := obj.(*corev1.Secret)
-		ct, err := r.clusterTargetIndex.ByIndex(clusterTargetByKubeconfigSecret, fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
-		utilruntime.Must(err)
-		for _, obj := range ct {
-			c.EnqueueObject(obj)
-		}
-		t, err := r.targetIndex.ByIndex(targetByKubeconfigSecret, fmt.Sprintf("%s/%s", secret.Namespace, secret.Name))
-		utilruntime.Must(err)
-		for _, obj := range t {
-			c.EnqueueObject(obj)
+		clusterTargets, targets := r.getTargetsByKubeconfigSecret(obj.(*corev1.Secret))
+		if len(clusterTargets) > 0 || len(targets) > 0 {
+			enqueue(obj)
 		}
 	}))
 
 	return c
 }
 
-func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
-	ctx := context.Background()
+// addKubeconfigSecretIndexers adds indexers to find targets by kubeconfig secret, unless they were already added,
+// e.g., by another controller sharing the same informers.
+func addKubeconfigSecretIndexers(clusterTargetInformer informers.ClusterTargetInformer, targetInformer informers.TargetInformer) {
+	if _, ok := clusterTargetInformer.Informer().GetIndexer().GetIndexers()[clusterTargetByKubeconfigSecret]; !ok {
+		utilruntime.Must(clusterTargetInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+			clusterTargetByKubeconfigSecret: func(obj interface{}) ([]string, error) {
+				ct := obj.(*multiclusterv1alpha1.ClusterTarget)
+				if s := ct.Spec.KubeconfigSecret; s != nil {
+					return []string{fmt.Sprintf("%s/%s", s.Namespace, s.Name)}, nil
+				}
+				return nil, nil
+			},
+		}))
+	}
+	if _, ok := targetInformer.Informer().GetIndexer().GetIndexers()[targetByKubeconfigSecret]; !ok {
+		utilruntime.Must(targetInformer.Informer().AddIndexers(map[string]cache.IndexFunc{
+			targetByKubeconfigSecret: func(obj interface{}) ([]string, error) {
+				t := obj.(*multiclusterv1alpha1.Target)
+				if s := t.Spec.KubeconfigSecret; s != nil {
+					return []string{fmt.Sprintf("%s/%s", t.Namespace, s.Name)}, nil
+				}
+				return nil, nil
+			},
+		}))
+	}
+}
+
+func getTargetsByKubeconfigSecret(clusterTargetIndex, targetIndex cache.Indexer, secret *corev1.Secret) (clusterTargets []interface{}, targets []interface{}) {
+	key := fmt.Sprintf("%s/%s", secret.Namespace, secret.Name)
+	clusterTargets, err := clusterTargetIndex.ByIndex(clusterTargetByKubeconfigSecret, key)
+	utilruntime.Must(err)
+	targets, err = targetIndex.ByIndex(targetByKubeconfigSecret, key)
+	utilruntime.Must(err)
+	return clusterTargets, targets
+}
+
+func (c *reconciler) getTargetsByKubeconfigSecret(secret *corev1.Secret) (clusterTargets []interface{}, targets []interface{}) {
+	return getTargetsByKubeconfigSecret(c.clusterTargetIndex, c.targetIndex, secret)
+}
+
+const singletonKey = "targets"
 
+func (c *reconciler) Handle(_ interface{}) (requeueAfter *time.Duration, err error) {
 	clusterTargets, err := c.clusterTargetLister.List(labels.Everything())
 	if err != nil {
 		return nil, err
@@ -150,6 +168,7 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 
 	targetSpecs := make(map[string]interface{}, len(targets)+len(clusterTargets))
 	kubeconfigSecretData := make(map[string]interface{}, len(targets)+len(clusterTargets))
+	kubeconfigSecrets := make(map[string]*corev1.Secret, len(targets)+len(clusterTargets))
 	for _, t := range clusterTargets {
 		key := fmt.Sprintf("%s/%s", t.Namespace, t.Name)
 		targetSpecs[key] = t.Spec
@@ -162,6 +181,7 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 				continue
 			}
 			kubeconfigSecretData[key] = secret.Data
+			kubeconfigSecrets[key] = secret
 		}
 	}
 	for _, t := range targets {
@@ -176,28 +196,54 @@ func (c *reconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err e
 				continue
 			}
 			kubeconfigSecretData[key] = secret.Data
+			kubeconfigSecrets[key] = secret
 		}
 	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if !reflect.DeepEqual(c.targetSpecs, targetSpecs) || !reflect.DeepEqual(c.kubeconfigSecretData, kubeconfigSecretData) {
-		// rollout restarts
-		g := multierror.Group{}
-		for _, deployName := range []string{c.controllerManagerDeploymentName, c.proxySchedulerDeploymentName} {
-			deployName := deployName
-			g.Go(func() error {
-				p := []byte(`{"spec":{"template":{"metadata":{"annotations":{"` + common.AnnotationKeyRestartedAt + `":"` + time.Now().UTC().Format(time.RFC3339) + `"}}}}}`)
-				_, err := c.kubeClient.AppsV1().Deployments(c.installNamespace).Patch(ctx, deployName, types.MergePatchType, p, metav1.PatchOptions{})
-				return err
-			})
+
+	// remove targets that were deleted or changed
+	for key, t := range c.targets {
+		if !reflect.DeepEqual(c.targetSpecs[key], targetSpecs[key]) || !reflect.DeepEqual(c.kubeconfigSecretData[key], kubeconfigSecretData[key]) {
+			c.handler.RemoveTarget(t)
+			delete(c.targets, key)
 		}
-		if err := g.Wait(); err != nil {
-			return nil, err
+	}
+
+	// add targets that were created or changed (or were invalid and may have been fixed)
+	for _, ct := range clusterTargets {
+		key := fmt.Sprintf("%s/%s", ct.Namespace, ct.Name)
+		if _, ok := c.targets[key]; ok {
+			continue
+		}
+		t, err := agent.TargetFromClusterTarget(ct, kubeconfigSecrets[key])
+		if err != nil {
+			// don't requeue, the target will be reconsidered when it or its kubeconfig secret changes
+			utilruntime.HandleError(err)
+			continue
 		}
-		c.targetSpecs = targetSpecs
-		c.kubeconfigSecretData = kubeconfigSecretData
+		c.handler.AddTarget(t)
+		c.targets[key] = t
 	}
+	for _, tg := range targets {
+		key := fmt.Sprintf("%s/%s", tg.Namespace, tg.Name)
+		if _, ok := c.targets[key]; ok {
+			continue
+		}
+		t, err := agent.TargetFromTarget(tg, kubeconfigSecrets[key])
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		c.handler.AddTarget(t)
+		c.targets[key] = t
+	}
+
+	c.targetSpecs = targetSpecs
+	c.kubeconfigSecretData = kubeconfigSecretData
+
+	c.handler.SetSynced()
 
 	return nil, nil
 }
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	customfake "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/fake"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: c
+  cluster:
+    server: https://%s
+contexts:
+- name: c
+  context:
+    cluster: c
+    user: u
+current-context: c
+users:
+- name: u
+  user:
+    token: t
+`
+
+type recorder struct {
+	events []string
+	synced bool
+}
+
+func (r *recorder) AddTarget(t agent.Target) {
+	r.events = append(r.events, "add "+t.VirtualNodeName+" "+t.ClientConfig.Host)
+}
+
+func (r *recorder) RemoveTarget(t agent.Target) {
+	r.events = append(r.events, "remove "+t.VirtualNodeName)
+}
+
+func (r *recorder) SetSynced() {
+	r.synced = true
+}
+
+func TestHandle(t *testing.T) {
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	customInformerFactory := informers.NewSharedInformerFactory(customfake.NewSimpleClientset(), 0)
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	targetInformer := customInformerFactory.Multicluster().V1alpha1().Targets()
+	clusterTargetInformer := customInformerFactory.Multicluster().V1alpha1().ClusterTargets()
+
+	h := &recorder{}
+	NewController(clusterTargetInformer, targetInformer, secretInformer, h)
+
+	r := &reconciler{
+		clusterTargetLister: clusterTargetInformer.Lister(),
+		targetLister:        targetInformer.Lister(),
+		secretLister:        secretInformer.Lister(),
+		handler:             h,
+		targets:             map[string]agent.Target{},
+	}
+
+	secretStore := secretInformer.Informer().GetStore()
+	targetStore := targetInformer.Informer().GetStore()
+
+	tg := &v1alpha1.Target{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a"},
+		Spec:       v1alpha1.TargetSpec{KubeconfigSecret: &v1alpha1.KubeconfigSecret{Name: "a"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a"},
+		Data:       map[string][]byte{"config": []byte(fmt.Sprintf(kubeconfig, "a"))},
+	}
+	virtualNodeName := "admiralty-ns-a"
+
+	steps := []struct {
+		name   string
+		change func()
+		events []string
+	}{
+		{
+			name:   "no targets",
+			change: func() {},
+		},
+		{
+			name:   "missing secret",
+			change: func() { must(t, targetStore.Add(tg)) },
+		},
+		{
+			name:   "secret created",
+			change: func() { must(t, secretStore.Add(secret)) },
+			events: []string{"add " + virtualNodeName + " https://a"},
+		},
+		{
+			name:   "no change",
+			change: func() {},
+		},
+		{
+			name: "secret updated",
+			change: func() {
+				s := secret.DeepCopy()
+				s.Data["config"] = []byte(fmt.Sprintf(kubeconfig, "b"))
+				must(t, secretStore.Update(s))
+			},
+			events: []string{"remove " + virtualNodeName, "add " + virtualNodeName + " https://b"},
+		},
+		{
+			name:   "target deleted",
+			change: func() { must(t, targetStore.Delete(tg)) },
+			events: []string{"remove " + virtualNodeName},
+		},
+	}
+
+	for _, s := range steps {
+		h.events = nil
+		s.change()
+		if _, err := r.Handle(singletonKey); err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if !h.synced {
+			t.Fatalf("%s: expected handler to be synced", s.name)
+		}
+		if !reflect.DeepEqual(h.events, s.events) {
+			t.Errorf("%s: expected events %v, got %v", s.name, s.events, h.events)
+		}
+	}
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,316 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+
+	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/multicluster/v1alpha1"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+)
+
+// TODO: configurable
+var (
+	StatusProbePeriod  = 30 * time.Second
+	StatusProbeTimeout = 5 * time.Second
+	// LastContactTimeRefreshPeriod is how often the last contact time is refreshed while conditions don't change,
+	// so that statuses aren't written at every probe
+	LastContactTimeRefreshPeriod = 5 * time.Minute
+	// StatusProbeWorkers is how many targets are probed concurrently (keys are per target),
+	// so that unreachable targets, which take up to StatusProbeTimeout per request, don't delay the probes of the others
+	StatusProbeWorkers = 10
+)
+
+// virtualNodeKey is enqueued when a target's virtual node changes, to re-evaluate the VirtualNodeReady condition
+// without probing the target cluster out of schedule
+type virtualNodeKey string
+
+type statusReconciler struct {
+	customClient versioned.Interface
+
+	clusterTargetLister listers.ClusterTargetLister
+	targetLister        listers.TargetLister
+	secretLister        corelisters.SecretLister
+	nodeLister          corelisters.NodeLister
+}
+
+// NewStatusController returns a controller that periodically probes targets,
+// and reports conditions, last contact time, virtual node name and finalizer on Target and ClusterTarget statuses.
+// Unlike the target controller, it should only run in the leader.
+func NewStatusController(
+	customClient versioned.Interface,
+	clusterTargetInformer informers.ClusterTargetInformer,
+	targetInformer informers.TargetInformer,
+	secretInformer coreinformers.SecretInformer,
+	nodeInformer coreinformers.NodeInformer,
+) *controller.Controller {
+
+	r := &statusReconciler{
+		customClient: customClient,
+
+		clusterTargetLister: clusterTargetInformer.Lister(),
+		targetLister:        targetInformer.Lister(),
+		secretLister:        secretInformer.Lister(),
+		nodeLister:          nodeInformer.Lister(),
+	}
+
+	c := controller.New("target-status", r,
+		clusterTargetInformer.Informer().HasSynced,
+		targetInformer.Informer().HasSynced,
+		secretInformer.Informer().HasSynced,
+		nodeInformer.Informer().HasSynced)
+
+	// status updates don't change the generation, so we don't react to our own updates
+	enqueueIfGenerationChanged := cache.ResourceEventHandlerFuncs{
+		AddFunc: c.EnqueueObject,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if oldObj.(metav1.Object).GetGeneration() != newObj.(metav1.Object).GetGeneration() {
+				c.EnqueueObject(newObj)
+			}
+		},
+	}
+	clusterTargetInformer.Informer().AddEventHandler(enqueueIfGenerationChanged)
+	targetInformer.Informer().AddEventHandler(enqueueIfGenerationChanged)
+
+	addKubeconfigSecretIndexers(clusterTargetInformer, targetInformer)
+	clusterTargetIndex := clusterTargetInformer.Informer().GetIndexer()
+	targetIndex := targetInformer.Informer().GetIndexer()
+	secretInformer.Informer().AddEventHandler(controller.HandleAllWith(func(obj interface{}) {
+		clusterTargets, targets := getTargetsByKubeconfigSecret(clusterTargetIndex, targetIndex, obj.(*corev1.Secret))
+		for _, t := range clusterTargets {
+			c.EnqueueObject(t)
+		}
+		for _, t := range targets {
+			c.EnqueueObject(t)
+		}
+	}))
+
+	nodeInformer.Informer().AddEventHandler(controller.HandleAllWith(func(obj interface{}) {
+		l := obj.(*corev1.Node).Labels
+		if n, ok := l[common.LabelKeyClusterTargetName]; ok {
+			c.EnqueueKey(virtualNodeKey(n))
+		} else if n, ok := l[common.LabelKeyTargetName]; ok {
+			c.EnqueueKey(virtualNodeKey(l[common.LabelKeyTargetNamespace] + "/" + n))
+		}
+	}))
+
+	return c
+}
+
+func (r *statusReconciler) Handle(obj interface{}) (requeueAfter *time.Duration, err error) {
+	ctx := context.Background()
+
+	var key string
+	probe := true
+	requeueAfter = &StatusProbePeriod
+	switch k := obj.(type) {
+	case virtualNodeKey:
+		// the target's own key is requeued to probe it periodically
+		key, probe, requeueAfter = string(k), false, nil
+	default:
+		key = k.(string)
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	utilruntime.Must(err)
+
+	if namespace == "" {
+		ct, err := r.clusterTargetLister.Get(name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		var secret *corev1.Secret
+		if s := ct.Spec.KubeconfigSecret; s != nil {
+			if secret, err = r.getSecret(s.Namespace, s.Name); err != nil {
+				return nil, err
+			}
+		}
+		t, buildErr := agent.TargetFromClusterTarget(ct, secret)
+
+		status := multiclusterv1alpha1.TargetStatus(*ct.Status.DeepCopy())
+		r.reconcileStatus(ctx, &status, t, buildErr, namespace, name, ct.Generation, probe)
+		if equality.Semantic.DeepEqual(multiclusterv1alpha1.TargetStatus(ct.Status), status) {
+			return requeueAfter, nil
+		}
+
+		ctCopy := ct.DeepCopy()
+		ctCopy.Status = multiclusterv1alpha1.ClusterTargetStatus(status)
+		if _, err := r.customClient.MulticlusterV1alpha1().ClusterTargets().UpdateStatus(ctx, ctCopy, metav1.UpdateOptions{}); err != nil {
+			return nil, err
+		}
+		return requeueAfter, nil
+	}
+
+	tg, err := r.targetLister.Targets(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var secret *corev1.Secret
+	if s := tg.Spec.KubeconfigSecret; s != nil {
+		if secret, err = r.getSecret(namespace, s.Name); err != nil {
+			return nil, err
+		}
+	}
+	t, buildErr := agent.TargetFromTarget(tg, secret)
+
+	status := *tg.Status.DeepCopy()
+	r.reconcileStatus(ctx, &status, t, buildErr, namespace, name, tg.Generation, probe)
+	if equality.Semantic.DeepEqual(tg.Status, status) {
+		return requeueAfter, nil
+	}
+
+	tgCopy := tg.DeepCopy()
+	tgCopy.Status = status
+	if _, err := r.customClient.MulticlusterV1alpha1().Targets(namespace).UpdateStatus(ctx, tgCopy, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return requeueAfter, nil
+}
+
+// getSecret returns nil if the secret is not found, which is reported as an invalid kubeconfig
+func (r *statusReconciler) getSecret(namespace, name string) (*corev1.Secret, error) {
+	secret, err := r.secretLister.Secrets(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+type conditionSetter func(conditionType string, conditionStatus metav1.ConditionStatus, reason, message string)
+
+// reconcileStatus computes a target's status; the target cluster is only probed if probe is true,
+// otherwise only the VirtualNodeReady condition is re-evaluated
+func (r *statusReconciler) reconcileStatus(ctx context.Context, status *multiclusterv1alpha1.TargetStatus, t agent.Target, buildErr error, namespace, name string, generation int64, probe bool) {
+	status.VirtualNodeName = agent.VirtualNodeName(namespace, name)
+	status.Finalizer = agent.Finalizer(namespace, name)
+
+	previousConditions := append([]metav1.Condition(nil), status.Conditions...)
+	setCondition := func(conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             conditionStatus,
+			ObservedGeneration: generation,
+			Reason:             reason,
+			Message:            message,
+		})
+	}
+
+	r.probeVirtualNode(status.VirtualNodeName, setCondition)
+
+	if !probe {
+		return
+	}
+	contacted := r.probe(ctx, t, buildErr, setCondition)
+
+	now := time.Now()
+	if contacted && (!equality.Semantic.DeepEqual(previousConditions, status.Conditions) ||
+		status.LastContactTime == nil || now.Sub(status.LastContactTime.Time) >= LastContactTimeRefreshPeriod) {
+		status.LastContactTime = &metav1.Time{Time: now}
+	}
+}
+
+// probe sets the KubeconfigValid, Reachable and Authorized conditions, and returns whether the target cluster was contacted
+func (r *statusReconciler) probe(ctx context.Context, t agent.Target, buildErr error, setCondition conditionSetter) bool {
+	if buildErr != nil {
+		setCondition(multiclusterv1alpha1.TargetConditionKubeconfigValid, metav1.ConditionFalse, "Invalid", buildErr.Error())
+		setCondition(multiclusterv1alpha1.TargetConditionReachable, metav1.ConditionUnknown, "KubeconfigInvalid", "")
+		setCondition(multiclusterv1alpha1.TargetConditionAuthorized, metav1.ConditionUnknown, "KubeconfigInvalid", "")
+		return false
+	}
+	setCondition(multiclusterv1alpha1.TargetConditionKubeconfigValid, metav1.ConditionTrue, "Valid", "")
+
+	cfg := rest.CopyConfig(t.ClientConfig)
+	cfg.Timeout = StatusProbeTimeout
+
+	k, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		setCondition(multiclusterv1alpha1.TargetConditionKubeconfigValid, metav1.ConditionFalse, "Invalid", err.Error())
+		return false
+	}
+	customClient, err := versioned.NewForConfig(cfg)
+	if err != nil {
+		setCondition(multiclusterv1alpha1.TargetConditionKubeconfigValid, metav1.ConditionFalse, "Invalid", err.Error())
+		return false
+	}
+
+	if _, err := k.Discovery().ServerVersion(); err != nil && !errors.IsUnauthorized(err) && !errors.IsForbidden(err) {
+		setCondition(multiclusterv1alpha1.TargetConditionReachable, metav1.ConditionFalse, "Unreachable", err.Error())
+		setCondition(multiclusterv1alpha1.TargetConditionAuthorized, metav1.ConditionUnknown, "Unreachable", "")
+		return false
+	}
+	setCondition(multiclusterv1alpha1.TargetConditionReachable, metav1.ConditionTrue, "Reachable", "")
+
+	ctx, cancel := context.WithTimeout(ctx, StatusProbeTimeout)
+	defer cancel()
+	if _, err := customClient.MulticlusterV1alpha1().PodChaperons(t.Namespace).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		if errors.IsUnauthorized(err) || errors.IsForbidden(err) {
+			setCondition(multiclusterv1alpha1.TargetConditionAuthorized, metav1.ConditionFalse, "Forbidden", err.Error())
+		} else {
+			setCondition(multiclusterv1alpha1.TargetConditionAuthorized, metav1.ConditionUnknown, "Error", err.Error())
+		}
+		return true
+	}
+	setCondition(multiclusterv1alpha1.TargetConditionAuthorized, metav1.ConditionTrue, "Authorized", "")
+	return true
+}
+
+func (r *statusReconciler) probeVirtualNode(virtualNodeName string, setCondition conditionSetter) {
+	n, err := r.nodeLister.Get(virtualNodeName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			setCondition(multiclusterv1alpha1.TargetConditionVirtualNodeReady, metav1.ConditionFalse, "NotFound", "")
+		} else {
+			setCondition(multiclusterv1alpha1.TargetConditionVirtualNodeReady, metav1.ConditionUnknown, "Error", err.Error())
+		}
+		return
+	}
+	for _, cond := range n.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			setCondition(multiclusterv1alpha1.TargetConditionVirtualNodeReady, metav1.ConditionStatus(cond.Status), "NodeReady"+string(cond.Status), cond.Message)
+			return
+		}
+	}
+	setCondition(multiclusterv1alpha1.TargetConditionVirtualNodeReady, metav1.ConditionUnknown, "NodeReadyUnknown", "")
+}
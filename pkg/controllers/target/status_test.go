@@ -0,0 +1,173 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
+	customfake "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/fake"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+)
+
+const insecureKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: c
+  cluster:
+    server: %s
+contexts:
+- name: c
+  context:
+    cluster: c
+    user: u
+current-context: c
+users:
+- name: u
+  user:
+    token: t
+`
+
+func TestStatus(t *testing.T) {
+	var probes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch req.URL.Path {
+		case "/version":
+			fmt.Fprint(w, `{"major":"1","minor":"30","gitVersion":"v1.30.0"}`)
+		case "/apis/multicluster.admiralty.io/v1alpha1/namespaces/ns/podchaperons":
+			fmt.Fprint(w, `{"kind":"PodChaperonList","apiVersion":"multicluster.admiralty.io/v1alpha1","metadata":{},"items":[]}`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	tg := &v1alpha1.Target{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a"},
+		Spec:       v1alpha1.TargetSpec{KubeconfigSecret: &v1alpha1.KubeconfigSecret{Name: "a"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a"},
+		Data:       map[string][]byte{"config": []byte(fmt.Sprintf(insecureKubeconfig, server.URL))},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: agent.VirtualNodeName("ns", "a")},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+	}
+
+	customClient := customfake.NewSimpleClientset(tg)
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	customInformerFactory := informers.NewSharedInformerFactory(customClient, 0)
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
+	targetInformer := customInformerFactory.Multicluster().V1alpha1().Targets()
+	clusterTargetInformer := customInformerFactory.Multicluster().V1alpha1().ClusterTargets()
+	NewStatusController(customClient, clusterTargetInformer, targetInformer, secretInformer, nodeInformer)
+
+	r := &statusReconciler{
+		customClient:        customClient,
+		clusterTargetLister: clusterTargetInformer.Lister(),
+		targetLister:        targetInformer.Lister(),
+		secretLister:        secretInformer.Lister(),
+		nodeLister:          nodeInformer.Lister(),
+	}
+
+	targetStore := targetInformer.Informer().GetStore()
+	nodeStore := nodeInformer.Informer().GetStore()
+	require.NoError(t, targetStore.Add(tg))
+	require.NoError(t, secretInformer.Informer().GetStore().Add(secret))
+	require.NoError(t, nodeStore.Add(node))
+
+	// handle runs the reconciler and returns the updated target, if any, and the number of requests to the target cluster
+	handle := func(key interface{}, wantRequeue bool) (*v1alpha1.Target, int32) {
+		customClient.ClearActions()
+		atomic.StoreInt32(&probes, 0)
+		requeueAfter, err := r.Handle(key)
+		require.NoError(t, err)
+		require.Equal(t, wantRequeue, requeueAfter != nil)
+
+		var updated *v1alpha1.Target
+		for _, a := range customClient.Actions() {
+			if a.GetVerb() == "update" && a.GetSubresource() == "status" {
+				updated, err = customClient.MulticlusterV1alpha1().Targets("ns").Get(context.Background(), "a", metav1.GetOptions{})
+				require.NoError(t, err)
+				require.NoError(t, targetStore.Update(updated))
+			}
+		}
+		return updated, atomic.LoadInt32(&probes)
+	}
+	status := func(tg *v1alpha1.Target, conditionType string) metav1.ConditionStatus {
+		return meta.FindStatusCondition(tg.Status.Conditions, conditionType).Status
+	}
+
+	// first probe
+	updated, n := handle("ns/a", true)
+	require.NotNil(t, updated)
+	require.NotZero(t, n)
+	require.Equal(t, metav1.ConditionTrue, status(updated, v1alpha1.TargetConditionReachable))
+	require.Equal(t, metav1.ConditionTrue, status(updated, v1alpha1.TargetConditionAuthorized))
+	require.Equal(t, metav1.ConditionTrue, status(updated, v1alpha1.TargetConditionVirtualNodeReady))
+	require.NotNil(t, updated.Status.LastContactTime)
+	lastContactTime := updated.Status.LastContactTime
+
+	// nothing changed: probed again, but no status update
+	updated, n = handle("ns/a", true)
+	require.Nil(t, updated)
+	require.NotZero(t, n)
+
+	// virtual node changed: not probed again, and the periodic probe is already scheduled
+	nodeCopy := node.DeepCopy()
+	nodeCopy.Status.Conditions[0].Status = corev1.ConditionUnknown
+	require.NoError(t, nodeStore.Update(nodeCopy))
+	updated, n = handle(virtualNodeKey("ns/a"), false)
+	require.NotNil(t, updated)
+	require.Zero(t, n)
+	require.Equal(t, metav1.ConditionUnknown, status(updated, v1alpha1.TargetConditionVirtualNodeReady))
+	require.Equal(t, lastContactTime, updated.Status.LastContactTime)
+
+	// last contact time refreshed eventually
+	tgCopy := updated.DeepCopy()
+	tgCopy.Status.LastContactTime = &metav1.Time{Time: time.Now().Add(-LastContactTimeRefreshPeriod)}
+	require.NoError(t, targetStore.Update(tgCopy))
+	updated, _ = handle("ns/a", true)
+	require.NotNil(t, updated)
+	require.True(t, updated.Status.LastContactTime.After(tgCopy.Status.LastContactTime.Time))
+	lastContactTime = updated.Status.LastContactTime
+
+	// unreachable: last contact time kept
+	server.Close()
+	updated, _ = handle("ns/a", true)
+	require.NotNil(t, updated)
+	require.Equal(t, metav1.ConditionFalse, status(updated, v1alpha1.TargetConditionReachable))
+	require.Equal(t, lastContactTime, updated.Status.LastContactTime)
+}
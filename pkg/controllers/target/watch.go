@@ -0,0 +1,47 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target
+
+import (
+	"context"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+
+	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+)
+
+// Watch starts the informers and the target controller in the background, feeding h until ctx is done.
+// Unlike other controllers, it isn't leader-elected: every replica needs to know the current targets.
+func Watch(ctx context.Context, k kubernetes.Interface, customClient versioned.Interface, h Handler) {
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(k, time.Second*30)
+	customInformerFactory := informers.NewSharedInformerFactory(customClient, time.Second*30)
+
+	c := NewController(
+		customInformerFactory.Multicluster().V1alpha1().ClusterTargets(),
+		customInformerFactory.Multicluster().V1alpha1().Targets(),
+		kubeInformerFactory.Core().V1().Secrets(),
+		h)
+
+	kubeInformerFactory.Start(ctx.Done())
+	customInformerFactory.Start(ctx.Done())
+
+	go func() { utilruntime.Must(c.Run(ctx, 1)) }()
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	scheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ClusterMulticlusterPoliciesGetter has a method to return a ClusterMulticlusterPolicyInterface.
+// A group's client should implement this interface.
+type ClusterMulticlusterPoliciesGetter interface {
+	ClusterMulticlusterPolicies() ClusterMulticlusterPolicyInterface
+}
+
+// ClusterMulticlusterPolicyInterface has methods to work with ClusterMulticlusterPolicy resources.
+type ClusterMulticlusterPolicyInterface interface {
+	Create(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.CreateOptions) (*v1alpha1.ClusterMulticlusterPolicy, error)
+	Update(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.UpdateOptions) (*v1alpha1.ClusterMulticlusterPolicy, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.ClusterMulticlusterPolicy, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.ClusterMulticlusterPolicyList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterMulticlusterPolicy, err error)
+	ClusterMulticlusterPolicyExpansion
+}
+
+// clusterMulticlusterPolicies implements ClusterMulticlusterPolicyInterface
+type clusterMulticlusterPolicies struct {
+	client rest.Interface
+}
+
+// newClusterMulticlusterPolicies returns a ClusterMulticlusterPolicies
+func newClusterMulticlusterPolicies(c *MulticlusterV1alpha1Client) *clusterMulticlusterPolicies {
+	return &clusterMulticlusterPolicies{
+		client: c.RESTClient(),
+	}
+}
+
+// Get takes name of the clusterMulticlusterPolicy, and returns the corresponding clusterMulticlusterPolicy object, and an error if there is any.
+func (c *clusterMulticlusterPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	result = &v1alpha1.ClusterMulticlusterPolicy{}
+	err = c.client.Get().
+		Resource("clustermulticlusterpolicies").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ClusterMulticlusterPolicies that match those selectors.
+func (c *clusterMulticlusterPolicies) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.ClusterMulticlusterPolicyList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.ClusterMulticlusterPolicyList{}
+	err = c.client.Get().
+		Resource("clustermulticlusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested clusterMulticlusterPolicies.
+func (c *clusterMulticlusterPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Resource("clustermulticlusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a clusterMulticlusterPolicy and creates it.  Returns the server's representation of the clusterMulticlusterPolicy, and an error, if there is any.
+func (c *clusterMulticlusterPolicies) Create(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.CreateOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	result = &v1alpha1.ClusterMulticlusterPolicy{}
+	err = c.client.Post().
+		Resource("clustermulticlusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterMulticlusterPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a clusterMulticlusterPolicy and updates it. Returns the server's representation of the clusterMulticlusterPolicy, and an error, if there is any.
+func (c *clusterMulticlusterPolicies) Update(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.UpdateOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	result = &v1alpha1.ClusterMulticlusterPolicy{}
+	err = c.client.Put().
+		Resource("clustermulticlusterpolicies").
+		Name(clusterMulticlusterPolicy.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(clusterMulticlusterPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the clusterMulticlusterPolicy and deletes it. Returns an error if one occurs.
+func (c *clusterMulticlusterPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Resource("clustermulticlusterpolicies").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *clusterMulticlusterPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Resource("clustermulticlusterpolicies").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched clusterMulticlusterPolicy.
+func (c *clusterMulticlusterPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	result = &v1alpha1.ClusterMulticlusterPolicy{}
+	err = c.client.Patch(pt).
+		Resource("clustermulticlusterpolicies").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeClusterMulticlusterPolicies implements ClusterMulticlusterPolicyInterface
+type FakeClusterMulticlusterPolicies struct {
+	Fake *FakeMulticlusterV1alpha1
+}
+
+var clustermulticlusterpoliciesResource = v1alpha1.SchemeGroupVersion.WithResource("clustermulticlusterpolicies")
+
+var clustermulticlusterpoliciesKind = v1alpha1.SchemeGroupVersion.WithKind("ClusterMulticlusterPolicy")
+
+// Get takes name of the clusterMulticlusterPolicy, and returns the corresponding clusterMulticlusterPolicy object, and an error if there is any.
+func (c *FakeClusterMulticlusterPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootGetAction(clustermulticlusterpoliciesResource, name), &v1alpha1.ClusterMulticlusterPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterMulticlusterPolicy), err
+}
+
+// List takes label and field selectors, and returns the list of ClusterMulticlusterPolicies that match those selectors.
+func (c *FakeClusterMulticlusterPolicies) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.ClusterMulticlusterPolicyList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootListAction(clustermulticlusterpoliciesResource, clustermulticlusterpoliciesKind, opts), &v1alpha1.ClusterMulticlusterPolicyList{})
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.ClusterMulticlusterPolicyList{ListMeta: obj.(*v1alpha1.ClusterMulticlusterPolicyList).ListMeta}
+	for _, item := range obj.(*v1alpha1.ClusterMulticlusterPolicyList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested clusterMulticlusterPolicies.
+func (c *FakeClusterMulticlusterPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewRootWatchAction(clustermulticlusterpoliciesResource, opts))
+}
+
+// Create takes the representation of a clusterMulticlusterPolicy and creates it.  Returns the server's representation of the clusterMulticlusterPolicy, and an error, if there is any.
+func (c *FakeClusterMulticlusterPolicies) Create(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.CreateOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootCreateAction(clustermulticlusterpoliciesResource, clusterMulticlusterPolicy), &v1alpha1.ClusterMulticlusterPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterMulticlusterPolicy), err
+}
+
+// Update takes the representation of a clusterMulticlusterPolicy and updates it. Returns the server's representation of the clusterMulticlusterPolicy, and an error, if there is any.
+func (c *FakeClusterMulticlusterPolicies) Update(ctx context.Context, clusterMulticlusterPolicy *v1alpha1.ClusterMulticlusterPolicy, opts v1.UpdateOptions) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootUpdateAction(clustermulticlusterpoliciesResource, clusterMulticlusterPolicy), &v1alpha1.ClusterMulticlusterPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterMulticlusterPolicy), err
+}
+
+// Delete takes name of the clusterMulticlusterPolicy and deletes it. Returns an error if one occurs.
+func (c *FakeClusterMulticlusterPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewRootDeleteActionWithOptions(clustermulticlusterpoliciesResource, name, opts), &v1alpha1.ClusterMulticlusterPolicy{})
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeClusterMulticlusterPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewRootDeleteCollectionAction(clustermulticlusterpoliciesResource, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.ClusterMulticlusterPolicyList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched clusterMulticlusterPolicy.
+func (c *FakeClusterMulticlusterPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.ClusterMulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewRootPatchSubresourceAction(clustermulticlusterpoliciesResource, name, pt, data, subresources...), &v1alpha1.ClusterMulticlusterPolicy{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.ClusterMulticlusterPolicy), err
+}
@@ -28,6 +28,10 @@ type FakeMulticlusterV1alpha1 struct {
 	*testing.Fake
 }
 
+func (c *FakeMulticlusterV1alpha1) ClusterMulticlusterPolicies() v1alpha1.ClusterMulticlusterPolicyInterface {
+	return &FakeClusterMulticlusterPolicies{c}
+}
+
 func (c *FakeMulticlusterV1alpha1) ClusterSources() v1alpha1.ClusterSourceInterface {
 	return &FakeClusterSources{c}
 }
@@ -40,6 +44,10 @@ func (c *FakeMulticlusterV1alpha1) ClusterTargets() v1alpha1.ClusterTargetInterf
 	return &FakeClusterTargets{c}
 }
 
+func (c *FakeMulticlusterV1alpha1) MulticlusterPolicies(namespace string) v1alpha1.MulticlusterPolicyInterface {
+	return &FakeMulticlusterPolicies{c, namespace}
+}
+
 func (c *FakeMulticlusterV1alpha1) PodChaperons(namespace string) v1alpha1.PodChaperonInterface {
 	return &FakePodChaperons{c, namespace}
 }
@@ -0,0 +1,129 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeMulticlusterPolicies implements MulticlusterPolicyInterface
+type FakeMulticlusterPolicies struct {
+	Fake *FakeMulticlusterV1alpha1
+	ns   string
+}
+
+var multiclusterpoliciesResource = v1alpha1.SchemeGroupVersion.WithResource("multiclusterpolicies")
+
+var multiclusterpoliciesKind = v1alpha1.SchemeGroupVersion.WithKind("MulticlusterPolicy")
+
+// Get takes name of the multiclusterPolicy, and returns the corresponding multiclusterPolicy object, and an error if there is any.
+func (c *FakeMulticlusterPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(multiclusterpoliciesResource, c.ns, name), &v1alpha1.MulticlusterPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MulticlusterPolicy), err
+}
+
+// List takes label and field selectors, and returns the list of MulticlusterPolicies that match those selectors.
+func (c *FakeMulticlusterPolicies) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.MulticlusterPolicyList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(multiclusterpoliciesResource, multiclusterpoliciesKind, c.ns, opts), &v1alpha1.MulticlusterPolicyList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.MulticlusterPolicyList{ListMeta: obj.(*v1alpha1.MulticlusterPolicyList).ListMeta}
+	for _, item := range obj.(*v1alpha1.MulticlusterPolicyList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested multiclusterPolicies.
+func (c *FakeMulticlusterPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(multiclusterpoliciesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a multiclusterPolicy and creates it.  Returns the server's representation of the multiclusterPolicy, and an error, if there is any.
+func (c *FakeMulticlusterPolicies) Create(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.CreateOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(multiclusterpoliciesResource, c.ns, multiclusterPolicy), &v1alpha1.MulticlusterPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MulticlusterPolicy), err
+}
+
+// Update takes the representation of a multiclusterPolicy and updates it. Returns the server's representation of the multiclusterPolicy, and an error, if there is any.
+func (c *FakeMulticlusterPolicies) Update(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.UpdateOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(multiclusterpoliciesResource, c.ns, multiclusterPolicy), &v1alpha1.MulticlusterPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MulticlusterPolicy), err
+}
+
+// Delete takes name of the multiclusterPolicy and deletes it. Returns an error if one occurs.
+func (c *FakeMulticlusterPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(multiclusterpoliciesResource, c.ns, name, opts), &v1alpha1.MulticlusterPolicy{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeMulticlusterPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(multiclusterpoliciesResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.MulticlusterPolicyList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched multiclusterPolicy.
+func (c *FakeMulticlusterPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.MulticlusterPolicy, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(multiclusterpoliciesResource, c.ns, name, pt, data, subresources...), &v1alpha1.MulticlusterPolicy{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MulticlusterPolicy), err
+}
@@ -18,12 +18,16 @@
 
 package v1alpha1
 
+type ClusterMulticlusterPolicyExpansion interface{}
+
 type ClusterSourceExpansion interface{}
 
 type ClusterSummaryExpansion interface{}
 
 type ClusterTargetExpansion interface{}
 
+type MulticlusterPolicyExpansion interface{}
+
 type PodChaperonExpansion interface{}
 
 type SourceExpansion interface{}
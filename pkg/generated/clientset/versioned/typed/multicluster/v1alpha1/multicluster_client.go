@@ -28,9 +28,11 @@ import (
 
 type MulticlusterV1alpha1Interface interface {
 	RESTClient() rest.Interface
+	ClusterMulticlusterPoliciesGetter
 	ClusterSourcesGetter
 	ClusterSummariesGetter
 	ClusterTargetsGetter
+	MulticlusterPoliciesGetter
 	PodChaperonsGetter
 	SourcesGetter
 	TargetsGetter
@@ -41,6 +43,10 @@ type MulticlusterV1alpha1Client struct {
 	restClient rest.Interface
 }
 
+func (c *MulticlusterV1alpha1Client) ClusterMulticlusterPolicies() ClusterMulticlusterPolicyInterface {
+	return newClusterMulticlusterPolicies(c)
+}
+
 func (c *MulticlusterV1alpha1Client) ClusterSources() ClusterSourceInterface {
 	return newClusterSources(c)
 }
@@ -53,6 +59,10 @@ func (c *MulticlusterV1alpha1Client) ClusterTargets() ClusterTargetInterface {
 	return newClusterTargets(c)
 }
 
+func (c *MulticlusterV1alpha1Client) MulticlusterPolicies(namespace string) MulticlusterPolicyInterface {
+	return newMulticlusterPolicies(c, namespace)
+}
+
 func (c *MulticlusterV1alpha1Client) PodChaperons(namespace string) PodChaperonInterface {
 	return newPodChaperons(c, namespace)
 }
@@ -0,0 +1,178 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	scheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// MulticlusterPoliciesGetter has a method to return a MulticlusterPolicyInterface.
+// A group's client should implement this interface.
+type MulticlusterPoliciesGetter interface {
+	MulticlusterPolicies(namespace string) MulticlusterPolicyInterface
+}
+
+// MulticlusterPolicyInterface has methods to work with MulticlusterPolicy resources.
+type MulticlusterPolicyInterface interface {
+	Create(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.CreateOptions) (*v1alpha1.MulticlusterPolicy, error)
+	Update(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.UpdateOptions) (*v1alpha1.MulticlusterPolicy, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.MulticlusterPolicy, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.MulticlusterPolicyList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.MulticlusterPolicy, err error)
+	MulticlusterPolicyExpansion
+}
+
+// multiclusterPolicies implements MulticlusterPolicyInterface
+type multiclusterPolicies struct {
+	client rest.Interface
+	ns     string
+}
+
+// newMulticlusterPolicies returns a MulticlusterPolicies
+func newMulticlusterPolicies(c *MulticlusterV1alpha1Client, namespace string) *multiclusterPolicies {
+	return &multiclusterPolicies{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the multiclusterPolicy, and returns the corresponding multiclusterPolicy object, and an error if there is any.
+func (c *multiclusterPolicies) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	result = &v1alpha1.MulticlusterPolicy{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of MulticlusterPolicies that match those selectors.
+func (c *multiclusterPolicies) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.MulticlusterPolicyList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.MulticlusterPolicyList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested multiclusterPolicies.
+func (c *multiclusterPolicies) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a multiclusterPolicy and creates it.  Returns the server's representation of the multiclusterPolicy, and an error, if there is any.
+func (c *multiclusterPolicies) Create(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.CreateOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	result = &v1alpha1.MulticlusterPolicy{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(multiclusterPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a multiclusterPolicy and updates it. Returns the server's representation of the multiclusterPolicy, and an error, if there is any.
+func (c *multiclusterPolicies) Update(ctx context.Context, multiclusterPolicy *v1alpha1.MulticlusterPolicy, opts v1.UpdateOptions) (result *v1alpha1.MulticlusterPolicy, err error) {
+	result = &v1alpha1.MulticlusterPolicy{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		Name(multiclusterPolicy.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(multiclusterPolicy).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the multiclusterPolicy and deletes it. Returns an error if one occurs.
+func (c *multiclusterPolicies) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *multiclusterPolicies) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched multiclusterPolicy.
+func (c *multiclusterPolicies) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.MulticlusterPolicy, err error) {
+	result = &v1alpha1.MulticlusterPolicy{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("multiclusterpolicies").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -53,12 +53,16 @@ func (f *genericInformer) Lister() cache.GenericLister {
 func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource) (GenericInformer, error) {
 	switch resource {
 	// Group=multicluster.admiralty.io, Version=v1alpha1
+	case v1alpha1.SchemeGroupVersion.WithResource("clustermulticlusterpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().ClusterMulticlusterPolicies().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("clustersources"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().ClusterSources().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("clustersummaries"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().ClusterSummaries().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("clustertargets"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().ClusterTargets().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("multiclusterpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().MulticlusterPolicies().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("podchaperons"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Multicluster().V1alpha1().PodChaperons().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("sources"):
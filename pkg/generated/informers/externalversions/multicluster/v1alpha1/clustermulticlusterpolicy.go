@@ -0,0 +1,89 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	multiclusterv1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	versioned "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	internalinterfaces "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions/internalinterfaces"
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ClusterMulticlusterPolicyInformer provides access to a shared informer and lister for
+// ClusterMulticlusterPolicies.
+type ClusterMulticlusterPolicyInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.ClusterMulticlusterPolicyLister
+}
+
+type clusterMulticlusterPolicyInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewClusterMulticlusterPolicyInformer constructs a new informer for ClusterMulticlusterPolicy type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewClusterMulticlusterPolicyInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredClusterMulticlusterPolicyInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredClusterMulticlusterPolicyInformer constructs a new informer for ClusterMulticlusterPolicy type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredClusterMulticlusterPolicyInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MulticlusterV1alpha1().ClusterMulticlusterPolicies().List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MulticlusterV1alpha1().ClusterMulticlusterPolicies().Watch(context.TODO(), options)
+			},
+		},
+		&multiclusterv1alpha1.ClusterMulticlusterPolicy{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *clusterMulticlusterPolicyInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredClusterMulticlusterPolicyInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *clusterMulticlusterPolicyInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&multiclusterv1alpha1.ClusterMulticlusterPolicy{}, f.defaultInformer)
+}
+
+func (f *clusterMulticlusterPolicyInformer) Lister() v1alpha1.ClusterMulticlusterPolicyLister {
+	return v1alpha1.NewClusterMulticlusterPolicyLister(f.Informer().GetIndexer())
+}
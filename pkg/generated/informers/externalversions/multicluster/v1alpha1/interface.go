@@ -24,12 +24,16 @@ import (
 
 // Interface provides access to all the informers in this group version.
 type Interface interface {
+	// ClusterMulticlusterPolicies returns a ClusterMulticlusterPolicyInformer.
+	ClusterMulticlusterPolicies() ClusterMulticlusterPolicyInformer
 	// ClusterSources returns a ClusterSourceInformer.
 	ClusterSources() ClusterSourceInformer
 	// ClusterSummaries returns a ClusterSummaryInformer.
 	ClusterSummaries() ClusterSummaryInformer
 	// ClusterTargets returns a ClusterTargetInformer.
 	ClusterTargets() ClusterTargetInformer
+	// MulticlusterPolicies returns a MulticlusterPolicyInformer.
+	MulticlusterPolicies() MulticlusterPolicyInformer
 	// PodChaperons returns a PodChaperonInformer.
 	PodChaperons() PodChaperonInformer
 	// Sources returns a SourceInformer.
@@ -49,6 +53,11 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
 }
 
+// ClusterMulticlusterPolicies returns a ClusterMulticlusterPolicyInformer.
+func (v *version) ClusterMulticlusterPolicies() ClusterMulticlusterPolicyInformer {
+	return &clusterMulticlusterPolicyInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // ClusterSources returns a ClusterSourceInformer.
 func (v *version) ClusterSources() ClusterSourceInformer {
 	return &clusterSourceInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
@@ -64,6 +73,11 @@ func (v *version) ClusterTargets() ClusterTargetInformer {
 	return &clusterTargetInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// MulticlusterPolicies returns a MulticlusterPolicyInformer.
+func (v *version) MulticlusterPolicies() MulticlusterPolicyInformer {
+	return &multiclusterPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // PodChaperons returns a PodChaperonInformer.
 func (v *version) PodChaperons() PodChaperonInformer {
 	return &podChaperonInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
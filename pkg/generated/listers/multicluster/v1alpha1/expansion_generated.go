@@ -18,6 +18,10 @@
 
 package v1alpha1
 
+// ClusterMulticlusterPolicyListerExpansion allows custom methods to be added to
+// ClusterMulticlusterPolicyLister.
+type ClusterMulticlusterPolicyListerExpansion interface{}
+
 // ClusterSourceListerExpansion allows custom methods to be added to
 // ClusterSourceLister.
 type ClusterSourceListerExpansion interface{}
@@ -30,6 +34,14 @@ type ClusterSummaryListerExpansion interface{}
 // ClusterTargetLister.
 type ClusterTargetListerExpansion interface{}
 
+// MulticlusterPolicyListerExpansion allows custom methods to be added to
+// MulticlusterPolicyLister.
+type MulticlusterPolicyListerExpansion interface{}
+
+// MulticlusterPolicyNamespaceListerExpansion allows custom methods to be added to
+// MulticlusterPolicyNamespaceLister.
+type MulticlusterPolicyNamespaceListerExpansion interface{}
+
 // PodChaperonListerExpansion allows custom methods to be added to
 // PodChaperonLister.
 type PodChaperonListerExpansion interface{}
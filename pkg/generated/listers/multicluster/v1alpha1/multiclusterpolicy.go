@@ -0,0 +1,99 @@
+/*
+ * Copyright The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// MulticlusterPolicyLister helps list MulticlusterPolicies.
+// All objects returned here must be treated as read-only.
+type MulticlusterPolicyLister interface {
+	// List lists all MulticlusterPolicies in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.MulticlusterPolicy, err error)
+	// MulticlusterPolicies returns an object that can list and get MulticlusterPolicies.
+	MulticlusterPolicies(namespace string) MulticlusterPolicyNamespaceLister
+	MulticlusterPolicyListerExpansion
+}
+
+// multiclusterPolicyLister implements the MulticlusterPolicyLister interface.
+type multiclusterPolicyLister struct {
+	indexer cache.Indexer
+}
+
+// NewMulticlusterPolicyLister returns a new MulticlusterPolicyLister.
+func NewMulticlusterPolicyLister(indexer cache.Indexer) MulticlusterPolicyLister {
+	return &multiclusterPolicyLister{indexer: indexer}
+}
+
+// List lists all MulticlusterPolicies in the indexer.
+func (s *multiclusterPolicyLister) List(selector labels.Selector) (ret []*v1alpha1.MulticlusterPolicy, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.MulticlusterPolicy))
+	})
+	return ret, err
+}
+
+// MulticlusterPolicies returns an object that can list and get MulticlusterPolicies.
+func (s *multiclusterPolicyLister) MulticlusterPolicies(namespace string) MulticlusterPolicyNamespaceLister {
+	return multiclusterPolicyNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// MulticlusterPolicyNamespaceLister helps list and get MulticlusterPolicies.
+// All objects returned here must be treated as read-only.
+type MulticlusterPolicyNamespaceLister interface {
+	// List lists all MulticlusterPolicies in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.MulticlusterPolicy, err error)
+	// Get retrieves the MulticlusterPolicy from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.MulticlusterPolicy, error)
+	MulticlusterPolicyNamespaceListerExpansion
+}
+
+// multiclusterPolicyNamespaceLister implements the MulticlusterPolicyNamespaceLister
+// interface.
+type multiclusterPolicyNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all MulticlusterPolicies in the indexer for a given namespace.
+func (s multiclusterPolicyNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.MulticlusterPolicy, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.MulticlusterPolicy))
+	})
+	return ret, err
+}
+
+// Get retrieves the MulticlusterPolicy from the indexer for a given namespace and name.
+func (s multiclusterPolicyNamespaceLister) Get(name string) (*v1alpha1.MulticlusterPolicy, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("multiclusterpolicy"), name)
+	}
+	return obj.(*v1alpha1.MulticlusterPolicy), nil
+}
@@ -73,13 +73,16 @@ func MakeDelegatePod(proxyPod *corev1.Pod, clusterName string) (*v1alpha1.PodCha
 
 	// At this stage, we remove incompatible fields rather than keep known compatible ones only,
 	// so we can discover current and future incompatibilities as we encounter them.
-	removeServiceAccount(&delegatePod.Spec)
+	// The service account name is kept, so the target cluster mints a token for the eponymous service account
+	// (created by the service account follow controller).
+	removeServiceAccountToken(&delegatePod.Spec)
 
 	if _, ok := srcPod.Annotations[common.AnnotationKeyNoReservation]; !ok {
 		delegatePod.Spec.SchedulerName = common.CandidateSchedulerName
 	}
 
-	// support different default priority in target cluster
+	// the target cluster resolves the priority from the priority class name
+	// (mapped by the proxy scheduler if the target says so), or its own default
 	delegatePod.Spec.Priority = nil
 
 	return delegatePod, nil
@@ -119,37 +122,23 @@ func ChangeLabels(labels map[string]string, noPrefixLabelRegex string) (map[stri
 	return newLabels, changed, nil
 }
 
-func removeServiceAccount(podSpec *corev1.PodSpec) {
+// removeServiceAccountToken removes the volume of the source cluster's service account token, and its mounts,
+// so the target cluster mounts its own
+func removeServiceAccountToken(podSpec *corev1.PodSpec) {
 	var saSecretName string
 	for i, c := range podSpec.Containers {
-		j := -1
-		for i, m := range c.VolumeMounts {
-			if m.MountPath == "/var/run/secrets/kubernetes.io/serviceaccount" {
-				saSecretName = m.Name // should be the same secret name for all containers
-				j = i
-				break
-			}
-		}
-		if j > -1 {
-			c.VolumeMounts = append(c.VolumeMounts[:j], c.VolumeMounts[j+1:]...)
-			podSpec.Containers[i] = c
+		if name, mounts, ok := removeServiceAccountMount(c.VolumeMounts); ok {
+			saSecretName = name // should be the same secret name for all containers
+			podSpec.Containers[i].VolumeMounts = mounts
 		}
 	}
 	for i, c := range podSpec.InitContainers {
-		j := -1
-		for i, m := range c.VolumeMounts {
-			if m.MountPath == "/var/run/secrets/kubernetes.io/serviceaccount" {
-				saSecretName = m.Name // should be the same secret name for all containers
-				j = i
-				break
-			}
-		}
-		if j > -1 {
-			c.VolumeMounts = append(c.VolumeMounts[:j], c.VolumeMounts[j+1:]...)
-			podSpec.InitContainers[i] = c
+		if name, mounts, ok := removeServiceAccountMount(c.VolumeMounts); ok {
+			saSecretName = name
+			podSpec.InitContainers[i].VolumeMounts = mounts
 		}
 	}
-	// TODO... what about ephemeral containers
+	removeEphemeralContainersServiceAccountMounts(podSpec.EphemeralContainers)
 	j := -1
 	for i, v := range podSpec.Volumes {
 		if v.Name == saSecretName {
@@ -161,3 +150,22 @@ func removeServiceAccount(podSpec *corev1.PodSpec) {
 		podSpec.Volumes = append(podSpec.Volumes[:j], podSpec.Volumes[j+1:]...)
 	}
 }
+
+// removeEphemeralContainersServiceAccountMounts removes the mounts of the source service account's volume,
+// which is removed from delegate pods, so target clusters mount their own
+func removeEphemeralContainersServiceAccountMounts(containers []corev1.EphemeralContainer) {
+	for i, c := range containers {
+		if _, mounts, ok := removeServiceAccountMount(c.VolumeMounts); ok {
+			containers[i].VolumeMounts = mounts
+		}
+	}
+}
+
+func removeServiceAccountMount(mounts []corev1.VolumeMount) (string, []corev1.VolumeMount, bool) {
+	for i, m := range mounts {
+		if m.MountPath == "/var/run/secrets/kubernetes.io/serviceaccount" {
+			return m.Name, append(mounts[:i:i], mounts[i+1:]...), true
+		}
+	}
+	return "", mounts, false
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 )
 
 func TestChangeLabels(t *testing.T) {
@@ -107,3 +108,17 @@ func TestChangeLabels(t *testing.T) {
 		})
 	}
 }
+
+func TestPreemptionCost(t *testing.T) {
+	pod := func(priority int32) *corev1.Pod {
+		return &corev1.Pod{Spec: corev1.PodSpec{Priority: &priority}}
+	}
+
+	c := NewPreemptionCost("node", []*corev1.Pod{pod(-10), pod(-20)}, 0)
+	require.Equal(t, PreemptionCost{NodeName: "node", NumVictims: 2, HighestPriority: -10, SumPriorities: -30}, c)
+
+	require.True(t, PreemptionCost{NumPDBViolations: 0, HighestPriority: 100}.Less(PreemptionCost{NumPDBViolations: 1}))
+	require.True(t, PreemptionCost{HighestPriority: 1, SumPriorities: 10}.Less(PreemptionCost{HighestPriority: 2}))
+	require.True(t, PreemptionCost{HighestPriority: 1, SumPriorities: 1, NumVictims: 10}.Less(PreemptionCost{HighestPriority: 1, SumPriorities: 2}))
+	require.False(t, PreemptionCost{NumVictims: 2}.Less(PreemptionCost{NumVictims: 1}))
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package delegatepod
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// PreemptionCost summarizes the victims a candidate pod would preempt in its target cluster,
+// reported by the candidate scheduler so the proxy scheduler can pick the cheapest target.
+type PreemptionCost struct {
+	NodeName         string `json:"nodeName"`
+	NumVictims       int    `json:"numVictims"`
+	NumPDBViolations int64  `json:"numPDBViolations"`
+	HighestPriority  int32  `json:"highestPriority"`
+	SumPriorities    int64  `json:"sumPriorities"`
+}
+
+func NewPreemptionCost(nodeName string, victims []*corev1.Pod, numPDBViolations int64) PreemptionCost {
+	c := PreemptionCost{NodeName: nodeName, NumVictims: len(victims), NumPDBViolations: numPDBViolations}
+	for i, v := range victims {
+		var p int32
+		if v.Spec.Priority != nil {
+			p = *v.Spec.Priority
+		}
+		if i == 0 || p > c.HighestPriority {
+			c.HighestPriority = p
+		}
+		c.SumPriorities += int64(p)
+	}
+	return c
+}
+
+// Less compares costs like the default preemption plugin compares nodes:
+// fewer PDB violations first, then lower highest priority, then lower sum of priorities, then fewer victims.
+func (c PreemptionCost) Less(o PreemptionCost) bool {
+	if c.NumPDBViolations != o.NumPDBViolations {
+		return c.NumPDBViolations < o.NumPDBViolations
+	}
+	if c.HighestPriority != o.HighestPriority {
+		return c.HighestPriority < o.HighestPriority
+	}
+	if c.SumPriorities != o.SumPriorities {
+		return c.SumPriorities < o.SumPriorities
+	}
+	return c.NumVictims < o.NumVictims
+}
+
+// GetPreemptionCost returns nil if the candidate has no preemption cost annotation
+func GetPreemptionCost(c *v1alpha1.PodChaperon) (*PreemptionCost, error) {
+	s, ok := c.Annotations[common.AnnotationKeyPreemptionCost]
+	if !ok {
+		return nil, nil
+	}
+	cost := &PreemptionCost{}
+	if err := json.Unmarshal([]byte(s), cost); err != nil {
+		return nil, fmt.Errorf("invalid preemption cost on pod chaperon %s/%s: %v", c.Namespace, c.Name, err)
+	}
+	return cost, nil
+}
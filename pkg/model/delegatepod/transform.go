@@ -0,0 +1,342 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package delegatepod
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	corev1 "k8s.io/api/core/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// Transformer transforms the delegate pod chaperons of a target, given their proxy pods.
+// Custom transformers can be compiled into the agent and referenced by name in targets' delegate pod transformations
+// (see RegisterTransformer).
+type Transformer interface {
+	Transform(delegatePod *v1alpha1.PodChaperon, proxyPod *corev1.Pod) error
+}
+
+// TransformerFunc adapts a function to the Transformer interface.
+type TransformerFunc func(delegatePod *v1alpha1.PodChaperon, proxyPod *corev1.Pod) error
+
+func (f TransformerFunc) Transform(delegatePod *v1alpha1.PodChaperon, proxyPod *corev1.Pod) error {
+	return f(delegatePod, proxyPod)
+}
+
+var (
+	customTransformersMx sync.RWMutex
+	customTransformers   = map[string]Transformer{}
+)
+
+// RegisterTransformer makes a custom transformer available to targets' delegate pod transformations, under a name.
+// It is meant to be called from the init function of a package imported by the main packages of both the agent
+// (which validates targets and transforms delegate pod spec updates) and the proxy scheduler (which transforms candidates).
+// It panics if the name is already registered.
+func RegisterTransformer(name string, t Transformer) {
+	customTransformersMx.Lock()
+	defer customTransformersMx.Unlock()
+	if _, ok := customTransformers[name]; ok {
+		panic(fmt.Sprintf("delegate pod transformer %s already registered", name))
+	}
+	customTransformers[name] = t
+}
+
+func getCustomTransformer(name string) (Transformer, bool) {
+	customTransformersMx.RLock()
+	defer customTransformersMx.RUnlock()
+	t, ok := customTransformers[name]
+	return t, ok
+}
+
+// Pipeline is a target's compiled delegate pod transformations, applied in order. The nil pipeline doesn't transform anything.
+type Pipeline []Transformer
+
+var _ Transformer = Pipeline(nil)
+
+// NewPipeline compiles a target's delegate pod transformations. It fails if a transformation doesn't have exactly one field set,
+// a JSON patch, JSON pointer or regexp is invalid, a JSON patch or field mapping would modify protocol metadata
+// (see touchesProtocolMetadata), or a custom transformer isn't registered.
+func NewPipeline(transformations []v1alpha1.DelegatePodTransformation) (Pipeline, error) {
+	var p Pipeline
+	for i, t := range transformations {
+		s, err := NewTransformer(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid delegate pod transformation %d: %v", i, err)
+		}
+		p = append(p, s)
+	}
+	return p, nil
+}
+
+func (p Pipeline) Transform(delegatePod *v1alpha1.PodChaperon, proxyPod *corev1.Pod) error {
+	for i, t := range p {
+		if err := t.Transform(delegatePod, proxyPod); err != nil {
+			return fmt.Errorf("delegate pod transformation %d failed: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// NewTransformer compiles a delegate pod transformation (see NewPipeline).
+func NewTransformer(t v1alpha1.DelegatePodTransformation) (Transformer, error) {
+	n := 0
+	var s Transformer
+	if t.JSONPatch != nil {
+		n++
+		patch, err := json.Marshal(t.JSONPatch)
+		if err != nil {
+			return nil, err
+		}
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		for _, op := range ops {
+			// DecodePatch doesn't validate operations, only Apply does
+			switch op.Kind() {
+			case "add", "remove", "replace", "move", "copy", "test":
+			default:
+				return nil, fmt.Errorf("unsupported JSON patch operation %q", op.Kind())
+			}
+			if op.Kind() == "test" {
+				continue
+			}
+			path, err := op.Path()
+			if err != nil {
+				return nil, err
+			}
+			pointers := []string{path}
+			if op.Kind() == "move" {
+				from, err := op.From()
+				if err != nil {
+					return nil, err
+				}
+				pointers = append(pointers, from)
+			}
+			for _, pointer := range pointers {
+				tokens, err := parsePointer(pointer)
+				if err != nil {
+					return nil, err
+				}
+				if touchesProtocolMetadata(tokens) {
+					return nil, fmt.Errorf("JSON patch operation %s at %q would modify protocol metadata", op.Kind(), pointer)
+				}
+			}
+		}
+		s = jsonPatchStep(ops)
+	}
+	if m := t.FieldMapping; m != nil {
+		n++
+		tokens, err := parsePointer(m.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("cannot map the whole document")
+		}
+		if touchesProtocolMetadata(tokens) {
+			return nil, fmt.Errorf("field mapping at %q would modify protocol metadata", m.Path)
+		}
+		s = fieldMappingStep{path: m.Path, tokens: tokens, values: m.Values}
+	}
+	if r := t.ImageRewrite; r != nil {
+		n++
+		re, err := regexp.Compile(r.Regexp)
+		if err != nil {
+			return nil, err
+		}
+		s = imageRewriteStep{re: re, replacement: r.Replacement}
+	}
+	if t.Custom != "" {
+		n++
+		c, ok := getCustomTransformer(t.Custom)
+		if !ok {
+			return nil, fmt.Errorf("custom transformer %s not registered", t.Custom)
+		}
+		s = c
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("exactly one of jsonPatch, fieldMapping, imageRewrite and custom must be set")
+	}
+	return s, nil
+}
+
+var applyOptions = func() *jsonpatch.ApplyOptions {
+	o := jsonpatch.NewApplyOptions()
+	o.EnsurePathExistsOnAdd = true
+	o.AllowMissingPathOnRemove = true
+	return o
+}()
+
+type jsonPatchStep jsonpatch.Patch
+
+func (s jsonPatchStep) Transform(delegatePod *v1alpha1.PodChaperon, _ *corev1.Pod) error {
+	return patchPodChaperon(delegatePod, jsonpatch.Patch(s))
+}
+
+// patchPodChaperon applies a JSON patch to a pod chaperon in place
+func patchPodChaperon(c *v1alpha1.PodChaperon, patch jsonpatch.Patch) error {
+	doc, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	doc, err = patch.ApplyWithOptions(doc, applyOptions)
+	if err != nil {
+		return err
+	}
+	patched := &v1alpha1.PodChaperon{}
+	if err := json.Unmarshal(doc, patched); err != nil {
+		return err
+	}
+	*c = *patched
+	return nil
+}
+
+type fieldMappingStep struct {
+	path   string
+	tokens []string
+	values map[string]string
+}
+
+func (s fieldMappingStep) Transform(delegatePod *v1alpha1.PodChaperon, _ *corev1.Pod) error {
+	b, err := json.Marshal(delegatePod)
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+
+	current := ""
+	v, found := lookup(doc, s.tokens)
+	if found {
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s is not a string", s.path)
+		}
+		current = str
+	}
+	mapped, ok := s.values[current]
+	if !ok || (found && mapped == current) {
+		return nil
+	}
+
+	var op map[string]interface{}
+	if mapped == "" {
+		if !found {
+			return nil
+		}
+		op = map[string]interface{}{"op": "remove", "path": s.path}
+	} else {
+		op = map[string]interface{}{"op": "add", "path": s.path, "value": mapped}
+	}
+	patch, err := json.Marshal([]interface{}{op})
+	if err != nil {
+		return err
+	}
+	ops, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return err
+	}
+	return patchPodChaperon(delegatePod, ops)
+}
+
+// parsePointer splits a JSON pointer (RFC 6901) into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// touchesProtocolMetadata returns true if modifying the value that reference tokens point to could modify
+// labels or annotations prefixed with common.KeyPrefix (e.g., the parent UID label, the reservation annotations),
+// which the schedulers and the agent's controllers rely on, i.e., the whole document, metadata, labels or annotations,
+// or a prefixed key.
+func touchesProtocolMetadata(tokens []string) bool {
+	if len(tokens) == 0 {
+		return true
+	}
+	if tokens[0] != "metadata" {
+		return false
+	}
+	if len(tokens) == 1 {
+		return true
+	}
+	if tokens[1] != "labels" && tokens[1] != "annotations" {
+		return false
+	}
+	return len(tokens) == 2 || strings.HasPrefix(tokens[2], common.KeyPrefix)
+}
+
+// lookup returns the value that reference tokens point to in a decoded JSON document, if any
+func lookup(doc interface{}, tokens []string) (interface{}, bool) {
+	v := doc
+	for _, t := range tokens {
+		switch typed := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = typed[t]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(typed) {
+				return nil, false
+			}
+			v = typed[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+type imageRewriteStep struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+func (s imageRewriteStep) Transform(delegatePod *v1alpha1.PodChaperon, _ *corev1.Pod) error {
+	spec := &delegatePod.Spec
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Image = s.re.ReplaceAllString(spec.InitContainers[i].Image, s.replacement)
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].Image = s.re.ReplaceAllString(spec.Containers[i].Image, s.replacement)
+	}
+	for i := range spec.EphemeralContainers {
+		spec.EphemeralContainers[i].Image = s.re.ReplaceAllString(spec.EphemeralContainers[i].Image, s.replacement)
+	}
+	return nil
+}
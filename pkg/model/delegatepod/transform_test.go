@@ -0,0 +1,114 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package delegatepod
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// registerTestTransformer registers a custom transformer for the duration of a test, so tests can run several times in a process
+func registerTestTransformer(t *testing.T, name string, transformer Transformer) {
+	RegisterTransformer(name, transformer)
+	t.Cleanup(func() {
+		customTransformersMx.Lock()
+		defer customTransformersMx.Unlock()
+		delete(customTransformers, name)
+	})
+}
+
+func TestPipeline(t *testing.T) {
+	registerTestTransformer(t, "test-fargate", TransformerFunc(func(delegatePod *v1alpha1.PodChaperon, proxyPod *corev1.Pod) error {
+		delegatePod.Labels["eks.amazonaws.com/fargate-profile"] = proxyPod.Namespace
+		return nil
+	}))
+
+	p, err := NewPipeline([]v1alpha1.DelegatePodTransformation{
+		{ImageRewrite: &v1alpha1.ImageRewrite{Regexp: `^docker\.io/(.*)$`, Replacement: "mirror.example.com/$1"}},
+		{FieldMapping: &v1alpha1.FieldMapping{Path: "/spec/priorityClassName", Values: map[string]string{"high": "target-high", "low": ""}}},
+		{FieldMapping: &v1alpha1.FieldMapping{Path: "/spec/runtimeClassName", Values: map[string]string{"": "gvisor"}}},
+		{JSONPatch: []v1alpha1.JSONPatchOperation{
+			{Op: "add", Path: "/spec/tolerations/-", Value: &apiextensionsv1.JSON{Raw: []byte(`{"key":"virtual-kubelet.io/provider","operator":"Exists"}`)}},
+			{Op: "add", Path: "/metadata/annotations/team", Value: &apiextensionsv1.JSON{Raw: []byte(`"a"`)}},
+			{Op: "remove", Path: "/metadata/labels/missing"},
+		}},
+		{Custom: "test-fargate"},
+	})
+	require.NoError(t, err)
+
+	proxyPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}
+	c := &v1alpha1.PodChaperon{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "a"}},
+		Spec: corev1.PodSpec{
+			PriorityClassName: "high",
+			InitContainers:    []corev1.Container{{Name: "init", Image: "docker.io/busybox"}},
+			Containers:        []corev1.Container{{Name: "a", Image: "docker.io/library/nginx"}, {Name: "b", Image: "gcr.io/b"}},
+		},
+	}
+	require.NoError(t, p.Transform(c, proxyPod))
+	require.Equal(t, "mirror.example.com/busybox", c.Spec.InitContainers[0].Image)
+	require.Equal(t, "mirror.example.com/library/nginx", c.Spec.Containers[0].Image)
+	require.Equal(t, "gcr.io/b", c.Spec.Containers[1].Image)
+	require.Equal(t, "target-high", c.Spec.PriorityClassName)
+	require.NotNil(t, c.Spec.RuntimeClassName)
+	require.Equal(t, "gvisor", *c.Spec.RuntimeClassName)
+	require.Equal(t, []corev1.Toleration{{Key: "virtual-kubelet.io/provider", Operator: corev1.TolerationOpExists}}, c.Spec.Tolerations)
+	require.Equal(t, map[string]string{"team": "a"}, c.Annotations)
+	require.Equal(t, map[string]string{"app": "a", "eks.amazonaws.com/fargate-profile": "ns"}, c.Labels)
+
+	// mapping to the empty value removes the field
+	c.Spec.PriorityClassName = "low"
+	require.NoError(t, p.Transform(c, proxyPod))
+	require.Equal(t, "", c.Spec.PriorityClassName)
+}
+
+func TestNewTransformer(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		t    v1alpha1.DelegatePodTransformation
+	}{
+		{name: "none", t: v1alpha1.DelegatePodTransformation{}},
+		{name: "several", t: v1alpha1.DelegatePodTransformation{Custom: "foo", ImageRewrite: &v1alpha1.ImageRewrite{Regexp: "^"}}},
+		{name: "invalid regexp", t: v1alpha1.DelegatePodTransformation{ImageRewrite: &v1alpha1.ImageRewrite{Regexp: "("}}},
+		{name: "invalid pointer", t: v1alpha1.DelegatePodTransformation{FieldMapping: &v1alpha1.FieldMapping{Path: "spec"}}},
+		{name: "invalid operation", t: v1alpha1.DelegatePodTransformation{JSONPatch: []v1alpha1.JSONPatchOperation{{Op: "merge", Path: "/spec"}}}},
+		{name: "unknown custom", t: v1alpha1.DelegatePodTransformation{Custom: "unknown"}},
+		{name: "protocol label", t: v1alpha1.DelegatePodTransformation{JSONPatch: []v1alpha1.JSONPatchOperation{
+			{Op: "remove", Path: "/metadata/labels/" + strings.ReplaceAll(common.LabelKeyParentUID, "/", "~1")}}}},
+		{name: "protocol annotation moved", t: v1alpha1.DelegatePodTransformation{JSONPatch: []v1alpha1.JSONPatchOperation{
+			{Op: "move", From: "/metadata/annotations/" + strings.ReplaceAll(common.AnnotationKeyIsReserved, "/", "~1"), Path: "/metadata/annotations/foo"}}}},
+		{name: "all labels", t: v1alpha1.DelegatePodTransformation{JSONPatch: []v1alpha1.JSONPatchOperation{
+			{Op: "replace", Path: "/metadata/labels", Value: &apiextensionsv1.JSON{Raw: []byte(`{}`)}}}}},
+		{name: "metadata", t: v1alpha1.DelegatePodTransformation{JSONPatch: []v1alpha1.JSONPatchOperation{
+			{Op: "remove", Path: "/metadata"}}}},
+		{name: "mapped protocol annotation", t: v1alpha1.DelegatePodTransformation{FieldMapping: &v1alpha1.FieldMapping{
+			Path: "/metadata/annotations/" + strings.ReplaceAll(common.AnnotationKeyIsReserved, "/", "~1"), Values: map[string]string{"true": ""}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTransformer(tc.t)
+			require.Error(t, err)
+		})
+	}
+}
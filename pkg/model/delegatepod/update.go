@@ -0,0 +1,166 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package delegatepod
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/yaml"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// UpdateFromProxyPod applies the allowed updates of a proxy pod's spec to its delegate pod chaperon's spec
+// (see UpdateMutableFields and AddEphemeralContainers). The updates are transformed first, if transformer isn't nil,
+// as the delegate pod chaperon was when it was made, so, e.g., rewritten images aren't reverted.
+// It returns whether the delegate spec changed.
+func UpdateFromProxyPod(delegate *v1alpha1.PodChaperon, proxyPod *corev1.Pod, transformer Transformer) (bool, error) {
+	src := proxyPod.Spec.DeepCopy()
+	tolerations, err := addedTolerations(proxyPod)
+	if err != nil {
+		return false, err
+	}
+	src.Tolerations = tolerations
+	// e.g., added by kubectl debug
+	removeEphemeralContainersServiceAccountMounts(src.EphemeralContainers)
+
+	if transformer != nil {
+		c := &v1alpha1.PodChaperon{ObjectMeta: *delegate.ObjectMeta.DeepCopy(), Spec: *src}
+		if err := transformer.Transform(c, proxyPod); err != nil {
+			return false, err
+		}
+		src = &c.Spec
+	}
+
+	changed := UpdateMutableFields(&delegate.Spec, src)
+	if AddEphemeralContainers(&delegate.Spec, src.EphemeralContainers) {
+		changed = true
+	}
+	return changed, nil
+}
+
+// addedTolerations filters out the tolerations that the proxy pod mutating webhook set
+// to schedule the proxy pod onto virtual nodes, i.e., not meant for the delegate pod
+func addedTolerations(proxyPod *corev1.Pod) ([]corev1.Toleration, error) {
+	if _, ok := proxyPod.Annotations[common.AnnotationKeyUseConstraintsFromSpecForProxyPodScheduling]; ok {
+		// all tolerations are meant for the proxy pod
+		return nil, nil
+	}
+	proxyPodSched := &corev1.PodSpec{}
+	if s, ok := proxyPod.Annotations[common.AnnotationKeyProxyPodSchedulingConstraints]; ok {
+		if err := yaml.Unmarshal([]byte(s), proxyPodSched); err != nil {
+			return nil, err
+		}
+	}
+	var tolerations []corev1.Toleration
+	for _, t := range proxyPod.Spec.Tolerations {
+		if t.Key == common.LabelAndTaintKeyVirtualKubeletProvider || t.Key == corev1.TaintNodeNetworkUnavailable ||
+			hasToleration(proxyPodSched.Tolerations, t) {
+			continue
+		}
+		tolerations = append(tolerations, t)
+	}
+	return tolerations, nil
+}
+
+// UpdateMutableFields copies the pod spec fields that Kubernetes allows to update from src to dst,
+// i.e., container and init container images and resources (in-place resize), active deadline seconds (only if set or decreased),
+// and tolerations (only additions). Containers are matched by name. It returns whether dst changed.
+func UpdateMutableFields(dst *corev1.PodSpec, src *corev1.PodSpec) bool {
+	return updateMutableFields(dst, src, true)
+}
+
+// UpdateMutableFieldsExceptResources is like UpdateMutableFields, but leaves resources alone,
+// for target clusters where in-place resize isn't enabled, or where resources were defaulted (e.g., by LimitRanger)
+// in a way that the update would contradict.
+func UpdateMutableFieldsExceptResources(dst *corev1.PodSpec, src *corev1.PodSpec) bool {
+	return updateMutableFields(dst, src, false)
+}
+
+func updateMutableFields(dst *corev1.PodSpec, src *corev1.PodSpec, resources bool) bool {
+	changed := updateContainers(dst.Containers, src.Containers, resources)
+	if updateContainers(dst.InitContainers, src.InitContainers, resources) {
+		changed = true
+	}
+
+	if d := src.ActiveDeadlineSeconds; d != nil && (dst.ActiveDeadlineSeconds == nil || *d < *dst.ActiveDeadlineSeconds) {
+		v := *d
+		dst.ActiveDeadlineSeconds = &v
+		changed = true
+	}
+
+	for _, t := range src.Tolerations {
+		if !hasToleration(dst.Tolerations, t) {
+			dst.Tolerations = append(dst.Tolerations, t)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// AddEphemeralContainers adds the ephemeral containers from src that are missing in dst, by name.
+// Ephemeral containers cannot be changed or removed once added. It returns whether dst changed.
+// Note that pods' ephemeral containers can only be updated through the pods/ephemeralcontainers subresource.
+func AddEphemeralContainers(dst *corev1.PodSpec, src []corev1.EphemeralContainer) bool {
+	changed := false
+	for _, s := range src {
+		found := false
+		for _, d := range dst.EphemeralContainers {
+			if d.Name == s.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dst.EphemeralContainers = append(dst.EphemeralContainers, *s.DeepCopy())
+			changed = true
+		}
+	}
+	return changed
+}
+
+func updateContainers(dst []corev1.Container, src []corev1.Container, resources bool) bool {
+	changed := false
+	for i := range dst {
+		for _, s := range src {
+			if s.Name != dst[i].Name {
+				continue
+			}
+			if s.Image != dst[i].Image {
+				dst[i].Image = s.Image
+				changed = true
+			}
+			if resources && !apiequality.Semantic.DeepEqual(s.Resources, dst[i].Resources) {
+				s.Resources.DeepCopyInto(&dst[i].Resources)
+				changed = true
+			}
+			break
+		}
+	}
+	return changed
+}
+
+func hasToleration(tolerations []corev1.Toleration, t corev1.Toleration) bool {
+	for _, other := range tolerations {
+		if other.MatchToleration(&t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package delegatepod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestUpdateMutableFields(t *testing.T) {
+	deadline := func(d int64) *int64 { return &d }
+	cpu := func(q string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(q)}}
+	}
+	spec := func() *corev1.PodSpec {
+		return &corev1.PodSpec{
+			InitContainers:        []corev1.Container{{Name: "init", Image: "init:1"}},
+			Containers:            []corev1.Container{{Name: "a", Image: "a:1", Resources: cpu("100m")}, {Name: "b", Image: "b:1"}},
+			ActiveDeadlineSeconds: deadline(60),
+			Tolerations:           []corev1.Toleration{{Key: "foo", Operator: corev1.TolerationOpExists}},
+		}
+	}
+
+	dst := spec()
+	require.False(t, UpdateMutableFields(dst, spec()))
+	require.Equal(t, spec(), dst)
+
+	src := spec()
+	src.InitContainers[0].Image = "init:2"
+	src.Containers[0].Resources = cpu("0.2")
+	src.Containers[1].Image = "b:2"
+	src.Containers = append(src.Containers, corev1.Container{Name: "c", Image: "c:1"})
+	src.ActiveDeadlineSeconds = deadline(30)
+	src.Tolerations = append(src.Tolerations, corev1.Toleration{Key: "bar", Operator: corev1.TolerationOpExists})
+	src.NodeName = "ignored"
+	require.True(t, UpdateMutableFields(dst, src))
+
+	expected := spec()
+	expected.InitContainers[0].Image = "init:2"
+	expected.Containers[0].Resources = cpu("0.2")
+	expected.Containers[1].Image = "b:2"
+	expected.ActiveDeadlineSeconds = deadline(30)
+	expected.Tolerations = append(expected.Tolerations, corev1.Toleration{Key: "bar", Operator: corev1.TolerationOpExists})
+	require.Equal(t, expected, dst)
+
+	// deadlines can't be increased, tolerations can't be removed
+	src = spec()
+	src.ActiveDeadlineSeconds = deadline(90)
+	src.Tolerations = nil
+	src.InitContainers[0].Image = "init:2"
+	src.Containers[0].Resources = cpu("0.2")
+	src.Containers[1].Image = "b:2"
+	require.False(t, UpdateMutableFields(dst, src))
+	require.Equal(t, expected, dst)
+
+	// resources left alone
+	dst = spec()
+	src = spec()
+	src.Containers[0].Resources = cpu("0.2")
+	require.False(t, UpdateMutableFieldsExceptResources(dst, src))
+	src.Containers[1].Image = "b:2"
+	require.True(t, UpdateMutableFieldsExceptResources(dst, src))
+	expected = spec()
+	expected.Containers[1].Image = "b:2"
+	require.Equal(t, expected, dst)
+}
+
+func TestUpdateFromProxyPod(t *testing.T) {
+	proxyPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			common.AnnotationKeyProxyPodSchedulingConstraints: "tolerations:\n- key: proxy\n  operator: Exists\n",
+		}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "a", Image: "a:2"}},
+			Tolerations: []corev1.Toleration{
+				{Key: common.LabelAndTaintKeyVirtualKubeletProvider, Value: common.VirtualKubeletProviderName},
+				{Key: corev1.TaintNodeNetworkUnavailable, Operator: corev1.TolerationOpExists},
+				{Key: "proxy", Operator: corev1.TolerationOpExists},
+				{Key: "added", Operator: corev1.TolerationOpExists},
+			},
+		},
+	}
+	delegate := &v1alpha1.PodChaperon{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "a", Image: "a:1"}}}}
+
+	changed, err := UpdateFromProxyPod(delegate, proxyPod, nil)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "a:2", delegate.Spec.Containers[0].Image)
+	require.Equal(t, []corev1.Toleration{{Key: "added", Operator: corev1.TolerationOpExists}}, delegate.Spec.Tolerations)
+
+	// tolerations are all meant for proxy pods when constraints from spec are used for proxy pod scheduling
+	proxyPod.Annotations = map[string]string{common.AnnotationKeyUseConstraintsFromSpecForProxyPodScheduling: ""}
+	delegate = &v1alpha1.PodChaperon{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "a", Image: "a:2"}}}}
+	changed, err = UpdateFromProxyPod(delegate, proxyPod, nil)
+	require.NoError(t, err)
+	require.False(t, changed)
+	require.Empty(t, delegate.Spec.Tolerations)
+
+	// updates are transformed like the delegate pod was, so rewritten images aren't reverted
+	p, err := NewPipeline([]v1alpha1.DelegatePodTransformation{{ImageRewrite: &v1alpha1.ImageRewrite{Regexp: "^", Replacement: "mirror/"}}})
+	require.NoError(t, err)
+	delegate = &v1alpha1.PodChaperon{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "a", Image: "mirror/a:2"}}}}
+	changed, err = UpdateFromProxyPod(delegate, proxyPod, p)
+	require.NoError(t, err)
+	require.False(t, changed)
+	proxyPod.Spec.Containers[0].Image = "a:3"
+	changed, err = UpdateFromProxyPod(delegate, proxyPod, p)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, "mirror/a:3", delegate.Spec.Containers[0].Image)
+}
+
+func TestAddEphemeralContainers(t *testing.T) {
+	debugger := func(name string, image string) corev1.EphemeralContainer {
+		return corev1.EphemeralContainer{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: name, Image: image}, TargetContainerName: "a"}
+	}
+	saMount := corev1.VolumeMount{Name: "kube-api-access-abcde", MountPath: "/var/run/secrets/kubernetes.io/serviceaccount"}
+	otherMount := corev1.VolumeMount{Name: "data", MountPath: "/data"}
+
+	proxyPod := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers:          []corev1.Container{{Name: "a", Image: "a:1"}},
+		EphemeralContainers: []corev1.EphemeralContainer{debugger("debugger-1", "busybox:2"), debugger("debugger-2", "busybox")},
+	}}
+	proxyPod.Spec.EphemeralContainers[1].VolumeMounts = []corev1.VolumeMount{saMount, otherMount}
+	delegate := &v1alpha1.PodChaperon{Spec: corev1.PodSpec{
+		Containers:          []corev1.Container{{Name: "a", Image: "a:1"}},
+		EphemeralContainers: []corev1.EphemeralContainer{debugger("debugger-1", "busybox")},
+	}}
+
+	changed, err := UpdateFromProxyPod(delegate, proxyPod, nil)
+	require.NoError(t, err)
+	require.True(t, changed)
+	expected := []corev1.EphemeralContainer{debugger("debugger-1", "busybox"), debugger("debugger-2", "busybox")}
+	expected[1].VolumeMounts = []corev1.VolumeMount{otherMount}
+	require.Equal(t, expected, delegate.Spec.EphemeralContainers)
+	// the proxy pod isn't mutated
+	require.Equal(t, []corev1.VolumeMount{saMount, otherMount}, proxyPod.Spec.EphemeralContainers[1].VolumeMounts)
+
+	require.False(t, AddEphemeralContainers(&delegate.Spec, proxyPod.Spec.EphemeralContainers))
+}
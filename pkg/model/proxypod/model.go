@@ -17,7 +17,11 @@
 package proxypod
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"fmt"
+	"io"
 
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/yaml"
@@ -35,13 +39,65 @@ func IsProxy(pod *corev1.Pod) bool {
 	return pod.Spec.SchedulerName == common.ProxySchedulerName
 }
 
+// SetSourcePod saves the source pod manifest in an annotation of the proxy pod, as YAML,
+// or gzipped and base64-encoded if the YAML is larger than compressionThreshold bytes (if positive),
+// so that large pods (e.g., with many containers or large env blocks) stay under the annotation size limit.
+func SetSourcePod(proxyPod *corev1.Pod, srcPod *corev1.Pod, compressionThreshold int) error {
+	srcPodManifest, err := yaml.Marshal(srcPod)
+	if err != nil {
+		return err
+	}
+	if proxyPod.Annotations == nil {
+		proxyPod.Annotations = map[string]string{}
+	}
+	if compressionThreshold <= 0 || len(srcPodManifest) <= compressionThreshold {
+		proxyPod.Annotations[common.AnnotationKeySourcePodManifest] = string(srcPodManifest)
+		return nil
+	}
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(srcPodManifest); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	proxyPod.Annotations[common.AnnotationKeySourcePodManifestGzip] = base64.StdEncoding.EncodeToString(b.Bytes())
+	return nil
+}
+
+// HasSourcePod returns whether the source pod manifest was saved on the proxy pod, compressed or not
+func HasSourcePod(proxyPod *corev1.Pod) bool {
+	if _, ok := proxyPod.Annotations[common.AnnotationKeySourcePodManifestGzip]; ok {
+		return true
+	}
+	_, ok := proxyPod.Annotations[common.AnnotationKeySourcePodManifest]
+	return ok
+}
+
+// GetSourcePod reads the source pod manifest saved on the proxy pod by SetSourcePod, compressed or not
 func GetSourcePod(proxyPod *corev1.Pod) (*corev1.Pod, error) {
-	srcPodManifest, ok := proxyPod.Annotations[common.AnnotationKeySourcePodManifest]
-	if !ok {
+	var srcPodManifest []byte
+	if s, ok := proxyPod.Annotations[common.AnnotationKeySourcePodManifestGzip]; ok {
+		compressed, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode compressed source pod manifest: %v", err)
+		}
+		r, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress source pod manifest: %v", err)
+		}
+		defer r.Close()
+		if srcPodManifest, err = io.ReadAll(r); err != nil {
+			return nil, fmt.Errorf("cannot decompress source pod manifest: %v", err)
+		}
+	} else if s, ok := proxyPod.Annotations[common.AnnotationKeySourcePodManifest]; ok {
+		srcPodManifest = []byte(s)
+	} else {
 		return nil, fmt.Errorf("no source pod manifest on proxy pod")
 	}
 	srcPod := &corev1.Pod{}
-	if err := yaml.Unmarshal([]byte(srcPodManifest), srcPod); err != nil {
+	if err := yaml.Unmarshal(srcPodManifest, srcPod); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal source pod manifest: %v", err)
 	}
 	return srcPod, nil
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxypod
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestSourcePod(t *testing.T) {
+	srcPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Annotations: map[string]string{common.AnnotationKeyElect: ""}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "c",
+			Image: "busybox",
+			Env:   []corev1.EnvVar{{Name: "LARGE", Value: strings.Repeat("x", 10000)}},
+		}}},
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		compressionThreshold int
+		compressed           bool
+	}{
+		{"compression disabled", 0, false},
+		{"under threshold", 100000, false},
+		{"over threshold", 1000, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyPod := srcPod.DeepCopy()
+			require.False(t, HasSourcePod(proxyPod))
+			_, err := GetSourcePod(proxyPod)
+			require.Error(t, err)
+
+			require.NoError(t, SetSourcePod(proxyPod, srcPod, tc.compressionThreshold))
+			require.True(t, HasSourcePod(proxyPod))
+			_, hasPlain := proxyPod.Annotations[common.AnnotationKeySourcePodManifest]
+			_, hasGzip := proxyPod.Annotations[common.AnnotationKeySourcePodManifestGzip]
+			require.Equal(t, !tc.compressed, hasPlain)
+			require.Equal(t, tc.compressed, hasGzip)
+			if tc.compressed {
+				require.Less(t, len(proxyPod.Annotations[common.AnnotationKeySourcePodManifestGzip]), 1000)
+			}
+
+			got, err := GetSourcePod(proxyPod)
+			require.NoError(t, err)
+			require.Equal(t, srcPod, got)
+		})
+	}
+
+	// proxy pods created by older versions
+	manifest, err := yaml.Marshal(srcPod)
+	require.NoError(t, err)
+	proxyPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{common.AnnotationKeySourcePodManifest: string(manifest)}}}
+	got, err := GetSourcePod(proxyPod)
+	require.NoError(t, err)
+	require.Equal(t, srcPod, got)
+}
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package virtualnode
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// ClusterSummaryHeartbeatPeriod is how often target clusters refresh their cluster summaries' condition heartbeats.
+// ClusterSummaryStaleAfter is how long source clusters wait for a heartbeat before considering a cluster summary stale.
+const (
+	ClusterSummaryHeartbeatPeriod = time.Minute
+	ClusterSummaryStaleAfter      = 3 * ClusterSummaryHeartbeatPeriod
+)
+
+// UnreachableGracePeriod is how long a target's Reachable status condition must have been False
+// before its virtual node's Ready condition becomes Unknown and the node is tainted as unreachable,
+// so that a transient probe failure doesn't evict proxy pods.
+const UnreachableGracePeriod = time.Minute
+
+var pressureConditionTypes = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+// AggregateConditions computes a cluster summary's conditions from the cluster's nodes' conditions:
+// the cluster is ready if any node is ready, and under memory, disk or PID pressure if all ready nodes are
+// (i.e., new pods can't be scheduled anywhere). Heartbeat and transition times are carried over from previous
+// conditions, unless the heartbeat is due or the status changed, respectively. It returns whether anything changed.
+func AggregateConditions(previous []corev1.NodeCondition, nodes []*corev1.Node, now time.Time) ([]corev1.NodeCondition, bool) {
+	readyNodes := 0
+	pressuredNodes := map[corev1.NodeConditionType]int{}
+	for _, n := range nodes {
+		if c := getCondition(n.Status.Conditions, corev1.NodeReady); c == nil || c.Status != corev1.ConditionTrue {
+			continue
+		}
+		readyNodes++
+		for _, t := range pressureConditionTypes {
+			if c := getCondition(n.Status.Conditions, t); c != nil && c.Status == corev1.ConditionTrue {
+				pressuredNodes[t]++
+			}
+		}
+	}
+
+	var conditions []corev1.NodeCondition
+	if readyNodes > 0 {
+		conditions = append(conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue,
+			Reason: "NodesReady", Message: fmt.Sprintf("%d of %d nodes are ready", readyNodes, len(nodes))})
+	} else {
+		conditions = append(conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse,
+			Reason: "NoNodesReady", Message: fmt.Sprintf("0 of %d nodes are ready", len(nodes))})
+	}
+	for _, t := range pressureConditionTypes {
+		if readyNodes > 0 && pressuredNodes[t] == readyNodes {
+			conditions = append(conditions, corev1.NodeCondition{Type: t, Status: corev1.ConditionTrue,
+				Reason: "AllNodesUnder" + string(t), Message: fmt.Sprintf("all %d ready nodes are under pressure", readyNodes)})
+		} else {
+			conditions = append(conditions, corev1.NodeCondition{Type: t, Status: corev1.ConditionFalse,
+				Reason: "NodesWithout" + string(t), Message: fmt.Sprintf("%d of %d ready nodes are under pressure", pressuredNodes[t], readyNodes)})
+		}
+	}
+
+	heartbeatDue := true
+	for _, c := range previous {
+		if now.Sub(c.LastHeartbeatTime.Time) < ClusterSummaryHeartbeatPeriod {
+			heartbeatDue = false
+			break
+		}
+	}
+	conditions, changed := mergeConditions(previous, conditions, now, heartbeatDue)
+	return conditions, changed || heartbeatDue
+}
+
+// TargetReachable interprets a target's Reachable status condition, set by the target status controller.
+// A missing or Unknown condition (e.g., not probed yet) counts as reachable, and so does a False condition
+// until UnreachableGracePeriod has passed since its last transition. The returned error describes the last failed probe, if any.
+func TargetReachable(reachable *metav1.Condition, now time.Time) (bool, error) {
+	if reachable == nil || reachable.Status != metav1.ConditionFalse {
+		return true, nil
+	}
+	return now.Sub(reachable.LastTransitionTime.Time) < UnreachableGracePeriod, errors.New(reachable.Message)
+}
+
+// Conditions computes a virtual node's conditions, given whether its target cluster's API server is reachable,
+// and the target's cluster summary (possibly nil if the target's agent hasn't created it yet).
+// Cluster summaries without conditions (from older agents) are considered fresh, ready and without pressure.
+// Transition times are carried over from previous conditions. It returns whether anything other than heartbeats changed.
+func Conditions(previous []corev1.NodeCondition, reachable bool, reachableErr error, summary *v1alpha1.ClusterSummary, now time.Time) ([]corev1.NodeCondition, bool) {
+	freshType := corev1.NodeConditionType(common.NodeConditionTypeClusterSummaryFresh)
+	var conditions []corev1.NodeCondition
+
+	if !reachable {
+		msg := "cannot reach the target cluster's API server"
+		if reachableErr != nil {
+			msg += ": " + reachableErr.Error()
+		}
+		conditions = append(conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionUnknown, Reason: "TargetUnreachable", Message: msg})
+		for _, t := range pressureConditionTypes {
+			conditions = append(conditions, corev1.NodeCondition{Type: t, Status: corev1.ConditionUnknown, Reason: "TargetUnreachable", Message: msg})
+		}
+		conditions = append(conditions, corev1.NodeCondition{Type: freshType, Status: corev1.ConditionUnknown, Reason: "TargetUnreachable", Message: msg})
+		return mergeConditions(previous, conditions, now, true)
+	}
+
+	var summaryConditions []corev1.NodeCondition
+	if summary != nil {
+		summaryConditions = summary.Conditions
+	}
+
+	if c := getCondition(summaryConditions, corev1.NodeReady); c != nil && c.Status != corev1.ConditionTrue {
+		conditions = append(conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionFalse, Reason: "TargetNotReady", Message: c.Message})
+	} else {
+		conditions = append(conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue, Reason: "TargetReady", Message: "the target cluster is reachable and ready"})
+	}
+
+	for _, t := range pressureConditionTypes {
+		if c := getCondition(summaryConditions, t); c != nil && c.Status == corev1.ConditionTrue {
+			conditions = append(conditions, corev1.NodeCondition{Type: t, Status: corev1.ConditionTrue, Reason: "TargetUnder" + string(t), Message: c.Message})
+		} else {
+			conditions = append(conditions, corev1.NodeCondition{Type: t, Status: corev1.ConditionFalse, Reason: "TargetHasNo" + string(t)})
+		}
+	}
+
+	switch {
+	case summary == nil:
+		conditions = append(conditions, corev1.NodeCondition{Type: freshType, Status: corev1.ConditionFalse, Reason: "ClusterSummaryNotFound",
+			Message: "the target cluster's agent hasn't created its cluster summary"})
+	case len(summaryConditions) > 0 && now.Sub(lastHeartbeat(summaryConditions)) > ClusterSummaryStaleAfter:
+		conditions = append(conditions, corev1.NodeCondition{Type: freshType, Status: corev1.ConditionFalse, Reason: "ClusterSummaryStale",
+			Message: fmt.Sprintf("the target cluster's summary hasn't been refreshed since %s, is its agent running?", lastHeartbeat(summaryConditions).Format(time.RFC3339))})
+	default:
+		conditions = append(conditions, corev1.NodeCondition{Type: freshType, Status: corev1.ConditionTrue, Reason: "ClusterSummaryFresh"})
+	}
+
+	return mergeConditions(previous, conditions, now, true)
+}
+
+// managedTaints are the taints that Taints adds and removes; the unreachable and not-ready taints are also managed by the node lifecycle controller
+// in the source cluster based on the Ready condition, but we don't wait for it, and both agree.
+var (
+	unreachableTaints = []corev1.Taint{
+		{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoExecute},
+	}
+	notReadyTaints = []corev1.Taint{
+		{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute},
+	}
+	staleTaint = corev1.Taint{Key: common.TaintKeyClusterSummaryStale, Effect: corev1.TaintEffectNoSchedule}
+
+	managedTaints = append(append([]corev1.Taint{staleTaint}, unreachableTaints...), notReadyTaints...)
+)
+
+// Taints reconciles a virtual node's taints with its conditions (see Conditions):
+// node.kubernetes.io/unreachable (NoSchedule and NoExecute) if Ready is Unknown,
+// node.kubernetes.io/not-ready (NoSchedule and NoExecute) if Ready is False,
+// and TaintKeyClusterSummaryStale (NoSchedule) if ClusterSummaryFresh isn't True.
+// Other taints are kept as is, as are the times managed NoExecute taints were added. It returns whether anything changed.
+// Pressure conditions are translated into taints by the node lifecycle controller.
+func Taints(taints []corev1.Taint, conditions []corev1.NodeCondition, now time.Time) ([]corev1.Taint, bool) {
+	var desired []corev1.Taint
+	if c := getCondition(conditions, corev1.NodeReady); c != nil {
+		switch c.Status {
+		case corev1.ConditionUnknown:
+			desired = append(desired, unreachableTaints...)
+		case corev1.ConditionFalse:
+			desired = append(desired, notReadyTaints...)
+		}
+	}
+	if c := getCondition(conditions, corev1.NodeConditionType(common.NodeConditionTypeClusterSummaryFresh)); c != nil && c.Status != corev1.ConditionTrue {
+		desired = append(desired, staleTaint)
+	}
+
+	changed := false
+	var result []corev1.Taint
+	for _, t := range taints {
+		if isManagedTaint(t) && !hasTaint(desired, t) {
+			changed = true
+			continue
+		}
+		result = append(result, t)
+	}
+	for _, t := range desired {
+		if !hasTaint(result, t) {
+			if t.Effect == corev1.TaintEffectNoExecute {
+				t.TimeAdded = &metav1.Time{Time: now}
+			}
+			result = append(result, t)
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+func isManagedTaint(t corev1.Taint) bool {
+	return hasTaint(managedTaints, t)
+}
+
+func hasTaint(taints []corev1.Taint, t corev1.Taint) bool {
+	for _, other := range taints {
+		if other.MatchTaint(&t) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeConditions carries over previous transition times (and heartbeat times, unless heartbeat is true)
+// to conditions whose status didn't change, keeps previous conditions of other types (e.g., set by other controllers),
+// and returns whether anything other than heartbeat times changed
+func mergeConditions(previous []corev1.NodeCondition, conditions []corev1.NodeCondition, now time.Time, heartbeat bool) ([]corev1.NodeCondition, bool) {
+	changed := false
+	t := metav1.Time{Time: now}
+	for i := range conditions {
+		c := &conditions[i]
+		c.LastHeartbeatTime = t
+		c.LastTransitionTime = t
+		p := getCondition(previous, c.Type)
+		if p == nil {
+			changed = true
+			continue
+		}
+		if p.Status == c.Status {
+			c.LastTransitionTime = p.LastTransitionTime
+		}
+		if !heartbeat {
+			c.LastHeartbeatTime = p.LastHeartbeatTime
+		}
+		if p.Status != c.Status || p.Reason != c.Reason || p.Message != c.Message {
+			changed = true
+		}
+	}
+	for _, p := range previous {
+		if getCondition(conditions, p.Type) == nil {
+			conditions = append(conditions, p)
+		}
+	}
+	return conditions, changed
+}
+
+func getCondition(conditions []corev1.NodeCondition, t corev1.NodeConditionType) *corev1.NodeCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+func lastHeartbeat(conditions []corev1.NodeCondition) time.Time {
+	var last time.Time
+	for _, c := range conditions {
+		if c.LastHeartbeatTime.After(last) {
+			last = c.LastHeartbeatTime.Time
+		}
+	}
+	return last
+}
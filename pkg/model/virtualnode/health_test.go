@@ -0,0 +1,243 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package virtualnode
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func statuses(conditions []corev1.NodeCondition) map[corev1.NodeConditionType]corev1.ConditionStatus {
+	m := map[corev1.NodeConditionType]corev1.ConditionStatus{}
+	for _, c := range conditions {
+		m[c.Type] = c.Status
+	}
+	return m
+}
+
+func TestAggregateConditions(t *testing.T) {
+	node := func(ready bool, pressures ...corev1.NodeConditionType) *corev1.Node {
+		n := &corev1.Node{}
+		s := corev1.ConditionFalse
+		if ready {
+			s = corev1.ConditionTrue
+		}
+		n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{Type: corev1.NodeReady, Status: s})
+		for _, p := range pressures {
+			n.Status.Conditions = append(n.Status.Conditions, corev1.NodeCondition{Type: p, Status: corev1.ConditionTrue})
+		}
+		return n
+	}
+	now := time.Now()
+
+	// pressure counts if all ready nodes are under pressure (not-ready nodes don't count)
+	nodes := []*corev1.Node{node(true, corev1.NodeMemoryPressure, corev1.NodeDiskPressure), node(true, corev1.NodeMemoryPressure), node(false)}
+	conditions, changed := AggregateConditions(nil, nodes, now)
+	require.True(t, changed)
+	require.Equal(t, map[corev1.NodeConditionType]corev1.ConditionStatus{
+		corev1.NodeReady:          corev1.ConditionTrue,
+		corev1.NodeMemoryPressure: corev1.ConditionTrue,
+		corev1.NodeDiskPressure:   corev1.ConditionFalse,
+		corev1.NodePIDPressure:    corev1.ConditionFalse,
+	}, statuses(conditions))
+
+	// nothing changed, heartbeat not due
+	later := now.Add(ClusterSummaryHeartbeatPeriod / 2)
+	conditions2, changed := AggregateConditions(conditions, nodes, later)
+	require.False(t, changed)
+	require.Equal(t, conditions, conditions2)
+
+	// heartbeat due
+	later = now.Add(ClusterSummaryHeartbeatPeriod)
+	conditions2, changed = AggregateConditions(conditions, nodes, later)
+	require.True(t, changed)
+	for _, c := range conditions2 {
+		require.Equal(t, later, c.LastHeartbeatTime.Time)
+		require.Equal(t, now, c.LastTransitionTime.Time)
+	}
+
+	// no ready nodes
+	conditions, changed = AggregateConditions(conditions2, []*corev1.Node{node(false)}, later)
+	require.True(t, changed)
+	require.Equal(t, corev1.ConditionFalse, statuses(conditions)[corev1.NodeReady])
+	require.Equal(t, corev1.ConditionFalse, statuses(conditions)[corev1.NodeMemoryPressure])
+}
+
+func TestConditions(t *testing.T) {
+	now := time.Now()
+	fresh := corev1.NodeConditionType(common.NodeConditionTypeClusterSummaryFresh)
+	summary := func(heartbeat time.Time, ready corev1.ConditionStatus) *v1alpha1.ClusterSummary {
+		return &v1alpha1.ClusterSummary{Conditions: []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready, LastHeartbeatTime: metav1.NewTime(heartbeat)},
+			{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue, LastHeartbeatTime: metav1.NewTime(heartbeat)},
+		}}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		reachable bool
+		summary   *v1alpha1.ClusterSummary
+		want      map[corev1.NodeConditionType]corev1.ConditionStatus
+	}{{
+		name:      "unreachable",
+		reachable: false,
+		summary:   summary(now, corev1.ConditionTrue),
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionUnknown, corev1.NodeMemoryPressure: corev1.ConditionUnknown,
+			corev1.NodeDiskPressure: corev1.ConditionUnknown, corev1.NodePIDPressure: corev1.ConditionUnknown, fresh: corev1.ConditionUnknown,
+		},
+	}, {
+		name:      "healthy",
+		reachable: true,
+		summary:   summary(now, corev1.ConditionTrue),
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionTrue, corev1.NodeMemoryPressure: corev1.ConditionFalse,
+			corev1.NodeDiskPressure: corev1.ConditionTrue, corev1.NodePIDPressure: corev1.ConditionFalse, fresh: corev1.ConditionTrue,
+		},
+	}, {
+		name:      "not ready",
+		reachable: true,
+		summary:   summary(now, corev1.ConditionFalse),
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionFalse, corev1.NodeMemoryPressure: corev1.ConditionFalse,
+			corev1.NodeDiskPressure: corev1.ConditionTrue, corev1.NodePIDPressure: corev1.ConditionFalse, fresh: corev1.ConditionTrue,
+		},
+	}, {
+		name:      "stale",
+		reachable: true,
+		summary:   summary(now.Add(-2*ClusterSummaryStaleAfter), corev1.ConditionTrue),
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionTrue, corev1.NodeMemoryPressure: corev1.ConditionFalse,
+			corev1.NodeDiskPressure: corev1.ConditionTrue, corev1.NodePIDPressure: corev1.ConditionFalse, fresh: corev1.ConditionFalse,
+		},
+	}, {
+		name:      "not found",
+		reachable: true,
+		summary:   nil,
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionTrue, corev1.NodeMemoryPressure: corev1.ConditionFalse,
+			corev1.NodeDiskPressure: corev1.ConditionFalse, corev1.NodePIDPressure: corev1.ConditionFalse, fresh: corev1.ConditionFalse,
+		},
+	}, {
+		name:      "older agent",
+		reachable: true,
+		summary:   &v1alpha1.ClusterSummary{},
+		want: map[corev1.NodeConditionType]corev1.ConditionStatus{
+			corev1.NodeReady: corev1.ConditionTrue, corev1.NodeMemoryPressure: corev1.ConditionFalse,
+			corev1.NodeDiskPressure: corev1.ConditionFalse, corev1.NodePIDPressure: corev1.ConditionFalse, fresh: corev1.ConditionTrue,
+		},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			if !tc.reachable {
+				err = errors.New("timeout")
+			}
+			conditions, changed := Conditions(nil, tc.reachable, err, tc.summary, now)
+			require.True(t, changed)
+			require.Equal(t, tc.want, statuses(conditions))
+
+			// only heartbeats change
+			later := now.Add(time.Second)
+			conditions2, changed := Conditions(conditions, tc.reachable, err, tc.summary, later)
+			require.False(t, changed)
+			for _, c := range conditions2 {
+				require.Equal(t, later, c.LastHeartbeatTime.Time)
+				require.Equal(t, now, c.LastTransitionTime.Time)
+			}
+		})
+	}
+}
+
+func TestTargetReachable(t *testing.T) {
+	now := time.Now()
+	condition := func(status metav1.ConditionStatus, since time.Duration) *metav1.Condition {
+		return &metav1.Condition{Type: v1alpha1.TargetConditionReachable, Status: status, Message: "timeout",
+			LastTransitionTime: metav1.NewTime(now.Add(-since))}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		condition *metav1.Condition
+		reachable bool
+		err       bool
+	}{
+		{name: "not probed yet", condition: nil, reachable: true},
+		{name: "reachable", condition: condition(metav1.ConditionTrue, time.Hour), reachable: true},
+		{name: "invalid kubeconfig", condition: condition(metav1.ConditionUnknown, time.Hour), reachable: true},
+		{name: "unreachable within grace period", condition: condition(metav1.ConditionFalse, UnreachableGracePeriod/2), reachable: true, err: true},
+		{name: "unreachable", condition: condition(metav1.ConditionFalse, UnreachableGracePeriod), reachable: false, err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reachable, err := TargetReachable(tc.condition, now)
+			require.Equal(t, tc.reachable, reachable)
+			if tc.err {
+				require.EqualError(t, err, "timeout")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTaints(t *testing.T) {
+	now := time.Now()
+	vkTaint := corev1.Taint{Key: common.LabelAndTaintKeyVirtualKubeletProvider, Value: common.VirtualKubeletProviderName, Effect: corev1.TaintEffectNoSchedule}
+	conditions := func(ready, fresh corev1.ConditionStatus) []corev1.NodeCondition {
+		return []corev1.NodeCondition{
+			{Type: corev1.NodeReady, Status: ready},
+			{Type: corev1.NodeConditionType(common.NodeConditionTypeClusterSummaryFresh), Status: fresh},
+		}
+	}
+
+	taints, changed := Taints([]corev1.Taint{vkTaint}, conditions(corev1.ConditionTrue, corev1.ConditionTrue), now)
+	require.False(t, changed)
+	require.Equal(t, []corev1.Taint{vkTaint}, taints)
+
+	taints, changed = Taints(taints, conditions(corev1.ConditionUnknown, corev1.ConditionUnknown), now)
+	require.True(t, changed)
+	require.Equal(t, []corev1.Taint{
+		vkTaint,
+		{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeUnreachable, Effect: corev1.TaintEffectNoExecute, TimeAdded: &metav1.Time{Time: now}},
+		{Key: common.TaintKeyClusterSummaryStale, Effect: corev1.TaintEffectNoSchedule},
+	}, taints)
+
+	// time added is kept
+	taints2, changed := Taints(taints, conditions(corev1.ConditionUnknown, corev1.ConditionUnknown), now.Add(time.Minute))
+	require.False(t, changed)
+	require.Equal(t, taints, taints2)
+
+	taints, changed = Taints(taints, conditions(corev1.ConditionFalse, corev1.ConditionTrue), now)
+	require.True(t, changed)
+	require.Equal(t, []corev1.Taint{
+		vkTaint,
+		{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoSchedule},
+		{Key: corev1.TaintNodeNotReady, Effect: corev1.TaintEffectNoExecute, TimeAdded: &metav1.Time{Time: now}},
+	}, taints)
+
+	taints, changed = Taints(taints, conditions(corev1.ConditionTrue, corev1.ConditionTrue), now)
+	require.True(t, changed)
+	require.Equal(t, []corev1.Taint{vkTaint}, taints)
+}
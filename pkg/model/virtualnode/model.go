@@ -17,10 +17,15 @@
 package virtualnode
 
 import (
+	"strconv"
+
 	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
 )
 
-func BaseLabels(targetNamespace, targetName string) map[string]string {
+// BaseLabels returns the labels of a target's virtual node that don't come from its cluster summary,
+// including the target's weight, priority and tier if specified, so they can be used in node affinities.
+func BaseLabels(t agent.Target) map[string]string {
 	l := map[string]string{
 		"type": "virtual-kubelet",
 		common.LabelAndTaintKeyVirtualKubeletProvider:             common.VirtualKubeletProviderName,
@@ -28,11 +33,20 @@ func BaseLabels(targetNamespace, targetName string) map[string]string {
 		"alpha.service-controller.kubernetes.io/exclude-balancer": "true",
 		"node.kubernetes.io/exclude-from-external-load-balancers": "true",
 	}
-	if targetNamespace == "" {
-		l[common.LabelKeyClusterTargetName] = targetName
+	if t.Namespace == "" {
+		l[common.LabelKeyClusterTargetName] = t.Name
 	} else {
-		l[common.LabelKeyTargetNamespace] = targetNamespace
-		l[common.LabelKeyTargetName] = targetName
+		l[common.LabelKeyTargetNamespace] = t.Namespace
+		l[common.LabelKeyTargetName] = t.Name
+	}
+	if t.Weight != nil {
+		l[common.LabelKeyTargetWeight] = strconv.Itoa(int(*t.Weight))
+	}
+	if t.Priority != nil {
+		l[common.LabelKeyTargetPriority] = strconv.Itoa(int(*t.Priority))
+	}
+	if t.Tier != nil {
+		l[common.LabelKeyTargetTier] = strconv.Itoa(int(*t.Tier))
 	}
 	return l
 }
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package candidate
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// Args are the candidate plugin's arguments, in the pluginConfig section of the KubeSchedulerConfiguration.
+type Args struct {
+	// PermitTimeout is how long to wait for the proxy scheduler to allow a candidate. Defaults to 30s.
+	// It can be overridden per target in source clusters (the proxy scheduler annotates pod chaperons accordingly).
+	PermitTimeout *metav1.Duration `json:"permitTimeout,omitempty"`
+}
+
+const defaultPermitTimeout = 30 * time.Second
+
+func decodeArgs(obj runtime.Object) (Args, error) {
+	args := Args{}
+	if err := frameworkruntime.DecodeInto(obj, &args); err != nil {
+		return args, fmt.Errorf("invalid candidate plugin args: %v", err)
+	}
+	if args.PermitTimeout == nil {
+		args.PermitTimeout = &metav1.Duration{Duration: defaultPermitTimeout}
+	}
+	if args.PermitTimeout.Duration <= 0 {
+		return args, fmt.Errorf("invalid candidate plugin args: permitTimeout must be positive")
+	}
+	return args, nil
+}
+
+func (pl *Plugin) permitTimeout(c *v1alpha1.PodChaperon) time.Duration {
+	if c != nil {
+		if s, ok := c.Annotations[common.AnnotationKeyPermitTimeout]; ok {
+			d, err := time.ParseDuration(s)
+			if err == nil && d > 0 {
+				return d
+			}
+			klog.Warningf("ignoring invalid permit timeout %q on pod chaperon %s/%s", s, c.Namespace, c.Name)
+		}
+	}
+	return pl.args.PermitTimeout.Duration
+}
@@ -18,30 +18,43 @@ package candidate
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	policylisters "k8s.io/client-go/listers/policy/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/common"
 	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
 )
 
 type Plugin struct {
-	handle framework.Handle
-	client versioned.Interface
+	ctx               context.Context
+	handle            framework.Handle
+	client            versioned.Interface
+	args              Args
+	podChaperonLister listers.PodChaperonLister
+	pdbLister         policylisters.PodDisruptionBudgetLister
+	preemption        defaultPreemption
 }
 
 var _ framework.PreFilterPlugin = &Plugin{}
+var _ framework.PostFilterPlugin = &Plugin{}
 var _ framework.ReservePlugin = &Plugin{}
-var _ framework.PreBindPlugin = &Plugin{}
+var _ framework.PermitPlugin = &Plugin{}
 
 // Name is the name of the plugin used in the plugin registry and configurations.
 const Name = "candidate"
@@ -53,7 +66,7 @@ func (pl *Plugin) Name() string {
 
 func (pl *Plugin) PreFilter(ctx context.Context, state *framework.CycleState, p *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
 	// reset annotations
-	patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyIsReserved + `":null}}}`)
+	patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyIsReserved + `":null,"` + common.AnnotationKeyPreemptionCost + `":null}}}`)
 	if _, err := pl.client.MulticlusterV1alpha1().PodChaperons(p.Namespace).Patch(ctx, p.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
 		return nil, framework.NewStatus(framework.Error, err.Error())
 	}
@@ -72,48 +85,155 @@ func (pl *Plugin) Reserve(ctx context.Context, state *framework.CycleState, p *v
 	return nil
 }
 
+// Unreserve releases the other waiting candidates of the pod's group, if any, from the same source cluster,
+// which the proxy scheduler won't allow without this one, so they don't hold resources in vain
 func (pl *Plugin) Unreserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
+	group, ok := p.Annotations[common.AnnotationKeyPodGroup]
+	if !ok {
+		return
+	}
+	pl.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if member := wp.GetPod(); member.UID != p.UID && member.Namespace == p.Namespace &&
+			member.Annotations[common.AnnotationKeyPodGroup] == group &&
+			member.Labels[common.LabelKeyParentClusterName] == p.Labels[common.LabelKeyParentClusterName] {
+			wp.Reject(Name, fmt.Sprintf("pod group member %s unreserved", p.Name))
+		}
+	})
 }
 
-const waitDuration = 30 * time.Second
+// Permit makes the pod wait until its pod chaperon is allowed by the proxy scheduler, if ever.
+// Waiting pods are allowed (or rejected) by the pod chaperon informer's event handler.
+func (pl *Plugin) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	c, err := pl.getPodChaperon(p.Namespace, p.Name)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error()), 0
+	}
+	if isAllowed(c) {
+		return nil, 0
+	}
 
-func (pl *Plugin) PreBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
-	ctx, cancel := context.WithTimeout(ctx, waitDuration)
-	defer cancel()
+	// the pod chaperon may be allowed after we checked but before the pod is added to the waiting pods,
+	// in which case the event handler wouldn't find it, so we check again once it's waiting
+	go pl.allowOnceWaiting(p.UID, p.Namespace, p.Name)
 
-	// wait until pod is allowed, if ever
-	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
-		return pl.isAllowed(ctx, p)
-	}, ctx.Done()); err != nil {
-		// condition func doesn't throw, i.e., ctx timed out, pod was never allowed
-		return framework.NewStatus(framework.Error, err.Error())
-	}
+	return framework.NewStatus(framework.Wait), pl.permitTimeout(c)
+}
 
-	return nil
+func (pl *Plugin) allowOnceWaiting(uid types.UID, namespace, name string) {
+	var wp framework.WaitingPod
+	_ = wait.PollUntilContextTimeout(pl.ctx, 10*time.Millisecond, time.Second, true, func(ctx context.Context) (bool, error) {
+		wp = pl.handle.GetWaitingPod(uid)
+		return wp != nil, nil
+	})
+	if wp == nil {
+		return
+	}
+	if c, err := pl.getPodChaperon(namespace, name); err == nil && isAllowed(c) {
+		wp.Allow(Name)
+	}
 }
 
-func (pl *Plugin) isAllowed(ctx context.Context, p *v1.Pod) (bool, error) {
-	pod, err := pl.client.MulticlusterV1alpha1().PodChaperons(p.Namespace).Get(ctx, p.Name, metav1.GetOptions{})
+// getPodChaperon returns nil if the pod chaperon isn't cached (yet?)
+func (pl *Plugin) getPodChaperon(namespace, name string) (*v1alpha1.PodChaperon, error) {
+	c, err := pl.podChaperonLister.PodChaperons(namespace).Get(name)
 	if err != nil {
-		// TODO handle retriable vs. not retriable, esp. not found (we assume retriable for now)
-		// TODO log
-		return false, nil
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
 	}
+	return c, nil
+}
 
-	if _, ok := pod.Annotations[common.AnnotationKeyIsAllowed]; !ok {
+func isAllowed(c *v1alpha1.PodChaperon) bool {
+	if c == nil {
+		return false
+	}
+
+	if _, ok := c.Annotations[common.AnnotationKeyIsAllowed]; !ok {
 		// pod not allowed (yet?)
-		klog.V(1).Infof("candidate %s is not allowed", pod.Name)
-		return false, nil
+		klog.V(1).Infof("candidate %s is not allowed", c.Name)
+		return false
 	}
 
-	klog.V(1).Infof("candidate %s is allowed", pod.Name)
-	return true, nil
+	klog.V(1).Infof("candidate %s is allowed", c.Name)
+	return true
+}
+
+func (pl *Plugin) handlePodChaperon(obj interface{}) {
+	c, ok := obj.(*v1alpha1.PodChaperon)
+	if !ok {
+		return
+	}
+	if _, ok := c.Annotations[common.AnnotationKeyIsAllowed]; !ok {
+		return
+	}
+	pl.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if p := wp.GetPod(); p.Namespace == c.Namespace && p.Name == c.Name {
+			klog.V(1).Infof("candidate %s is allowed", c.Name)
+			wp.Allow(Name)
+		}
+	})
+}
+
+func (pl *Plugin) handlePodChaperonDeletion(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c, ok := obj.(*v1alpha1.PodChaperon)
+	if !ok {
+		return
+	}
+	pl.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if p := wp.GetPod(); p.Namespace == c.Namespace && p.Name == c.Name {
+			wp.Reject(Name, "pod chaperon deleted")
+		}
+	})
 }
 
 // New initializes a new plugin and returns it.
-func New(ctx context.Context, _ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+func New(ctx context.Context, obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := config.GetConfigOrDie()
 	client, err := versioned.NewForConfig(cfg)
 	utilruntime.Must(err)
-	return &Plugin{handle: h, client: client}, nil
+
+	f := informers.NewSharedInformerFactory(client, 0)
+	podChaperonInformer := f.Multicluster().V1alpha1().PodChaperons()
+
+	preemption, err := newDefaultPreemption(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+
+	pl := &Plugin{
+		ctx:               ctx,
+		handle:            h,
+		client:            client,
+		args:              args,
+		podChaperonLister: podChaperonInformer.Lister(),
+		pdbLister:         h.SharedInformerFactory().Policy().V1().PodDisruptionBudgets().Lister(),
+		preemption:        preemption,
+	}
+
+	if _, err := podChaperonInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: pl.handlePodChaperon,
+		UpdateFunc: func(_, newObj interface{}) {
+			pl.handlePodChaperon(newObj)
+		},
+		DeleteFunc: pl.handlePodChaperonDeletion,
+	}); err != nil {
+		return nil, err
+	}
+
+	f.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), podChaperonInformer.Informer().HasSynced) {
+		return nil, fmt.Errorf("failed to wait for pod chaperon cache to sync")
+	}
+
+	return pl, nil
 }
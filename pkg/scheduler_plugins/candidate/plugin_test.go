@@ -0,0 +1,185 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package candidate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	listers "admiralty.io/multicluster-scheduler/pkg/generated/listers/multicluster/v1alpha1"
+)
+
+// withFramework sets the plugin's handle to a scheduling framework running its Permit extension point,
+// so that waiting pods are managed as in the scheduler
+func withFramework(t *testing.T, pl *Plugin) framework.Framework {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	pl.ctx = ctx
+	fwk, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterPermitPlugin(Name, func(_ context.Context, _ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+			pl.handle = h
+			return pl, nil
+		}),
+	}, "candidate")
+	require.NoError(t, err)
+	return fwk
+}
+
+// newPlugin returns a plugin whose pod chaperon lister is backed by an indexer, to add pod chaperons to
+func newPlugin() (*Plugin, cache.Indexer) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	return &Plugin{
+		args:              Args{PermitTimeout: &metav1.Duration{Duration: time.Minute}},
+		podChaperonLister: listers.NewPodChaperonLister(indexer),
+	}, indexer
+}
+
+func newCandidate(name, group, parentClusterName string) *v1.Pod {
+	p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns",
+		Name:        name,
+		UID:         types.UID(name),
+		Labels:      map[string]string{common.LabelKeyParentClusterName: parentClusterName},
+		Annotations: map[string]string{},
+	}}
+	if group != "" {
+		p.Annotations[common.AnnotationKeyPodGroup] = group
+	}
+	return p
+}
+
+func TestUnreservePodGroup(t *testing.T) {
+	ctx := context.Background()
+	pl, _ := newPlugin()
+	fwk := withFramework(t, pl)
+
+	unreserved := newCandidate("unreserved", "job", "source")
+	member := newCandidate("member", "job", "source")
+	otherGroup := newCandidate("other-group", "other-job", "source")
+	otherSource := newCandidate("other-source", "job", "other-source")
+	alone := newCandidate("alone", "", "source")
+	for _, p := range []*v1.Pod{member, otherGroup, otherSource} {
+		require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), p, "node").Code())
+	}
+
+	// not a group member: nothing to release
+	pl.Unreserve(ctx, framework.NewCycleState(), alone, "node")
+	for _, p := range []*v1.Pod{member, otherGroup, otherSource} {
+		require.NotNil(t, fwk.GetWaitingPod(p.UID))
+	}
+
+	// only the waiting members of the same group, from the same source cluster, are released
+	pl.Unreserve(ctx, framework.NewCycleState(), unreserved, "node")
+	s := fwk.WaitOnPermit(ctx, member)
+	require.True(t, s.IsRejected())
+	require.Contains(t, s.Message(), "pod group member unreserved unreserved")
+	require.NotNil(t, fwk.GetWaitingPod(otherGroup.UID))
+	require.NotNil(t, fwk.GetWaitingPod(otherSource.UID))
+	fwk.RejectWaitingPod(otherGroup.UID)
+	fwk.RejectWaitingPod(otherSource.UID)
+}
+
+func newPodChaperon(name string, annotations map[string]string) *v1alpha1.PodChaperon {
+	return &v1alpha1.PodChaperon{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Annotations: annotations}}
+}
+
+func TestPermit(t *testing.T) {
+	ctx := context.Background()
+	pl, indexer := newPlugin()
+	fwk := withFramework(t, pl)
+
+	// already allowed: not waiting
+	allowed := newCandidate("allowed", "", "source")
+	require.NoError(t, indexer.Add(newPodChaperon("allowed", map[string]string{common.AnnotationKeyIsAllowed: "true"})))
+	s, _ := pl.Permit(ctx, framework.NewCycleState(), allowed, "node")
+	require.True(t, s.IsSuccess())
+
+	// allowed while waiting, by the pod chaperon event handler
+	waiting := newCandidate("waiting", "", "source")
+	c := newPodChaperon("waiting", map[string]string{common.AnnotationKeyPermitTimeout: "2m"})
+	require.NoError(t, indexer.Add(c))
+	s, timeout := pl.Permit(ctx, framework.NewCycleState(), waiting, "node")
+	require.Equal(t, framework.Wait, s.Code())
+	require.Equal(t, 2*time.Minute, timeout)
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), waiting, "node").Code())
+	c = c.DeepCopy()
+	c.Annotations[common.AnnotationKeyIsAllowed] = "true"
+	require.NoError(t, indexer.Update(c))
+	pl.handlePodChaperon(c)
+	require.True(t, fwk.WaitOnPermit(ctx, waiting).IsSuccess())
+
+	// rejected while waiting, because the proxy scheduler deleted the pod chaperon
+	rejected := newCandidate("rejected", "", "source")
+	c = newPodChaperon("rejected", nil)
+	require.NoError(t, indexer.Add(c))
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), rejected, "node").Code())
+	require.NoError(t, indexer.Delete(c))
+	pl.handlePodChaperonDeletion(cache.DeletedFinalStateUnknown{Key: "ns/rejected", Obj: c})
+	s = fwk.WaitOnPermit(ctx, rejected)
+	require.True(t, s.IsRejected())
+	require.Contains(t, s.Message(), "pod chaperon deleted")
+
+	// timed out
+	timedOut := newCandidate("timed-out", "", "source")
+	require.NoError(t, indexer.Add(newPodChaperon("timed-out", map[string]string{common.AnnotationKeyPermitTimeout: "10ms"})))
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), timedOut, "node").Code())
+	s = fwk.WaitOnPermit(ctx, timedOut)
+	require.True(t, s.IsRejected())
+	require.Contains(t, s.Message(), "timeout")
+}
+
+func TestAllowOnceWaiting(t *testing.T) {
+	ctx := context.Background()
+	pl, indexer := newPlugin()
+	fwk := withFramework(t, pl)
+
+	// the pod chaperon is allowed after Permit checked it, but before the pod is waiting,
+	// so the event handler doesn't find the pod, but the pod is allowed once it's waiting
+	p := newCandidate("racy", "", "source")
+	c := newPodChaperon("racy", nil)
+	require.NoError(t, indexer.Add(c))
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), p, "node").Code())
+	c = c.DeepCopy()
+	c.Annotations = map[string]string{common.AnnotationKeyIsAllowed: "true"}
+	require.NoError(t, indexer.Update(c))
+	pl.allowOnceWaiting(p.UID, p.Namespace, p.Name)
+	require.True(t, fwk.WaitOnPermit(ctx, p).IsSuccess())
+
+	// not allowed: still waiting
+	p = newCandidate("not-allowed", "", "source")
+	require.NoError(t, indexer.Add(newPodChaperon("not-allowed", nil)))
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), p, "node").Code())
+	pl.allowOnceWaiting(p.UID, p.Namespace, p.Name)
+	require.NotNil(t, fwk.GetWaitingPod(p.UID))
+	fwk.RejectWaitingPod(p.UID)
+}
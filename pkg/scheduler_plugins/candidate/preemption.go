@@ -0,0 +1,144 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package candidate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/kubernetes/pkg/features"
+	"k8s.io/kubernetes/pkg/scheduler/apis/config"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultpreemption"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/feature"
+	"k8s.io/kubernetes/pkg/scheduler/framework/preemption"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+)
+
+// defaultPreemption is the subset of the default preemption plugin that we use,
+// to preempt for real, or only dry run and report the cost to the proxy scheduler
+type defaultPreemption interface {
+	framework.PostFilterPlugin
+	preemption.Interface
+}
+
+func newDefaultPreemption(ctx context.Context, h framework.Handle) (defaultPreemption, error) {
+	// defaults from k8s.io/kubernetes/pkg/scheduler/apis/config/v1
+	args := &config.DefaultPreemptionArgs{MinCandidateNodesPercentage: 10, MinCandidateNodesAbsolute: 100}
+	fts := feature.Features{
+		EnablePodDisruptionConditions: utilfeature.DefaultFeatureGate.Enabled(features.PodDisruptionConditions),
+	}
+	p, err := defaultpreemption.New(ctx, args, h, fts)
+	if err != nil {
+		return nil, err
+	}
+	return p.(defaultPreemption), nil
+}
+
+// PostFilter replaces the default preemption plugin (disabled in the candidate scheduler's profile):
+// until the proxy scheduler allows a candidate to preempt other pods, it only dry runs preemption,
+// and reports the cost on the candidate's pod chaperon, so the proxy scheduler can pick the cheapest target.
+func (pl *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, p *v1.Pod, m framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
+	c, err := pl.getPodChaperon(p.Namespace, p.Name)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	if c == nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "pod chaperon not found")
+	}
+	if _, ok := c.Annotations[common.AnnotationKeyIsAllowedToPreempt]; ok {
+		return pl.preemption.PostFilter(ctx, state, p, m)
+	}
+
+	cost, status := pl.dryRunPreemption(ctx, state, p, m)
+	if !status.IsSuccess() {
+		return nil, status
+	}
+	if cost == nil {
+		return nil, framework.NewStatus(framework.Unschedulable, "preemption wouldn't help")
+	}
+
+	b, err := json.Marshal(cost)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	// the annotation value is a JSON string within a JSON patch
+	v, err := json.Marshal(string(b))
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyPreemptionCost + `":` + string(v) + `}}}`)
+	if _, err := pl.client.MulticlusterV1alpha1().PodChaperons(p.Namespace).Patch(ctx, p.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	return nil, framework.NewStatus(framework.Unschedulable, fmt.Sprintf("could preempt %d pod(s) on node %s, waiting for proxy scheduler", cost.NumVictims, cost.NodeName))
+}
+
+// dryRunPreemption returns the cost of the best preemption candidate, or nil if preemption wouldn't help
+func (pl *Plugin) dryRunPreemption(ctx context.Context, state *framework.CycleState, p *v1.Pod, m framework.NodeToStatusMap) (*delegatepod.PreemptionCost, *framework.Status) {
+	if ok, msg := pl.preemption.PodEligibleToPreemptOthers(p, m[p.Status.NominatedNodeName]); !ok {
+		return nil, framework.NewStatus(framework.Unschedulable, msg)
+	}
+
+	nodeInfos, err := pl.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	var potentialNodes []*framework.NodeInfo
+	for _, nodeInfo := range nodeInfos {
+		// like the default preemption plugin, skip nodes where removing pods wouldn't help
+		if m[nodeInfo.Node().Name].Code() != framework.UnschedulableAndUnresolvable {
+			potentialNodes = append(potentialNodes, nodeInfo)
+		}
+	}
+	if len(potentialNodes) == 0 {
+		return nil, nil
+	}
+
+	pdbs, err := pl.pdbLister.List(labels.Everything())
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+
+	ev := preemption.Evaluator{
+		PluginName: Name,
+		Handler:    pl.handle,
+		PodLister:  pl.handle.SharedInformerFactory().Core().V1().Pods().Lister(),
+		PdbLister:  pl.pdbLister,
+		State:      state,
+		Interface:  pl.preemption,
+	}
+	offset, numCandidates := pl.preemption.GetOffsetAndNumCandidates(int32(len(potentialNodes)))
+	candidates, _, err := ev.DryRunPreemption(ctx, p, potentialNodes, pdbs, offset, numCandidates)
+	if err != nil && len(candidates) == 0 {
+		return nil, framework.AsStatus(err)
+	}
+	best := ev.SelectCandidate(ctx, candidates)
+	if best == nil || best.Name() == "" {
+		return nil, nil
+	}
+	cost := delegatepod.NewPreemptionCost(best.Name(), best.Victims().Pods, best.Victims().NumPDBViolations)
+	return &cost, nil
+}
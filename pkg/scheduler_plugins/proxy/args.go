@@ -0,0 +1,97 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// Args are the proxy plugin's arguments, in the pluginConfig section of the KubeSchedulerConfiguration.
+// They can be overridden per target, see v1alpha1.TargetTimeouts.
+type Args struct {
+	// FilterTimeout is how long to wait for a candidate to be reserved or found unschedulable. Defaults to 30s.
+	FilterTimeout *metav1.Duration `json:"filterTimeout,omitempty"`
+	// PreBindTimeout is how long to wait for the candidate to be bound.
+	// Defaults to 60s, increased from an arbitrary 30s, because Fargate takes 30-60 seconds.
+	PreBindTimeout *metav1.Duration `json:"preBindTimeout,omitempty"`
+	// PodGroupTimeout is how long members of a pod group wait for each other to be reserved. Defaults to 60s.
+	PodGroupTimeout *metav1.Duration `json:"podGroupTimeout,omitempty"`
+}
+
+const (
+	defaultFilterTimeout   = 30 * time.Second
+	defaultPreBindTimeout  = 60 * time.Second
+	defaultPodGroupTimeout = 60 * time.Second
+)
+
+func decodeArgs(obj runtime.Object) (Args, error) {
+	args := Args{}
+	if err := frameworkruntime.DecodeInto(obj, &args); err != nil {
+		return args, fmt.Errorf("invalid proxy plugin args: %v", err)
+	}
+	if args.FilterTimeout == nil {
+		args.FilterTimeout = &metav1.Duration{Duration: defaultFilterTimeout}
+	}
+	if args.PreBindTimeout == nil {
+		args.PreBindTimeout = &metav1.Duration{Duration: defaultPreBindTimeout}
+	}
+	if args.PodGroupTimeout == nil {
+		args.PodGroupTimeout = &metav1.Duration{Duration: defaultPodGroupTimeout}
+	}
+	if args.FilterTimeout.Duration <= 0 {
+		return args, fmt.Errorf("invalid proxy plugin args: filterTimeout must be positive")
+	}
+	if args.PreBindTimeout.Duration <= 0 {
+		return args, fmt.Errorf("invalid proxy plugin args: preBindTimeout must be positive")
+	}
+	if args.PodGroupTimeout.Duration <= 0 {
+		return args, fmt.Errorf("invalid proxy plugin args: podGroupTimeout must be positive")
+	}
+	return args, nil
+}
+
+func (pl *Plugin) filterTimeout(t *target) time.Duration {
+	if o := t.timeouts; o != nil && o.Filter != nil {
+		return o.Filter.Duration
+	}
+	return pl.args.FilterTimeout.Duration
+}
+
+func (pl *Plugin) preBindTimeout(t *target) time.Duration {
+	if o := t.timeouts; o != nil && o.PreBind != nil {
+		return o.PreBind.Duration
+	}
+	return pl.args.PreBindTimeout.Duration
+}
+
+// setPermitTimeout tells the candidate scheduler to override its permit timeout, if the target says so,
+// or if the candidate is a pod group member, which may wait for the rest of the group before being allowed
+func (pl *Plugin) setPermitTimeout(c *v1alpha1.PodChaperon, t *target, g *podGroup) {
+	if o := t.timeouts; o != nil && o.Permit != nil {
+		c.Annotations[common.AnnotationKeyPermitTimeout] = o.Permit.Duration.String()
+	} else if g != nil {
+		c.Annotations[common.AnnotationKeyPermitTimeout] = (pl.filterTimeout(t) + pl.args.PodGroupTimeout.Duration).String()
+	}
+}
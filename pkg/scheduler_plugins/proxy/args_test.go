@@ -0,0 +1,56 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestTimeouts(t *testing.T) {
+	args, err := decodeArgs(nil)
+	require.NoError(t, err)
+	require.Equal(t, defaultFilterTimeout, args.FilterTimeout.Duration)
+	require.Equal(t, defaultPreBindTimeout, args.PreBindTimeout.Duration)
+	require.Equal(t, defaultPodGroupTimeout, args.PodGroupTimeout.Duration)
+
+	args, err = decodeArgs(&runtime.Unknown{Raw: []byte(`{"filterTimeout":"2m"}`)})
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, args.FilterTimeout.Duration)
+	require.Equal(t, defaultPreBindTimeout, args.PreBindTimeout.Duration)
+
+	_, err = decodeArgs(&runtime.Unknown{Raw: []byte(`{"preBindTimeout":"-1s"}`)})
+	require.Error(t, err)
+
+	pl := &Plugin{args: args}
+	tg := &target{timeouts: &v1alpha1.TargetTimeouts{PreBind: &metav1.Duration{Duration: 5 * time.Minute}}}
+	require.Equal(t, 2*time.Minute, pl.filterTimeout(tg))
+	require.Equal(t, 5*time.Minute, pl.preBindTimeout(tg))
+
+	c := &v1alpha1.PodChaperon{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+	pl.setPermitTimeout(c, tg, nil)
+	require.NotContains(t, c.Annotations, common.AnnotationKeyPermitTimeout)
+	pl.setPermitTimeout(c, tg, &podGroup{})
+	require.Equal(t, "3m0s", c.Annotations[common.AnnotationKeyPermitTimeout])
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/controller"
+	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
+	informers "admiralty.io/multicluster-scheduler/pkg/generated/informers/externalversions"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+)
+
+const podChaperonByParentUID = "podChaperonByParentUID"
+
+func indexPodChaperonByParentUID(obj interface{}) ([]string, error) {
+	c, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, nil
+	}
+	uid, ok := c.GetLabels()[common.LabelKeyParentUID]
+	if !ok {
+		return nil, nil
+	}
+	return []string{uid}, nil
+}
+
+// target caches the pod chaperons of a target cluster, to avoid listing them once per second per pending pod
+type target struct {
+	client    versioned.Interface
+	namespace string
+	timeouts  *v1alpha1.TargetTimeouts
+	weight    *int32
+	priority  *int32
+	tier      int32
+
+	priorityClassMap map[string]string
+	transformer      delegatepod.Pipeline
+	// transformerErr is set if the target's delegate pod transformations don't compile in the scheduler,
+	// e.g., if a custom transformer is compiled into the agent (whose webhook validated the target) but not the scheduler
+	transformerErr error
+
+	podChaperonInformer cache.SharedIndexInformer
+
+	cancel context.CancelFunc
+}
+
+func startTarget(ctx context.Context, client versioned.Interface, t agentconfig.Target, w *waiters) (*target, error) {
+	// keep the target, so that Filter explains why pods can't be scheduled to it, instead of silently ignoring it
+	transformer, transformerErr := delegatepod.NewPipeline(t.DelegatePodTransformations)
+	if transformerErr != nil {
+		utilruntime.HandleError(fmt.Errorf("target %s: %v", t.VirtualNodeName, transformerErr))
+	}
+
+	f := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithNamespace(t.Namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = common.LabelKeyParentUID
+		}))
+	informer := f.Multicluster().V1alpha1().PodChaperons().Informer()
+	if err := informer.AddIndexers(map[string]cache.IndexFunc{
+		podChaperonByParentUID: indexPodChaperonByParentUID,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := informer.AddEventHandler(controller.HandleAllWith(func(obj interface{}) {
+		if c, ok := obj.(metav1.Object); ok {
+			w.notify(types.UID(c.GetLabels()[common.LabelKeyParentUID]))
+		}
+	})); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	f.Start(ctx.Done())
+
+	return &target{
+		client:              client,
+		namespace:           t.Namespace,
+		timeouts:            t.Timeouts,
+		weight:              t.Weight,
+		priority:            t.Priority,
+		tier:                t.GetTier(),
+		priorityClassMap:    t.PriorityClassMap,
+		transformer:         transformer,
+		transformerErr:      transformerErr,
+		podChaperonInformer: informer,
+		cancel:              cancel,
+	}, nil
+}
+
+// accepts returns true if pods in the namespace can be scheduled to the target,
+// i.e., if it is a cluster target or a target in the same namespace
+func (t *target) accepts(namespace string) bool {
+	return t.namespace == "" || t.namespace == namespace
+}
+
+func (t *target) stop() {
+	t.cancel()
+}
+
+// waitForCacheSync returns false if the context is done before the cache has synced
+func (t *target) waitForCacheSync(ctx context.Context) bool {
+	return cache.WaitForCacheSync(ctx.Done(), t.podChaperonInformer.HasSynced)
+}
+
+// getCandidate returns the cached candidate of a proxy pod, or nil if it doesn't exist (yet)
+func (t *target) getCandidate(proxyPodUID types.UID) (*v1alpha1.PodChaperon, error) {
+	objs, err := t.podChaperonInformer.GetIndexer().ByIndex(podChaperonByParentUID, string(proxyPodUID))
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) > 1 {
+		return nil, fmt.Errorf("more than one candidate in target cluster")
+	}
+	if len(objs) < 1 {
+		return nil, nil
+	}
+	return objs[0].(*v1alpha1.PodChaperon), nil
+}
+
+// waiters wakes up scheduling and binding cycles waiting for candidates of a given proxy pod to change
+type waiters struct {
+	mx         sync.Mutex
+	chansByUID map[types.UID]map[chan struct{}]struct{}
+}
+
+func newWaiters() *waiters {
+	return &waiters{chansByUID: map[types.UID]map[chan struct{}]struct{}{}}
+}
+
+// subscribe returns a channel that receives a value when a candidate of the proxy pod is added, updated or deleted,
+// and a function to call when done waiting.
+func (w *waiters) subscribe(proxyPodUID types.UID) (<-chan struct{}, func()) {
+	// buffered so notify doesn't block and changes between two receives aren't lost
+	ch := make(chan struct{}, 1)
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if w.chansByUID[proxyPodUID] == nil {
+		w.chansByUID[proxyPodUID] = map[chan struct{}]struct{}{}
+	}
+	w.chansByUID[proxyPodUID][ch] = struct{}{}
+
+	return ch, func() {
+		w.mx.Lock()
+		defer w.mx.Unlock()
+		delete(w.chansByUID[proxyPodUID], ch)
+		if len(w.chansByUID[proxyPodUID]) == 0 {
+			delete(w.chansByUID, proxyPodUID)
+		}
+	}
+}
+
+func (w *waiters) notify(proxyPodUID types.UID) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	for ch := range w.chansByUID[proxyPodUID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// waitForCandidate calls condition every time a candidate of the proxy pod changes in the target cluster,
+// until condition returns true or an error, or the context is done.
+func (w *waiters) waitForCandidate(ctx context.Context, t *target, proxyPodUID types.UID, condition func(c *v1alpha1.PodChaperon) (bool, error)) error {
+	// subscribe before checking the cache, so we don't miss changes in between
+	ch, unsubscribe := w.subscribe(proxyPodUID)
+	defer unsubscribe()
+
+	if !t.waitForCacheSync(ctx) {
+		return fmt.Errorf("pod chaperon cache not synced: %v", ctx.Err())
+	}
+
+	for {
+		c, err := t.getCandidate(proxyPodUID)
+		if err != nil {
+			return err
+		}
+		done, err := condition(c)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
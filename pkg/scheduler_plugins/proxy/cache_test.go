@@ -0,0 +1,65 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/fake"
+)
+
+func TestWaitForCandidate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := fake.NewSimpleClientset()
+	w := newWaiters()
+	target, err := startTarget(ctx, client, agentconfig.Target{Namespace: "default"}, w)
+	require.NoError(t, err)
+	defer target.stop()
+
+	c := &v1alpha1.PodChaperon{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "a",
+			Labels:    map[string]string{common.LabelKeyParentUID: "uid"},
+		},
+	}
+
+	go func() {
+		if _, err := client.MulticlusterV1alpha1().PodChaperons("default").Create(ctx, c, metav1.CreateOptions{}); err != nil {
+			t.Error(err)
+			return
+		}
+		c.Status.Conditions = []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}}
+		if _, err := client.MulticlusterV1alpha1().PodChaperons("default").UpdateStatus(ctx, c, metav1.UpdateOptions{}); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	require.NoError(t, w.waitForCandidate(ctx, target, "uid", candidateIsBound))
+	require.Empty(t, w.chansByUID)
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	schedutil "k8s.io/kubernetes/pkg/scheduler/util"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// explain summarizes why each target rejected a pod, one line per target, sorted by target name
+func (pl *Plugin) explain(p *v1.Pod, m framework.NodeToStatusMap) string {
+	pl.targetsMx.RLock()
+	defer pl.targetsMx.RUnlock()
+	var lines []string
+	for nodeName, status := range m {
+		t, ok := pl.targets[virtualNodeNameToClusterName(nodeName)]
+		if !ok || !t.accepts(p.Namespace) {
+			continue
+		}
+		msg := status.Message()
+		if msg == "" {
+			msg = status.Code().String()
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", virtualNodeNameToClusterName(nodeName), msg))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// setCandidatesScheduledCondition reports why each target rejected a proxy pod in a pod condition,
+// which, unlike the FailedScheduling event, doesn't aggregate identical reasons
+func (pl *Plugin) setCandidatesScheduledCondition(ctx context.Context, p *v1.Pod, status v1.ConditionStatus, reason, message string) {
+	cond := &v1.PodCondition{
+		Type:               v1.PodConditionType(common.PodConditionTypeCandidatesScheduled),
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+	newStatus := p.Status.DeepCopy()
+	if !podutil.UpdatePodCondition(newStatus, cond) {
+		return
+	}
+	if err := schedutil.PatchPodStatus(ctx, pl.handle.ClientSet(), p, newStatus); err != nil {
+		utilruntime.HandleError(fmt.Errorf("cannot set %s condition of pod %s/%s: %v", common.PodConditionTypeCandidatesScheduled, p.Namespace, p.Name, err))
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestExplain(t *testing.T) {
+	pl := &Plugin{targets: map[string]*target{
+		"a":     {namespace: "ns"},
+		"b":     {},
+		"other": {namespace: "other"},
+	}}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns"}}
+	m := framework.NodeToStatusMap{
+		"b":     framework.NewStatus(framework.UnschedulableAndUnresolvable, "candidate unschedulable in target b: 0/12 nodes are available: 12 Insufficient nvidia.com/gpu."),
+		"a":     framework.NewStatus(framework.UnschedulableAndUnresolvable, "unreserved in a previous cycle"),
+		"other": framework.NewStatus(framework.UnschedulableAndUnresolvable, "target in different namespace"),
+		"node":  framework.NewStatus(framework.UnschedulableAndUnresolvable),
+	}
+	require.Equal(t, "a: unreserved in a previous cycle\nb: candidate unschedulable in target b: 0/12 nodes are available: 12 Insufficient nvidia.com/gpu.", pl.explain(pod, m))
+}
@@ -28,32 +28,45 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
+	podutil "k8s.io/kubernetes/pkg/api/v1/pod"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
 	"admiralty.io/multicluster-scheduler/pkg/common"
 	agentconfig "admiralty.io/multicluster-scheduler/pkg/config/agent"
+	targetcontroller "admiralty.io/multicluster-scheduler/pkg/controllers/target"
 	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned"
-	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
 )
 
 type Plugin struct {
-	handle           framework.Handle
-	clusterName      string
-	targets          map[string]*versioned.Clientset
-	targetNamespaces map[string]string
+	ctx         context.Context
+	handle      framework.Handle
+	clusterName string
+	args        Args
+
+	// targets are updated at runtime by the target controller
+	targets   map[string]*target
+	targetsMx sync.RWMutex
+
+	waiters *waiters
 
 	failedNodeNamesByPodUID map[types.UID]map[string]bool
+	allowedTierByPodUID     map[types.UID]int32
 	mx                      sync.RWMutex
 }
 
+var _ framework.PreFilterPlugin = &Plugin{}
 var _ framework.FilterPlugin = &Plugin{}
 var _ framework.PostFilterPlugin = &Plugin{}
+var _ framework.ScorePlugin = &Plugin{}
 var _ framework.ReservePlugin = &Plugin{}
+var _ framework.PermitPlugin = &Plugin{}
 var _ framework.PreBindPlugin = &Plugin{}
 var _ framework.PostBindPlugin = &Plugin{}
+var _ agentconfig.TargetHandler = &Plugin{}
 
 // Name is the name of the plugin used in the plugin registry and configurations.
 const Name = "proxy"
@@ -67,36 +80,50 @@ func virtualNodeNameToClusterName(nodeName string) string {
 	return nodeName
 }
 
-func (pl *Plugin) getCandidate(ctx context.Context, proxyPod *v1.Pod, clusterName string) (*v1alpha1.PodChaperon, error) {
-	target, ok := pl.targets[clusterName]
-	if !ok {
-		return nil, fmt.Errorf("no target for cluster name %s", clusterName)
+func (pl *Plugin) AddTarget(t agentconfig.Target) {
+	client, err := versioned.NewForConfig(t.ClientConfig)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("cannot create client for target %s: %v", t.VirtualNodeName, err))
+		return
 	}
-	l, err := target.MulticlusterV1alpha1().PodChaperons(proxyPod.Namespace).List(ctx, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(proxyPod.UID)})
+	target, err := startTarget(pl.ctx, client, t, pl.waiters)
 	if err != nil {
-		return nil, err
+		utilruntime.HandleError(fmt.Errorf("cannot start target %s: %v", t.VirtualNodeName, err))
+		return
 	}
-	if len(l.Items) > 1 {
-		return nil, fmt.Errorf("more than one candidate in target cluster")
+	pl.targetsMx.Lock()
+	defer pl.targetsMx.Unlock()
+	if old, ok := pl.targets[t.VirtualNodeName]; ok {
+		old.stop()
 	}
-	if len(l.Items) < 1 {
-		return nil, nil
+	pl.targets[t.VirtualNodeName] = target
+}
+
+func (pl *Plugin) RemoveTarget(t agentconfig.Target) {
+	pl.targetsMx.Lock()
+	defer pl.targetsMx.Unlock()
+	if target, ok := pl.targets[t.VirtualNodeName]; ok {
+		target.stop()
+		delete(pl.targets, t.VirtualNodeName)
 	}
-	return &l.Items[0], nil
 }
 
-func (pl *Plugin) allowCandidate(ctx context.Context, c *v1alpha1.PodChaperon, clusterName string) error {
+func (pl *Plugin) getTarget(clusterName string) (*target, error) {
+	pl.targetsMx.RLock()
+	defer pl.targetsMx.RUnlock()
 	target, ok := pl.targets[clusterName]
 	if !ok {
-		return fmt.Errorf("no target for cluster name %s", clusterName)
+		return nil, fmt.Errorf("no target for cluster name %s", clusterName)
 	}
+	return target, nil
+}
+
+func (pl *Plugin) allowCandidate(ctx context.Context, c *v1alpha1.PodChaperon, target *target) error {
 	patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyIsAllowed + `":"true"}}}`)
-	_, err := target.MulticlusterV1alpha1().PodChaperons(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	_, err := target.client.MulticlusterV1alpha1().PodChaperons(c.Namespace).Patch(ctx, c.Name, types.MergePatchType, patch, metav1.PatchOptions{})
 	return err
 }
 
-const filterWaitDuration = 30 * time.Second // TODO configure
-
 func (pl *Plugin) Filter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, nodeInfo *framework.NodeInfo) *framework.Status {
 	if nodeInfo.Node().Labels[common.LabelAndTaintKeyVirtualKubeletProvider] != common.VirtualKubeletProviderName {
 		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "")
@@ -110,56 +137,91 @@ func (pl *Plugin) Filter(ctx context.Context, state *framework.CycleState, pod *
 		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "target in different namespace")
 	}
 
+	targetClusterName := virtualNodeNameToClusterName(nodeInfo.Node().Name)
+	target, err := pl.getTarget(targetClusterName)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+
+	// only consider targets of higher tiers when all targets of lower tiers are unschedulable (see PostFilter)
+	if allowedTier := pl.allowedTier(pod); target.tier > allowedTier {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("target in tier %d, waiting for targets in tier %d or lower to be unschedulable", target.tier, allowedTier))
+	}
+
+	if target.transformerErr != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("cannot transform delegate pods for target %s: %v", targetClusterName, target.transformerErr))
+	}
+
 	// working without a candidate scheduler, we'll create a single candidate AFTER a virtual node is selected
 	if _, ok := pod.Annotations[common.AnnotationKeyNoReservation]; ok {
 		return nil
 	}
 
-	targetClusterName := virtualNodeNameToClusterName(nodeInfo.Node().Name)
+	g, err := getPodGroup(pod)
+	if err != nil {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, filterWaitDuration)
+	ctx, cancel := context.WithTimeout(ctx, pl.filterTimeout(target))
 	defer cancel()
 
-	var isReserved, isUnschedulable bool
+	var isReserved, isUnschedulable, isAllowedToPreempt bool
+	var last *v1alpha1.PodChaperon // to explain timeouts
 
-	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
-		c, err := pl.getCandidate(ctx, pod, targetClusterName)
-		if err != nil {
-			// may be forbidden, or namespace doesn't exist, or target cluster is unavailable
-			// handled below as unschedulable
-			return false, err
-		}
+	start := time.Now()
+
+	// the cache may not include the candidate right after we create it,
+	// so we remember not to create another one
+	created := false
+
+	if err := pl.waiters.waitForCandidate(ctx, target, pod.UID, func(c *v1alpha1.PodChaperon) (bool, error) {
 		// create candidate if not exists
 		if c == nil {
-			c, err := delegatepod.MakeDelegatePod(pod, pl.clusterName)
+			if created {
+				return false, nil
+			}
+
+			c, err := pl.makeCandidate(pod, target, g)
 			if err != nil {
 				return false, err
 			}
 
-			_, err = pl.targets[targetClusterName].MulticlusterV1alpha1().PodChaperons(c.Namespace).Create(ctx, c, metav1.CreateOptions{})
+			_, err = target.client.MulticlusterV1alpha1().PodChaperons(c.Namespace).Create(ctx, c, metav1.CreateOptions{})
 			if err != nil {
 				// may be forbidden, or namespace doesn't exist, or target cluster is unavailable
 				// handled below as unschedulable
 				return false, err
 			}
+			created = true
 
 			return false, nil
 		}
+		last = c
 		_, isReserved = c.Annotations[common.AnnotationKeyIsReserved]
 		isUnschedulable = isCandidatePodUnschedulable(c)
+		// a candidate allowed to preempt is still unschedulable until victims are gone, keep waiting
+		_, isAllowedToPreempt = c.Annotations[common.AnnotationKeyIsAllowedToPreempt]
 
-		klog.V(1).Infof("candidate %s is reserved? %v unschedulable? %v", c.Name, isReserved, isUnschedulable)
+		klog.V(1).Infof("candidate %s is reserved? %v unschedulable? %v allowed to preempt? %v", c.Name, isReserved, isUnschedulable, isAllowedToPreempt)
 
-		return isReserved || isUnschedulable, nil
-	}, ctx.Done()); err != nil {
+		return isReserved || isUnschedulable && !isAllowedToPreempt, nil
+	}); err != nil {
 		// error or timeout or scheduling cycle done
-		return framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+		msg := fmt.Sprintf("waiting for candidate in target %s: %v", targetClusterName, err)
+		if last == nil {
+			msg += " (candidate not observed yet)"
+		} else if m := candidateSchedulingMessage(last); m != "" {
+			msg += fmt.Sprintf(" (last scheduling message: %s)", m)
+		}
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, msg)
 	}
 
-	if isUnschedulable {
-		return framework.NewStatus(framework.UnschedulableAndUnresolvable, "")
+	if !isReserved {
+		return framework.NewStatus(framework.UnschedulableAndUnresolvable, fmt.Sprintf("candidate unschedulable in target %s: %s", targetClusterName, candidateSchedulingMessage(last)))
 	}
 
+	state.Write(reservedAfterKey(nodeInfo.Node().Name), reservedAfter(time.Since(start)))
+
 	return nil
 }
 
@@ -170,24 +232,60 @@ func (pl *Plugin) unreservedInAPreviousCycle(podUID types.UID, nodeName string)
 }
 
 func (pl *Plugin) PostFilter(ctx context.Context, state *framework.CycleState, pod *v1.Pod, filteredNodeStatusMap framework.NodeToStatusMap) (*framework.PostFilterResult, *framework.Status) {
-	pl.failedNodeNamesByPodUID[pod.UID] = nil
+	pl.setCandidatesScheduledCondition(ctx, pod, v1.ConditionFalse, v1.PodReasonUnschedulable, pl.explain(pod, filteredNodeStatusMap))
+
+	// all targets up to the allowed tier are unschedulable, try the next tier in the next cycle,
+	// or, if there's none, try to preempt lower-priority pods in the cheapest target,
+	// or, if that wouldn't help, start over from the lowest tier with a clean slate
+	if pl.escalateTier(pod) {
+		return nil, framework.NewStatus(framework.Unschedulable)
+	}
+
+	if result, status := pl.preempt(ctx, pod); result != nil || !status.IsSuccess() {
+		return result, status
+	}
+
+	pl.mx.Lock()
+	defer pl.mx.Unlock()
+	delete(pl.failedNodeNamesByPodUID, pod.UID)
+	delete(pl.allowedTierByPodUID, pod.UID)
 	return nil, framework.NewStatus(framework.Unschedulable)
 }
 
 func (pl *Plugin) Reserve(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
+	g, err := getPodGroup(p)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if g != nil {
+		// pod group members' candidates are released in PreBind, once the whole group is permitted
+		return nil
+	}
+	return pl.release(ctx, p, nodeName, nil)
+}
+
+// release allows the candidate in the selected target to be bound, or, with no reservation, creates it
+func (pl *Plugin) release(ctx context.Context, p *v1.Pod, nodeName string, g *podGroup) *framework.Status {
 	targetClusterName := virtualNodeNameToClusterName(nodeName)
-	c, err := pl.getCandidate(ctx, p, targetClusterName)
+	target, err := pl.getTarget(targetClusterName)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
+	if !target.waitForCacheSync(ctx) {
+		return framework.NewStatus(framework.Error, "pod chaperon cache not synced")
+	}
+	c, err := target.getCandidate(p.UID)
 	if err != nil {
 		return framework.NewStatus(framework.Error, err.Error())
 	}
 	if c == nil {
 		if _, ok := p.Annotations[common.AnnotationKeyNoReservation]; ok {
-			c, err := delegatepod.MakeDelegatePod(p, pl.clusterName)
+			c, err := pl.makeCandidate(p, target, g)
 			if err != nil {
 				return framework.NewStatus(framework.Error, err.Error())
 			}
 
-			_, err = pl.targets[targetClusterName].MulticlusterV1alpha1().PodChaperons(c.Namespace).Create(ctx, c, metav1.CreateOptions{})
+			_, err = target.client.MulticlusterV1alpha1().PodChaperons(c.Namespace).Create(ctx, c, metav1.CreateOptions{})
 			if err != nil {
 				// may be forbidden, or namespace doesn't exist, or target cluster is unavailable
 				return framework.NewStatus(framework.Error, err.Error())
@@ -197,7 +295,7 @@ func (pl *Plugin) Reserve(ctx context.Context, state *framework.CycleState, p *v
 		}
 		return framework.NewStatus(framework.Error, "candidate not found")
 	}
-	if err = pl.allowCandidate(ctx, c, targetClusterName); err != nil {
+	if err = pl.allowCandidate(ctx, c, target); err != nil {
 		return framework.NewStatus(framework.Error, err.Error())
 	}
 
@@ -209,27 +307,39 @@ func (pl *Plugin) Unreserve(ctx context.Context, state *framework.CycleState, p
 	// unless we've filtered out all other nodes already
 	// in which case we reset the memory and try again to see if things have changed
 	pl.mx.Lock()
-	defer pl.mx.Unlock()
-
 	if pl.failedNodeNamesByPodUID[p.UID] == nil {
 		pl.failedNodeNamesByPodUID[p.UID] = map[string]bool{}
 	}
 	pl.failedNodeNamesByPodUID[p.UID][nodeName] = true
-}
+	pl.mx.Unlock()
 
-const preBindWaitDuration = 60 * time.Second // increased from arbitrary 30 seconds, because Fargate takes 30-60 seconds
+	if g, err := getPodGroup(p); err == nil && g != nil {
+		pl.releaseGroup(ctx, g, p, nodeName)
+	}
+}
 
 func (pl *Plugin) PreBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) *framework.Status {
 	// wait for candidate to be bound or not
 	targetClusterName := virtualNodeNameToClusterName(nodeName)
+	target, err := pl.getTarget(targetClusterName)
+	if err != nil {
+		return framework.NewStatus(framework.Error, err.Error())
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, preBindWaitDuration)
+	ctx, cancel := context.WithTimeout(ctx, pl.preBindTimeout(target))
 	defer cancel()
 
-	// TODO subscribe to a controller instead of polling
-	if err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
-		return pl.candidateIsBound(ctx, p, targetClusterName)
-	}, ctx.Done()); err != nil {
+	g, err := getPodGroup(p)
+	if err != nil {
+		return framework.AsStatus(err)
+	}
+	if g != nil {
+		if status := pl.release(ctx, p, nodeName, g); !status.IsSuccess() {
+			return status
+		}
+	}
+
+	if err := pl.waiters.waitForCandidate(ctx, target, p.UID, candidateIsBound); err != nil {
 		// or binding cycle done, candidate was never bound or not
 		return framework.NewStatus(framework.Error, err.Error())
 	}
@@ -237,15 +347,11 @@ func (pl *Plugin) PreBind(ctx context.Context, state *framework.CycleState, p *v
 	return nil
 }
 
-func (pl *Plugin) candidateIsBound(ctx context.Context, p *v1.Pod, targetClusterName string) (bool, error) {
-	c, err := pl.getCandidate(ctx, p, targetClusterName)
-	if err != nil {
-		// TODO handle retriable vs. not retriable (we assume retriable for now)
-		// TODO log
-		return false, nil
-	}
+func candidateIsBound(c *v1alpha1.PodChaperon) (bool, error) {
 	if c == nil {
-		return false, fmt.Errorf("candidate not found")
+		// with no reservation, the candidate may have just been created in Reserve and not be cached yet;
+		// if it was deleted, we'll time out
+		return false, nil
 	}
 
 	for _, cond := range c.Status.Conditions {
@@ -261,44 +367,99 @@ func (pl *Plugin) candidateIsBound(ctx context.Context, p *v1.Pod, targetCluster
 }
 
 func (pl *Plugin) PostBind(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) {
-	targetClusterName := virtualNodeNameToClusterName(nodeName)
+	if _, cond := podutil.GetPodConditionFromList(p.Status.Conditions, v1.PodConditionType(common.PodConditionTypeCandidatesScheduled)); cond != nil {
+		pl.setCandidatesScheduledCondition(ctx, p, v1.ConditionTrue, "Scheduled", "")
+	}
+	pl.deleteCandidates(ctx, p, virtualNodeNameToClusterName(nodeName))
+	pl.forget(p.UID)
+}
+
+// deleteCandidates deletes the candidates of a proxy pod in all targets, except the one it's scheduled to, if any
+func (pl *Plugin) deleteCandidates(ctx context.Context, p *v1.Pod, exceptClusterName string) {
+	pl.targetsMx.RLock()
+	targets := make(map[string]*target, len(pl.targets))
 	for clusterName, target := range pl.targets {
-		if clusterName == targetClusterName {
+		if clusterName == exceptClusterName || !target.accepts(p.Namespace) {
 			continue
 		}
-		if ns := pl.targetNamespaces[clusterName]; ns != "" && ns != p.Namespace {
-			continue
-		}
-		err := target.MulticlusterV1alpha1().PodChaperons(p.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(p.UID)})
+		targets[clusterName] = target
+	}
+	pl.targetsMx.RUnlock()
+	for _, target := range targets {
+		err := target.client.MulticlusterV1alpha1().PodChaperons(p.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(p.UID)})
 		utilruntime.HandleError(err)
 	}
+}
 
+// deleteCandidate deletes the candidate of a proxy pod in a single target, e.g., to release its reservation
+func (pl *Plugin) deleteCandidate(ctx context.Context, p *v1.Pod, nodeName string) {
+	target, err := pl.getTarget(virtualNodeNameToClusterName(nodeName))
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	err = target.client.MulticlusterV1alpha1().PodChaperons(p.Namespace).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(p.UID)})
+	utilruntime.HandleError(err)
+}
+
+// forget purges the state kept across scheduling cycles for a proxy pod
+func (pl *Plugin) forget(podUID types.UID) {
 	pl.mx.Lock()
 	defer pl.mx.Unlock()
-	delete(pl.failedNodeNamesByPodUID, p.UID)
-	// TODO if a proxy pod is deleted while pending, with failed node names, PostBind won't be called,
-	// so we're leaking memory, but there's no multi-cycle "FinalUnreserve" plugin, we'd have to listen to deletions...
+	delete(pl.failedNodeNamesByPodUID, podUID)
+	delete(pl.allowedTierByPodUID, podUID)
+}
+
+// handlePodDeletion cleans up after pending proxy pods, for which PostBind won't be called
+// (there's no multi-cycle "FinalUnreserve" plugin). Bound proxy pods' candidates are cleaned up by the feedback controller.
+func (pl *Plugin) handlePodDeletion(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	p, ok := obj.(*v1.Pod)
+	if !ok || !proxypod.IsProxy(p) || p.Spec.NodeName != "" {
+		return
+	}
+	pl.forget(p.UID)
+	// don't block the informer with calls to target clusters
+	go pl.deleteCandidates(pl.ctx, p, "")
 }
 
 // New initializes a new plugin and returns it.
-func New(ctx context.Context, _ runtime.Object, h framework.Handle) (framework.Plugin, error) {
-	agentCfg := agentconfig.NewFromCRD(context.Background())
-	n := len(agentCfg.Targets)
-	targets := make(map[string]*versioned.Clientset, n)
-	targetNamespaces := make(map[string]string, n)
-	for _, target := range agentCfg.Targets {
-		client, err := versioned.NewForConfig(target.ClientConfig)
-		utilruntime.Must(err)
-		targets[target.VirtualNodeName] = client
-		targetNamespaces[target.VirtualNodeName] = target.Namespace
-	}
-	// TODO... cache podchaperons with lister
-
-	return &Plugin{
+func New(ctx context.Context, obj runtime.Object, h framework.Handle) (framework.Plugin, error) {
+	args, err := decodeArgs(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	pl := &Plugin{
+		ctx:                     ctx,
 		handle:                  h,
 		clusterName:             os.Getenv("CLUSTER_NAME"),
-		targets:                 targets,
-		targetNamespaces:        targetNamespaces,
+		args:                    args,
+		targets:                 map[string]*target{},
+		waiters:                 newWaiters(),
 		failedNodeNamesByPodUID: map[types.UID]map[string]bool{},
-	}, nil
+		allowedTierByPodUID:     map[types.UID]int32{},
+	}
+
+	if _, err := h.SharedInformerFactory().Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: pl.handlePodDeletion,
+	}); err != nil {
+		return nil, err
+	}
+
+	customClient, err := versioned.NewForConfig(h.KubeConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	targetSet := agentconfig.NewTargetSet()
+	targetSet.AddHandler(pl)
+	targetcontroller.Watch(ctx, h.ClientSet(), customClient, targetSet)
+	if !targetSet.WaitForSync(ctx) {
+		return nil, fmt.Errorf("failed to wait for targets to sync")
+	}
+
+	return pl, nil
 }
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestHandlePodDeletion(t *testing.T) {
+	pl := &Plugin{
+		ctx:                     context.Background(),
+		targets:                 map[string]*target{},
+		failedNodeNamesByPodUID: map[types.UID]map[string]bool{},
+		allowedTierByPodUID:     map[types.UID]int32{},
+	}
+	remember := func(uid types.UID) {
+		pl.failedNodeNamesByPodUID[uid] = map[string]bool{"a": true}
+		pl.allowedTierByPodUID[uid] = 1
+	}
+
+	pending := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "pending"}, Spec: v1.PodSpec{SchedulerName: common.ProxySchedulerName}}
+	bound := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "bound"}, Spec: v1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: "a"}}
+	other := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "other"}}
+	tombstoned := &v1.Pod{ObjectMeta: metav1.ObjectMeta{UID: "tombstoned"}, Spec: v1.PodSpec{SchedulerName: common.ProxySchedulerName}}
+	for _, p := range []*v1.Pod{pending, bound, other, tombstoned} {
+		remember(p.UID)
+	}
+
+	pl.handlePodDeletion(pending)
+	pl.handlePodDeletion(bound)
+	pl.handlePodDeletion(other)
+	pl.handlePodDeletion(cache.DeletedFinalStateUnknown{Key: "tombstoned", Obj: tombstoned})
+
+	require.NotContains(t, pl.failedNodeNamesByPodUID, pending.UID)
+	require.NotContains(t, pl.allowedTierByPodUID, pending.UID)
+	require.NotContains(t, pl.failedNodeNamesByPodUID, tombstoned.UID)
+	require.Contains(t, pl.failedNodeNamesByPodUID, bound.UID)
+	require.Contains(t, pl.failedNodeNamesByPodUID, other.UID)
+}
+
+func TestFilterInvalidTransformations(t *testing.T) {
+	pl := &Plugin{
+		ctx: context.Background(),
+		targets: map[string]*target{"a": {
+			transformerErr: errors.New("custom transformer fargate not registered"),
+		}},
+		failedNodeNamesByPodUID: map[types.UID]map[string]bool{},
+		allowedTierByPodUID:     map[types.UID]int32{},
+	}
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{
+		common.LabelAndTaintKeyVirtualKubeletProvider: common.VirtualKubeletProviderName,
+	}}}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", UID: "uid"}}
+
+	s := pl.Filter(context.Background(), framework.NewCycleState(), pod, nodeInfo)
+	require.Equal(t, framework.UnschedulableAndUnresolvable, s.Code())
+	require.Contains(t, s.Message(), "custom transformer fargate not registered")
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// podGroup is a set of proxy pods that must be scheduled all or nothing, see common.AnnotationKeyPodGroup
+type podGroup struct {
+	namespace     string
+	name          string
+	minMember     int
+	acrossTargets bool
+}
+
+// getPodGroup returns nil if the pod isn't a pod group member
+func getPodGroup(p *v1.Pod) (*podGroup, error) {
+	name, ok := p.Annotations[common.AnnotationKeyPodGroup]
+	if !ok {
+		return nil, nil
+	}
+	g := &podGroup{namespace: p.Namespace, name: name, minMember: 1}
+	// malformed annotations make the pod unschedulable (see PreFilter), rather than silently ignoring the group
+	if s, ok := p.Annotations[common.AnnotationKeyPodGroupMinMember]; ok {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid pod group min member %q", s)
+		}
+		g.minMember = n
+	}
+	if s, ok := p.Annotations[common.AnnotationKeyPodGroupAcrossTargets]; ok {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod group across targets %q", s)
+		}
+		g.acrossTargets = b
+	}
+	return g, nil
+}
+
+func (g *podGroup) contains(p *v1.Pod) bool {
+	name, ok := p.Annotations[common.AnnotationKeyPodGroup]
+	return ok && name == g.name && p.Namespace == g.namespace
+}
+
+// placedMembers returns the names of the virtual nodes where other members of the group are assumed
+// (waiting in Permit, or binding) or bound, with their respective numbers of members
+func (pl *Plugin) placedMembers(g *podGroup, p *v1.Pod) (map[string]int, error) {
+	nodeInfos, err := pl.handle.SnapshotSharedLister().NodeInfos().List()
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, nodeInfo := range nodeInfos {
+		if nodeInfo.Node().Labels[common.LabelAndTaintKeyVirtualKubeletProvider] != common.VirtualKubeletProviderName {
+			continue
+		}
+		for _, podInfo := range nodeInfo.Pods {
+			member := podInfo.Pod
+			if member.UID == p.UID || !g.contains(member) || member.DeletionTimestamp != nil ||
+				member.Status.Phase == v1.PodSucceeded || member.Status.Phase == v1.PodFailed {
+				continue
+			}
+			counts[nodeInfo.Node().Name]++
+		}
+	}
+	return counts, nil
+}
+
+func (pl *Plugin) PreFilter(ctx context.Context, state *framework.CycleState, p *v1.Pod) (*framework.PreFilterResult, *framework.Status) {
+	g, err := getPodGroup(p)
+	if err != nil {
+		return nil, framework.NewStatus(framework.UnschedulableAndUnresolvable, err.Error())
+	}
+	if g == nil || g.acrossTargets {
+		return nil, nil
+	}
+	// keep the group together in the target where other members are already placed, if any
+	placed, err := pl.placedMembers(g, p)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	if len(placed) == 0 {
+		return nil, nil
+	}
+	nodeNames := sets.New[string]()
+	for nodeName := range placed {
+		nodeNames.Insert(nodeName)
+	}
+	return &framework.PreFilterResult{NodeNames: nodeNames}, nil
+}
+
+func (pl *Plugin) PreFilterExtensions() framework.PreFilterExtensions {
+	return nil
+}
+
+// Permit makes pod group members wait for the group to reach its minimum size in the same target
+// (or across targets), before their candidates are allowed (in PreBind rather than Reserve).
+func (pl *Plugin) Permit(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (*framework.Status, time.Duration) {
+	g, err := getPodGroup(p)
+	if err != nil {
+		return framework.AsStatus(err), 0
+	}
+	if g == nil {
+		return nil, 0
+	}
+
+	placed, err := pl.placedMembers(g, p)
+	if err != nil {
+		return framework.AsStatus(err), 0
+	}
+	n := 1 + placed[nodeName]
+	if g.acrossTargets {
+		n = 1
+		for _, count := range placed {
+			n += count
+		}
+	}
+	if n < g.minMember {
+		klog.V(1).Infof("pod group %s/%s has %d/%d members, waiting", g.namespace, g.name, n, g.minMember)
+		return framework.NewStatus(framework.Wait), pl.args.PodGroupTimeout.Duration
+	}
+
+	pl.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if member := wp.GetPod(); g.contains(member) && (g.acrossTargets || member.Spec.NodeName == nodeName) {
+			wp.Allow(Name)
+		}
+	})
+	return nil, 0
+}
+
+// releaseGroup is called when a pod group member is unreserved, to release its candidate
+// and those of the other members waiting with it, so they don't hold resources in target clusters in vain
+func (pl *Plugin) releaseGroup(ctx context.Context, g *podGroup, p *v1.Pod, nodeName string) {
+	pl.deleteCandidate(ctx, p, nodeName)
+	pl.handle.IterateOverWaitingPods(func(wp framework.WaitingPod) {
+		if member := wp.GetPod(); g.contains(member) && (g.acrossTargets || member.Spec.NodeName == nodeName) {
+			wp.Reject(Name, fmt.Sprintf("pod group member %s unreserved", p.Name))
+		}
+	})
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/defaultbinder"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/queuesort"
+	frameworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
+	tf "k8s.io/kubernetes/pkg/scheduler/testing/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/fake"
+)
+
+func TestGetPodGroup(t *testing.T) {
+	pod := func(namespace string, annotations map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Annotations: annotations}}
+	}
+
+	g, err := getPodGroup(pod("ns", nil))
+	require.NoError(t, err)
+	require.Nil(t, g)
+
+	g, err = getPodGroup(pod("ns", map[string]string{
+		common.AnnotationKeyPodGroup:              "job",
+		common.AnnotationKeyPodGroupMinMember:     "3",
+		common.AnnotationKeyPodGroupAcrossTargets: "true",
+	}))
+	require.NoError(t, err)
+	require.Equal(t, &podGroup{namespace: "ns", name: "job", minMember: 3, acrossTargets: true}, g)
+
+	require.True(t, g.contains(pod("ns", map[string]string{common.AnnotationKeyPodGroup: "job"})))
+	require.False(t, g.contains(pod("other", map[string]string{common.AnnotationKeyPodGroup: "job"})))
+	require.False(t, g.contains(pod("ns", map[string]string{common.AnnotationKeyPodGroup: "other"})))
+	require.False(t, g.contains(pod("ns", nil)))
+
+	_, err = getPodGroup(pod("ns", map[string]string{
+		common.AnnotationKeyPodGroup:          "job",
+		common.AnnotationKeyPodGroupMinMember: "zero",
+	}))
+	require.Error(t, err)
+}
+
+// snapshot is a static scheduler snapshot of virtual nodes and the pods placed on them
+type snapshot struct {
+	tf.NodeInfoLister
+}
+
+func (s snapshot) NodeInfos() framework.NodeInfoLister {
+	return s.NodeInfoLister
+}
+
+func (s snapshot) StorageInfos() framework.StorageInfoLister {
+	return nil
+}
+
+// newVirtualNodeInfo returns the node info of a virtual node, with pods placed on it
+func newVirtualNodeInfo(name string, pods ...*v1.Pod) *framework.NodeInfo {
+	nodeInfo := framework.NewNodeInfo(pods...)
+	nodeInfo.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+		common.LabelAndTaintKeyVirtualKubeletProvider: common.VirtualKubeletProviderName,
+	}}})
+	return nodeInfo
+}
+
+// withFramework sets the plugin's handle to a scheduling framework running its Permit extension point,
+// so that waiting pods are managed as in the scheduler
+func withFramework(t *testing.T, pl *Plugin, nodeInfos ...*framework.NodeInfo) framework.Framework {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	fwk, err := tf.NewFramework(ctx, []tf.RegisterPluginFunc{
+		tf.RegisterQueueSortPlugin(queuesort.Name, queuesort.New),
+		tf.RegisterBindPlugin(defaultbinder.Name, defaultbinder.New),
+		tf.RegisterPermitPlugin(Name, func(_ context.Context, _ runtime.Object, h framework.Handle) (framework.Plugin, error) {
+			pl.handle = h
+			return pl, nil
+		}),
+	}, "proxy", frameworkruntime.WithSnapshotSharedLister(snapshot{tf.NodeInfoLister(nodeInfos)}))
+	require.NoError(t, err)
+	return fwk
+}
+
+func newMember(name string, minMember int, acrossTargets bool, nodeName string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, UID: types.UID(name), Annotations: map[string]string{
+			common.AnnotationKeyPodGroup:              "job",
+			common.AnnotationKeyPodGroupMinMember:     strconv.Itoa(minMember),
+			common.AnnotationKeyPodGroupAcrossTargets: strconv.FormatBool(acrossTargets),
+		}},
+		Spec: v1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: nodeName},
+	}
+}
+
+func TestPreFilterPodGroup(t *testing.T) {
+	placed := newMember("placed", 3, false, "a")
+	pl := &Plugin{}
+	withFramework(t, pl, newVirtualNodeInfo("a", placed), newVirtualNodeInfo("b"))
+
+	// not a member
+	r, s := pl.PreFilter(context.Background(), framework.NewCycleState(), &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", UID: "other"}})
+	require.True(t, s.IsSuccess())
+	require.Nil(t, r)
+
+	// kept with the other members
+	r, s = pl.PreFilter(context.Background(), framework.NewCycleState(), newMember("p", 3, false, ""))
+	require.True(t, s.IsSuccess())
+	require.Equal(t, sets.New("a"), r.NodeNames)
+
+	// first member
+	other := newMember("p", 3, false, "")
+	other.Annotations[common.AnnotationKeyPodGroup] = "other-job"
+	r, s = pl.PreFilter(context.Background(), framework.NewCycleState(), other)
+	require.True(t, s.IsSuccess())
+	require.Nil(t, r)
+
+	// across targets
+	r, s = pl.PreFilter(context.Background(), framework.NewCycleState(), newMember("p", 3, true, ""))
+	require.True(t, s.IsSuccess())
+	require.Nil(t, r)
+
+	// malformed
+	malformed := newMember("p", 3, false, "")
+	malformed.Annotations[common.AnnotationKeyPodGroupMinMember] = "zero"
+	_, s = pl.PreFilter(context.Background(), framework.NewCycleState(), malformed)
+	require.Equal(t, framework.UnschedulableAndUnresolvable, s.Code())
+}
+
+func TestPermitPodGroup(t *testing.T) {
+	ctx := context.Background()
+	placed := newMember("placed", 3, false, "a")
+	p1 := newMember("p1", 3, false, "a")
+	p2 := newMember("p2", 3, false, "a")
+	elsewhere := newMember("elsewhere", 3, false, "b")
+	pl := &Plugin{args: Args{PodGroupTimeout: &metav1.Duration{Duration: time.Minute}}}
+	fwk := withFramework(t, pl, newVirtualNodeInfo("a", placed, p1), newVirtualNodeInfo("b", elsewhere))
+
+	// partial group: 2/3 members in target a (members in target b don't count), waiting
+	s, timeout := pl.Permit(ctx, framework.NewCycleState(), p1, "a")
+	require.Equal(t, framework.Wait, s.Code())
+	require.Equal(t, time.Minute, timeout)
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), p1, "a").Code())
+
+	// group complete: the waiting member is allowed too
+	s, _ = pl.Permit(ctx, framework.NewCycleState(), p2, "a")
+	require.True(t, s.IsSuccess())
+	require.True(t, fwk.WaitOnPermit(ctx, p1).IsSuccess())
+
+	// across targets, members in target b count
+	across := newMember("p1", 3, true, "a")
+	acrossPlaced := newMember("placed", 3, true, "a")
+	acrossElsewhere := newMember("elsewhere", 3, true, "b")
+	withFramework(t, pl, newVirtualNodeInfo("a", acrossPlaced), newVirtualNodeInfo("b", acrossElsewhere))
+	s, _ = pl.Permit(ctx, framework.NewCycleState(), across, "a")
+	require.True(t, s.IsSuccess())
+}
+
+func TestPermitPodGroupTimeout(t *testing.T) {
+	ctx := context.Background()
+	p1 := newMember("p1", 3, false, "a")
+	pl := &Plugin{args: Args{PodGroupTimeout: &metav1.Duration{Duration: 10 * time.Millisecond}}}
+	fwk := withFramework(t, pl, newVirtualNodeInfo("a", p1))
+
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, framework.NewCycleState(), p1, "a").Code())
+	s := fwk.WaitOnPermit(ctx, p1)
+	require.True(t, s.IsRejected())
+	require.Contains(t, s.Message(), "timeout")
+}
+
+func TestReleaseGroup(t *testing.T) {
+	ctx := context.Background()
+	unreserved := newMember("unreserved", 3, false, "a")
+	waiting := newMember("waiting", 3, false, "a")
+	elsewhere := newMember("elsewhere", 3, false, "b")
+	client := fake.NewSimpleClientset()
+	pl := &Plugin{
+		args:    Args{PodGroupTimeout: &metav1.Duration{Duration: time.Minute}},
+		targets: map[string]*target{"a": {client: client}},
+	}
+	fwk := withFramework(t, pl, newVirtualNodeInfo("a"), newVirtualNodeInfo("b"))
+	state := framework.NewCycleState()
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, state, waiting, "a").Code())
+	require.Equal(t, framework.Wait, fwk.RunPermitPlugins(ctx, state, elsewhere, "b").Code())
+
+	g, err := getPodGroup(unreserved)
+	require.NoError(t, err)
+	pl.releaseGroup(ctx, g, unreserved, "a")
+
+	// the unreserved member's candidate is deleted
+	require.Len(t, client.Actions(), 1)
+	require.Equal(t, "delete-collection", client.Actions()[0].GetVerb())
+	require.Equal(t, common.LabelKeyParentUID+"=unreserved", client.Actions()[0].(k8stesting.DeleteCollectionAction).GetListRestrictions().Labels.String())
+
+	// the member waiting in the same target is rejected, and Unreserve (called by the scheduler) deletes its candidate
+	s := fwk.WaitOnPermit(ctx, waiting)
+	require.True(t, s.IsRejected())
+	require.Contains(t, s.Message(), "pod group member unreserved unreserved")
+
+	// the member waiting in another target isn't, because the group doesn't span targets
+	require.NotNil(t, fwk.GetWaitingPod(elsewhere.UID))
+	fwk.RejectWaitingPod(elsewhere.UID)
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+)
+
+// makeCandidate makes a delegate pod chaperon for a target
+func (pl *Plugin) makeCandidate(p *v1.Pod, t *target, g *podGroup) (*v1alpha1.PodChaperon, error) {
+	c, err := delegatepod.MakeDelegatePod(p, pl.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	mapPriorityClass(c, t)
+	if err := t.transformer.Transform(c, p); err != nil {
+		return nil, err
+	}
+	pl.setPermitTimeout(c, t, g)
+	if g != nil {
+		c.Annotations[common.AnnotationKeyPodGroup] = g.name
+	}
+	return c, nil
+}
+
+// mapPriorityClass renames the candidate's priority class according to the target's priority class map, if any
+func mapPriorityClass(c *v1alpha1.PodChaperon, t *target) {
+	if name, ok := t.priorityClassMap[c.Spec.PriorityClassName]; ok {
+		c.Spec.PriorityClassName = name
+	}
+}
+
+// preempt allows the candidate with the cheapest preemption cost, as reported by candidate schedulers,
+// to preempt lower-priority pods in its target cluster, and nominates the target's virtual node.
+// It returns a nil result if preemption wouldn't help.
+func (pl *Plugin) preempt(ctx context.Context, p *v1.Pod) (*framework.PostFilterResult, *framework.Status) {
+	if p.Spec.PreemptionPolicy != nil && *p.Spec.PreemptionPolicy == v1.PreemptNever {
+		return nil, nil
+	}
+
+	best, err := pl.cheapestCandidate(p)
+	if err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	if best == nil {
+		return nil, nil
+	}
+	if best.cost == nil {
+		// already preempting (victims may still be terminating), don't preempt in another target
+		return nominate(best.clusterName), framework.NewStatus(framework.Success)
+	}
+
+	klog.V(1).Infof("allowing candidate %s of pod %s/%s to preempt %d pod(s) in target %s", best.candidate.Name, p.Namespace, p.Name, best.cost.NumVictims, best.clusterName)
+	patch := []byte(`{"metadata":{"annotations":{"` + common.AnnotationKeyIsAllowedToPreempt + `":"true"}}}`)
+	if _, err := best.target.client.MulticlusterV1alpha1().PodChaperons(best.candidate.Namespace).Patch(ctx, best.candidate.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return nil, framework.AsStatus(err)
+	}
+	return nominate(best.clusterName), framework.NewStatus(framework.Success)
+}
+
+type preemptionCandidate struct {
+	clusterName string
+	target      *target
+	candidate   *v1alpha1.PodChaperon
+	cost        *delegatepod.PreemptionCost
+}
+
+// cheapestCandidate returns the unschedulable candidate with the cheapest preemption cost,
+// or the candidate already allowed to preempt, if any, with a nil cost
+func (pl *Plugin) cheapestCandidate(p *v1.Pod) (*preemptionCandidate, error) {
+	var best *preemptionCandidate
+
+	pl.targetsMx.RLock()
+	defer pl.targetsMx.RUnlock()
+	for clusterName, t := range pl.targets {
+		if !t.accepts(p.Namespace) {
+			continue
+		}
+		c, err := t.getCandidate(p.UID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || !isCandidatePodUnschedulable(c) {
+			continue
+		}
+		if _, ok := c.Annotations[common.AnnotationKeyIsAllowedToPreempt]; ok {
+			return &preemptionCandidate{clusterName: clusterName, target: t, candidate: c}, nil
+		}
+		cost, err := delegatepod.GetPreemptionCost(c)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		if cost == nil {
+			continue
+		}
+		// break ties by cluster name, for determinism
+		if best == nil || cost.Less(*best.cost) || !best.cost.Less(*cost) && clusterName < best.clusterName {
+			best = &preemptionCandidate{clusterName: clusterName, target: t, candidate: c, cost: cost}
+		}
+	}
+	return best, nil
+}
+
+func nominate(clusterName string) *framework.PostFilterResult {
+	return &framework.PostFilterResult{NominatingInfo: &framework.NominatingInfo{
+		NominatedNodeName: clusterName, // virtual node name
+		NominatingMode:    framework.ModeOverride,
+	}}
+}
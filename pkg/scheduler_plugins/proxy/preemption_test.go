@@ -0,0 +1,91 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+)
+
+func TestCheapestCandidate(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", UID: "uid"}}
+
+	newTarget := func(annotations map[string]string) *target {
+		informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &v1alpha1.PodChaperon{}, 0, cache.Indexers{
+			podChaperonByParentUID: indexPodChaperonByParentUID,
+		})
+		c := &v1alpha1.PodChaperon{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "c", Labels: map[string]string{common.LabelKeyParentUID: "uid"}, Annotations: annotations},
+			Status: v1.PodStatus{Conditions: []v1.PodCondition{{
+				Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: v1.PodReasonUnschedulable,
+			}}},
+		}
+		require.NoError(t, informer.GetIndexer().Add(c))
+		return &target{namespace: "ns", podChaperonInformer: informer}
+	}
+	withCost := func(cost delegatepod.PreemptionCost) map[string]string {
+		b, err := json.Marshal(cost)
+		require.NoError(t, err)
+		return map[string]string{common.AnnotationKeyPreemptionCost: string(b)}
+	}
+
+	pl := &Plugin{targets: map[string]*target{
+		"a": newTarget(withCost(delegatepod.PreemptionCost{NumVictims: 1, HighestPriority: 100})),
+		"b": newTarget(withCost(delegatepod.PreemptionCost{NumVictims: 3, HighestPriority: 10})),
+		"c": newTarget(nil),
+	}}
+	best, err := pl.cheapestCandidate(pod)
+	require.NoError(t, err)
+	require.Equal(t, "b", best.clusterName)
+	require.Equal(t, 3, best.cost.NumVictims)
+
+	pl.targets["d"] = newTarget(map[string]string{common.AnnotationKeyIsAllowedToPreempt: "true"})
+	best, err = pl.cheapestCandidate(pod)
+	require.NoError(t, err)
+	require.Equal(t, "d", best.clusterName)
+	require.Nil(t, best.cost)
+
+	pl = &Plugin{targets: map[string]*target{"c": newTarget(nil)}}
+	best, err = pl.cheapestCandidate(pod)
+	require.NoError(t, err)
+	require.Nil(t, best)
+}
+
+func TestMapPriorityClass(t *testing.T) {
+	tg := &target{priorityClassMap: map[string]string{"high": "target-high", "": "target-default"}}
+
+	c := &v1alpha1.PodChaperon{Spec: v1.PodSpec{PriorityClassName: "high"}}
+	mapPriorityClass(c, tg)
+	require.Equal(t, "target-high", c.Spec.PriorityClassName)
+
+	c = &v1alpha1.PodChaperon{}
+	mapPriorityClass(c, tg)
+	require.Equal(t, "target-default", c.Spec.PriorityClassName)
+
+	c = &v1alpha1.PodChaperon{Spec: v1.PodSpec{PriorityClassName: "low"}}
+	mapPriorityClass(c, tg)
+	require.Equal(t, "low", c.Spec.PriorityClassName)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+const defaultWeight = 50
+
+// reservedAfter is written to the cycle state by Filter, for each virtual node whose candidate was reserved,
+// so Score can favor faster targets
+type reservedAfter time.Duration
+
+func (d reservedAfter) Clone() framework.StateData {
+	return d
+}
+
+func reservedAfterKey(nodeName string) framework.StateKey {
+	return framework.StateKey(Name + "/reserved-after/" + nodeName)
+}
+
+func (pl *Plugin) Score(ctx context.Context, state *framework.CycleState, p *v1.Pod, nodeName string) (int64, *framework.Status) {
+	nodeInfo, err := pl.handle.SnapshotSharedLister().NodeInfos().Get(nodeName)
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, fmt.Sprintf("getting node %q from snapshot: %v", nodeName, err))
+	}
+	target, err := pl.getTarget(virtualNodeNameToClusterName(nodeName))
+	if err != nil {
+		return 0, framework.NewStatus(framework.Error, err.Error())
+	}
+
+	free := freeFraction(nodeInfo, p)
+
+	// with no reservation, or if we didn't wait, we don't know how fast the target is
+	speed := 0.5
+	if d, err := state.Read(reservedAfterKey(nodeName)); err == nil {
+		speed = 1 - min(float64(d.(reservedAfter))/float64(pl.filterTimeout(target)), 1)
+	}
+
+	weight := float64(defaultWeight) / 100
+	if w := target.weight; w != nil {
+		weight = float64(*w) / 100
+	}
+
+	priority := pl.relativePriority(p.Namespace, target)
+
+	strategy := p.Annotations[common.AnnotationKeySchedulingStrategy]
+	score, ok := rank(strategy, free, speed, weight, priority)
+	if !ok {
+		klog.Warningf("pod %s/%s has unknown scheduling strategy %q, falling back to %s", p.Namespace, p.Name, strategy, common.SchedulingStrategySpread)
+	}
+
+	return int64(score * float64(framework.MaxNodeScore)), nil
+}
+
+// rank combines signals, all between 0 and 1, into a score between 0 and 1, according to a scheduling strategy.
+// Unknown strategies are ranked as spread (the default), and reported as such (false).
+func rank(strategy string, free, speed, weight, priority float64) (float64, bool) {
+	switch strategy {
+	case common.SchedulingStrategyBinpack:
+		return 0.4*(1-free) + 0.2*speed + 0.2*weight + 0.2*priority, true
+	case common.SchedulingStrategyCheapestFirst:
+		return 0.6*weight + 0.2*priority + 0.1*free + 0.1*speed, true
+	case common.SchedulingStrategySpread, "":
+		return 0.4*free + 0.2*speed + 0.2*weight + 0.2*priority, true
+	default:
+		score, _ := rank(common.SchedulingStrategySpread, free, speed, weight, priority)
+		return score, false
+	}
+}
+
+func (pl *Plugin) ScoreExtensions() framework.ScoreExtensions {
+	return nil
+}
+
+// freeFraction returns the fraction of CPU and memory that would remain allocatable in the target cluster
+// (as summarized by its ClusterSummary, reflected in virtual node status), if the pod were scheduled there.
+// Only pods scheduled from this cluster are accounted for.
+func freeFraction(nodeInfo *framework.NodeInfo, p *v1.Pod) float64 {
+	requests := resourcehelper.PodRequests(p, resourcehelper.PodResourcesOptions{})
+	cpu := fraction(nodeInfo.Allocatable.MilliCPU, nodeInfo.Requested.MilliCPU+requests.Cpu().MilliValue())
+	memory := fraction(nodeInfo.Allocatable.Memory, nodeInfo.Requested.Memory+requests.Memory().Value())
+	return (cpu + memory) / 2
+}
+
+func fraction(allocatable, requested int64) float64 {
+	if allocatable <= 0 {
+		return 0
+	}
+	return max(float64(allocatable-requested)/float64(allocatable), 0)
+}
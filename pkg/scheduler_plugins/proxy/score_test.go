@@ -0,0 +1,70 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestRank(t *testing.T) {
+	type target struct{ free, speed, weight, priority float64 }
+	large := target{free: 0.9, speed: 0.5, weight: 0.5, priority: 0.5}
+	small := target{free: 0.1, speed: 0.5, weight: 0.5, priority: 0.5}
+	cheap := target{free: 0.1, speed: 0.5, weight: 1, priority: 0.5}
+	preferred := target{free: 0.1, speed: 0.5, weight: 0.5, priority: 1}
+
+	better := func(strategy string, a, b target) bool {
+		sa, ok := rank(strategy, a.free, a.speed, a.weight, a.priority)
+		require.True(t, ok)
+		sb, ok := rank(strategy, b.free, b.speed, b.weight, b.priority)
+		require.True(t, ok)
+		return sa > sb
+	}
+
+	require.True(t, better("", large, small))
+	require.True(t, better(common.SchedulingStrategySpread, large, small))
+	require.True(t, better(common.SchedulingStrategyBinpack, small, large))
+	require.True(t, better(common.SchedulingStrategyCheapestFirst, cheap, large))
+	require.True(t, better(common.SchedulingStrategyBinpack, preferred, small))
+	require.True(t, better(common.SchedulingStrategyCheapestFirst, preferred, small))
+
+	// unknown strategies fall back to spread
+	spread, _ := rank(common.SchedulingStrategySpread, large.free, large.speed, large.weight, large.priority)
+	score, ok := rank("random", large.free, large.speed, large.weight, large.priority)
+	require.False(t, ok)
+	require.Equal(t, spread, score)
+}
+
+func TestFreeFraction(t *testing.T) {
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(&v1.Node{Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("4"),
+		v1.ResourceMemory: resource.MustParse("4Gi"),
+	}}})
+	p := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{Requests: v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("1"),
+		v1.ResourceMemory: resource.MustParse("3Gi"),
+	}}}}}}
+	require.InDelta(t, 0.5, freeFraction(nodeInfo, p), 0.001)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"slices"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// tiers returns the sorted distinct tiers of the targets accepting pods from a namespace
+func (pl *Plugin) tiers(namespace string) []int32 {
+	pl.targetsMx.RLock()
+	defer pl.targetsMx.RUnlock()
+	var tiers []int32
+	for _, t := range pl.targets {
+		if t.accepts(namespace) && !slices.Contains(tiers, t.tier) {
+			tiers = append(tiers, t.tier)
+		}
+	}
+	slices.Sort(tiers)
+	return tiers
+}
+
+// nextTier returns the lowest tier strictly greater than current, if any
+func nextTier(tiers []int32, current int32) (int32, bool) {
+	for _, tier := range tiers {
+		if tier > current {
+			return tier, true
+		}
+	}
+	return 0, false
+}
+
+// allowedTier returns the highest tier that a pod's scheduling cycle may consider.
+// It starts with the lowest tier and is escalated by PostFilter when all targets up to it are unschedulable.
+func (pl *Plugin) allowedTier(p *v1.Pod) int32 {
+	pl.mx.RLock()
+	tier, ok := pl.allowedTierByPodUID[p.UID]
+	pl.mx.RUnlock()
+	if ok {
+		return tier
+	}
+	tiers := pl.tiers(p.Namespace)
+	if len(tiers) == 0 {
+		return 0
+	}
+	return tiers[0]
+}
+
+// escalateTier allows a pod to be scheduled to targets of the next tier, if any, and returns true if it did
+func (pl *Plugin) escalateTier(p *v1.Pod) bool {
+	next, ok := nextTier(pl.tiers(p.Namespace), pl.allowedTier(p))
+	if !ok {
+		return false
+	}
+	pl.mx.Lock()
+	defer pl.mx.Unlock()
+	pl.allowedTierByPodUID[p.UID] = next
+	return true
+}
+
+// relativePriority returns a target's priority, between 0 and 1,
+// relative to the other targets accepting pods from the same namespace (0.5 if they all have the same priority)
+func (pl *Plugin) relativePriority(namespace string, target *target) float64 {
+	pl.targetsMx.RLock()
+	defer pl.targetsMx.RUnlock()
+	p := priority(target)
+	lowest, highest := p, p
+	for _, t := range pl.targets {
+		if t.accepts(namespace) {
+			lowest = min(lowest, priority(t))
+			highest = max(highest, priority(t))
+		}
+	}
+	if lowest == highest {
+		return 0.5
+	}
+	return float64(p-lowest) / float64(highest-lowest)
+}
+
+func priority(t *target) int64 {
+	if t.priority == nil {
+		return 0
+	}
+	return int64(*t.priority)
+}
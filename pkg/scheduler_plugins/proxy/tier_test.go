@@ -0,0 +1,52 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestTiers(t *testing.T) {
+	one, two := int32(1), int32(2)
+	pl := &Plugin{
+		targets: map[string]*target{
+			"a":     {namespace: "ns", tier: 1, priority: &two},
+			"b":     {namespace: "", tier: 1},
+			"c":     {namespace: "ns", tier: 3, priority: &one},
+			"other": {namespace: "other", tier: 0},
+		},
+		allowedTierByPodUID: map[types.UID]int32{},
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", UID: "uid"}}
+
+	require.Equal(t, []int32{1, 3}, pl.tiers("ns"))
+	require.Equal(t, int32(1), pl.allowedTier(pod))
+
+	require.True(t, pl.escalateTier(pod))
+	require.Equal(t, int32(3), pl.allowedTier(pod))
+	require.False(t, pl.escalateTier(pod))
+
+	require.Equal(t, 1.0, pl.relativePriority("ns", pl.targets["a"]))
+	require.Equal(t, 0.5, pl.relativePriority("ns", pl.targets["c"]))
+	require.Equal(t, 0.0, pl.relativePriority("ns", pl.targets["b"]))
+	require.Equal(t, 0.5, pl.relativePriority("other", pl.targets["other"]))
+}
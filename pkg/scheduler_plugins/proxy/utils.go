@@ -30,3 +30,17 @@ func isCandidatePodUnschedulable(c *v1alpha1.PodChaperon) bool {
 	}
 	return false
 }
+
+// candidateSchedulingMessage returns the message of the candidate's PodScheduled condition,
+// e.g., "0/12 nodes are available: 12 Insufficient nvidia.com/gpu.", if any
+func candidateSchedulingMessage(c *v1alpha1.PodChaperon) string {
+	if c == nil {
+		return ""
+	}
+	for _, cond := range c.Status.Conditions {
+		if cond.Type == v1.PodScheduled {
+			return cond.Message
+		}
+	}
+	return ""
+}
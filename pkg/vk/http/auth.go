@@ -0,0 +1,168 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/authenticatorfactory"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/authorization/authorizerfactory"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Auth authenticates requests to the virtual kubelet API with client certificates (signed by the source cluster's client CA)
+// or bearer tokens (TokenReview), and authorizes them (SubjectAccessReview) against the nodes' subresources, as a real kubelet does.
+type Auth struct {
+	authenticator authenticator.Request
+	authorizer    authorizer.Authorizer
+}
+
+// from k8s.io/apiserver/pkg/server/options.DefaultAuthWebhookRetryBackoff
+var authWebhookRetryBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   0.2,
+	Steps:    5,
+}
+
+// NewAuth delegates authentication and authorization to the source cluster's API server.
+// The client CA is read (and watched) from the extension-apiserver-authentication config map;
+// if it cannot be, only bearer tokens are accepted.
+func NewAuth(ctx context.Context, client kubernetes.Interface) (*Auth, error) {
+	authnConfig := authenticatorfactory.DelegatingAuthenticatorConfig{
+		TokenAccessReviewClient:  client.AuthenticationV1(),
+		TokenAccessReviewTimeout: 10 * time.Second,
+		WebhookRetryBackoff:      &authWebhookRetryBackoff,
+		CacheTTL:                 2 * time.Minute,
+	}
+
+	clientCA, err := dynamiccertificates.NewDynamicCAFromConfigMapController("client-ca", "kube-system", "extension-apiserver-authentication", "client-ca-file", client)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create client CA controller")
+	}
+	if err := clientCA.RunOnce(ctx); err != nil {
+		klog.Warningf("cannot load client CA, client certificate authentication won't work: %v", err)
+	} else {
+		go clientCA.Run(ctx, 1)
+		authnConfig.ClientCertificateCAContentProvider = clientCA
+	}
+
+	authn, _, err := authnConfig.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create authenticator")
+	}
+
+	authz, err := authorizerfactory.DelegatingAuthorizerConfig{
+		SubjectAccessReviewClient: client.AuthorizationV1(),
+		AllowCacheTTL:             5 * time.Minute,
+		DenyCacheTTL:              30 * time.Second,
+		WebhookRetryBackoff:       &authWebhookRetryBackoff,
+	}.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create authorizer")
+	}
+
+	return &Auth{authenticator: authn, authorizer: authz}, nil
+}
+
+// Handler wraps h with authentication and authorization
+func (a *Auth) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		resp, ok, err := a.authenticator.AuthenticateRequest(req)
+		if err != nil {
+			klog.Errorf("unable to authenticate the request due to an error: %v", err)
+		}
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		attrs := requestAttributes(resp.User, req)
+		decision, reason, err := a.authorizer.Authorize(req.Context(), attrs)
+		if err != nil {
+			msg := fmt.Sprintf("Authorization error (user=%s, verb=%s, resource=%s, subresource=%s)", attrs.GetUser().GetName(), attrs.GetVerb(), attrs.GetResource(), attrs.GetSubresource())
+			klog.Errorf("%s: %v", msg, err)
+			http.Error(w, msg, http.StatusInternalServerError)
+			return
+		}
+		if decision != authorizer.DecisionAllow {
+			msg := fmt.Sprintf("Forbidden (user=%s, verb=%s, resource=%s, subresource=%s)", attrs.GetUser().GetName(), attrs.GetVerb(), attrs.GetResource(), attrs.GetSubresource())
+			klog.V(2).Infof("%s: %s", msg, reason)
+			http.Error(w, msg, http.StatusForbidden)
+			return
+		}
+
+		h.ServeHTTP(w, req)
+	})
+}
+
+// requestAttributes is modified from k8s.io/kubernetes/pkg/kubelet/server.nodeAuthorizerAttributesGetter.
+// All virtual nodes share the same endpoint, so we can't tell which one is requested,
+// and authorize the request against all nodes (an empty name).
+func requestAttributes(u user.Info, r *http.Request) authorizer.Attributes {
+	verb := ""
+	switch r.Method {
+	case http.MethodPost:
+		verb = "create"
+	case http.MethodGet:
+		verb = "get"
+	case http.MethodPut:
+		verb = "update"
+	case http.MethodPatch:
+		verb = "patch"
+	case http.MethodDelete:
+		verb = "delete"
+	}
+
+	subresource := "proxy"
+	switch {
+	case isSubpath(r.URL.Path, "/stats"):
+		subresource = "stats"
+	case isSubpath(r.URL.Path, "/metrics"):
+		subresource = "metrics"
+	case isSubpath(r.URL.Path, "/logs"):
+		// kubelet logs (not container logs)
+		subresource = "log"
+	}
+
+	return authorizer.AttributesRecord{
+		User:            u,
+		Verb:            verb,
+		APIGroup:        "",
+		APIVersion:      "v1",
+		Resource:        "nodes",
+		Subresource:     subresource,
+		ResourceRequest: true,
+		Path:            r.URL.Path,
+	}
+}
+
+func isSubpath(subpath, path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	return subpath == path || strings.HasPrefix(subpath, path+"/")
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+)
+
+func TestAuthHandler(t *testing.T) {
+	a := &Auth{
+		authenticator: authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+			if req.Header.Get("Authorization") != "Bearer valid" {
+				return nil, false, nil
+			}
+			return &authenticator.Response{User: &user.DefaultInfo{Name: "kube-apiserver"}}, true, nil
+		}),
+		authorizer: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+			// e.g., metrics-server may get stats and metrics, but not exec
+			if a.GetUser().GetName() == "kube-apiserver" && a.GetResource() == "nodes" && a.GetSubresource() == "metrics" && a.GetVerb() == "get" {
+				return authorizer.DecisionAllow, "", nil
+			}
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+	}
+	h := a.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+
+	for _, tc := range []struct {
+		method, path, token string
+		code                int
+	}{
+		{http.MethodGet, "/metrics/resource", "", http.StatusUnauthorized},
+		{http.MethodGet, "/metrics/resource", "invalid", http.StatusUnauthorized},
+		{http.MethodGet, "/metrics/resource", "valid", http.StatusOK},
+		{http.MethodPost, "/exec/ns/pod/c", "valid", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, tc.code, w.Code, "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestRequestAttributes(t *testing.T) {
+	u := &user.DefaultInfo{Name: "u"}
+	for _, tc := range []struct {
+		method, path, verb, subresource string
+	}{
+		{http.MethodGet, "/stats/summary", "get", "stats"},
+		{http.MethodGet, "/metrics/resource", "get", "metrics"},
+		{http.MethodGet, "/containerLogs/ns/pod/c", "get", "proxy"},
+		{http.MethodPost, "/exec/ns/pod/c", "create", "proxy"},
+		{http.MethodPost, "/portForward/ns/pod", "create", "proxy"},
+		{http.MethodGet, "/statsfoo", "get", "proxy"},
+	} {
+		attrs := requestAttributes(u, httptest.NewRequest(tc.method, tc.path, nil))
+		require.Equal(t, tc.verb, attrs.GetVerb(), tc.path)
+		require.Equal(t, "nodes", attrs.GetResource(), tc.path)
+		require.Equal(t, tc.subresource, attrs.GetSubresource(), tc.path)
+		require.Equal(t, "", attrs.GetName(), tc.path)
+	}
+}
@@ -24,8 +24,10 @@ import (
 	"net/http"
 
 	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/virtual-kubelet/virtual-kubelet/log"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
 )
 
 // AcceptedCiphers is the list of accepted TLS ciphers, with known weak ciphers elided
@@ -53,15 +55,21 @@ func loadTLSConfig(certPEMBlock, keyPEMBlock []byte) (*tls.Config, error) {
 		MinVersion:               tls.VersionTLS12,
 		PreferServerCipherSuites: true,
 		CipherSuites:             AcceptedCiphers,
+		// client certificates are verified by the authenticator, which also accepts bearer tokens
+		ClientAuth: tls.RequestClientCert,
 	}, nil
 }
 
 type Provider interface {
 	RunInContainer(ctx context.Context, namespace, podName, containerName string, cmd []string, attach api.AttachIO) error
+	AttachToContainer(ctx context.Context, namespace, podName, containerName string, attach api.AttachIO) error
+	PortForward(ctx context.Context, namespace, podName string, port int32, stream io.ReadWriteCloser) error
 	GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts api.ContainerLogOpts) (io.ReadCloser, error)
+	GetStatsSummary(ctx context.Context) (*statsv1alpha1.Summary, error)
+	GetMetricsResource(ctx context.Context) ([]*dto.MetricFamily, error)
 }
 
-func SetupHTTPServer(ctx context.Context, p Provider, certPEMBlock, keyPEMBlock []byte) (_ func(), retErr error) {
+func SetupHTTPServer(ctx context.Context, p Provider, auth *Auth, certPEMBlock, keyPEMBlock []byte) (_ func(), retErr error) {
 	var closers []io.Closer
 	cancel := func() {
 		for _, c := range closers {
@@ -86,13 +94,17 @@ func SetupHTTPServer(ctx context.Context, p Provider, certPEMBlock, keyPEMBlock
 	mux := http.NewServeMux()
 
 	podRoutes := api.PodHandlerConfig{
-		RunInContainer:   p.RunInContainer,
-		GetContainerLogs: p.GetContainerLogs,
+		RunInContainer:     p.RunInContainer,
+		AttachToContainer:  p.AttachToContainer,
+		PortForward:        p.PortForward,
+		GetContainerLogs:   p.GetContainerLogs,
+		GetStatsSummary:    p.GetStatsSummary,
+		GetMetricsResource: p.GetMetricsResource,
 	}
 	api.AttachPodRoutes(podRoutes, mux, true)
 
 	s := &http.Server{
-		Handler:   mux,
+		Handler:   auth.Handler(mux),
 		TLSConfig: tlsCfg,
 	}
 	go serveHTTP(ctx, s, l, "pods")
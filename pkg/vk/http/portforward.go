@@ -0,0 +1,135 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward forwards a stream to a port of the delegate pod in the target cluster,
+// through the target API server's pods/portforward subresource (one SPDY connection per stream)
+func (p *ProxyPodProvider) PortForward(ctx context.Context, namespace, name string, port int32, stream io.ReadWriteCloser) error {
+	defer stream.Close()
+
+	targetName, delegatePodName, err := p.getTargetAndDelegatePodNames(ctx, namespace, name)
+	if err != nil {
+		return errors.Wrap(err, "cannot get delegate pod name")
+	}
+
+	targetConfig, targetClient, ok := p.getTarget(targetName)
+	if !ok {
+		return errors.Errorf("not a current target name")
+	}
+	req := targetClient.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(delegatePodName).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(targetConfig)
+	if err != nil {
+		return fmt.Errorf("could not make round tripper: %v", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+	conn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return fmt.Errorf("could not upgrade connection: %v", err)
+	}
+	defer conn.Close()
+
+	// the following is modified from k8s.io/client-go/tools/portforward
+
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("error creating error stream for port %d: %v", port, err)
+	}
+	// we're not writing to this stream
+	errorStream.Close()
+	defer conn.RemoveStreams(errorStream)
+
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		switch {
+		case err != nil:
+			errorChan <- fmt.Errorf("error reading from error stream for port %d: %v", port, err)
+		case len(message) > 0:
+			errorChan <- fmt.Errorf("an error occurred forwarding port %d: %v", port, string(message))
+		}
+		close(errorChan)
+	}()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		return fmt.Errorf("error creating forwarding stream for port %d: %v", port, err)
+	}
+	defer conn.RemoveStreams(dataStream)
+
+	return copyStreams(ctx, stream, dataStream, errorChan)
+}
+
+// copyStreams copies data both ways between the local and remote streams,
+// until the remote side is done, either side fails, or the context is canceled
+func copyStreams(ctx context.Context, local io.ReadWriter, remote httpstream.Stream, errorChan <-chan error) error {
+	copyError := make(chan error, 2)
+	remoteDone := make(chan struct{})
+
+	go func() {
+		// copy from the remote side to the local side
+		if _, err := io.Copy(local, remote); err != nil {
+			copyError <- fmt.Errorf("error copying from remote stream: %v", err)
+		}
+		close(remoteDone)
+	}()
+
+	go func() {
+		// inform the remote side we're not sending any more data after copy unblocks
+		defer remote.Close()
+
+		// copy from the local side to the remote side
+		if _, err := io.Copy(remote, local); err != nil {
+			copyError <- fmt.Errorf("error copying to remote stream: %v", err)
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case err := <-copyError:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// always expect something on errorChan (it may be nil)
+	return <-errorChan
+}
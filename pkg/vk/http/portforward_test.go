@@ -0,0 +1,59 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStream struct {
+	io.Reader
+	io.Writer
+}
+
+func (s fakeStream) Close() error         { return nil }
+func (s fakeStream) Reset() error         { return nil }
+func (s fakeStream) Headers() http.Header { return nil }
+func (s fakeStream) Identifier() uint32   { return 0 }
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+func TestCopyStreams(t *testing.T) {
+	var local bytes.Buffer
+	errorChan := make(chan error)
+	close(errorChan)
+	err := copyStreams(context.Background(), readWriter{strings.NewReader("ping"), &local}, fakeStream{strings.NewReader("pong"), io.Discard}, errorChan)
+	require.NoError(t, err)
+	require.Equal(t, "pong", local.String())
+
+	errorChan = make(chan error, 1)
+	errorChan <- errors.New("connection refused")
+	close(errorChan)
+	err = copyStreams(context.Background(), readWriter{strings.NewReader("ping"), io.Discard}, fakeStream{strings.NewReader(""), io.Discard}, errorChan)
+	require.EqualError(t, err, "connection refused")
+}
@@ -20,28 +20,130 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/virtual-kubelet/virtual-kubelet/node/api"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/remotecommand"
 
 	"admiralty.io/multicluster-scheduler/pkg/common"
+	"admiralty.io/multicluster-scheduler/pkg/config/agent"
 	"admiralty.io/multicluster-scheduler/pkg/model/proxypod"
 )
 
-type LogsExecProvider struct {
-	SourceClient  kubernetes.Interface
-	TargetConfigs map[string]*rest.Config
-	TargetClients map[string]kubernetes.Interface
+// ProxyPodProvider serves the logs, exec and stats of proxy pods from their delegate pods in target clusters
+type ProxyPodProvider struct {
+	SourceClient kubernetes.Interface
+
+	ctx context.Context
+
+	// proxyPods caches proxy pods, indexed by node name, for stats
+	proxyPods       cache.Indexer
+	proxyPodsSynced cache.InformerSynced
+
+	mu               sync.RWMutex
+	targetConfigs    map[string]*rest.Config
+	targetClients    map[string]kubernetes.Interface
+	targetNamespaces map[string]string
+	// delegatePods caches delegate pods in target clusters, for stats
+	delegatePods  map[string]cache.SharedIndexInformer
+	targetCancels map[string]context.CancelFunc
+
+	nodeStats *nodeStatsCache
+}
+
+var _ agent.TargetHandler = &ProxyPodProvider{}
+
+// NewProxyPodProvider returns a provider whose informers run until ctx is done.
+func NewProxyPodProvider(ctx context.Context, sourceClient kubernetes.Interface) *ProxyPodProvider {
+	f := informers.NewSharedInformerFactoryWithOptions(sourceClient, 30*time.Second,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("spec.schedulerName", common.ProxySchedulerName).String()
+		}))
+	proxyPodInformer := f.Core().V1().Pods().Informer()
+	utilruntime.Must(proxyPodInformer.AddIndexers(cache.Indexers{proxyPodByNodeName: indexPodByNodeName}))
+	f.Start(ctx.Done())
+
+	return &ProxyPodProvider{
+		SourceClient:     sourceClient,
+		ctx:              ctx,
+		proxyPods:        proxyPodInformer.GetIndexer(),
+		proxyPodsSynced:  proxyPodInformer.HasSynced,
+		targetConfigs:    map[string]*rest.Config{},
+		targetClients:    map[string]kubernetes.Interface{},
+		targetNamespaces: map[string]string{},
+		delegatePods:     map[string]cache.SharedIndexInformer{},
+		targetCancels:    map[string]context.CancelFunc{},
+		nodeStats:        newNodeStatsCache(),
+	}
+}
+
+func (p *ProxyPodProvider) AddTarget(t agent.Target) {
+	targetClient, err := kubernetes.NewForConfig(t.ClientConfig)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("cannot create client for target %s: %v", t.VirtualNodeName, err))
+		return
+	}
+	p.addTarget(t.VirtualNodeName, t.Namespace, t.ClientConfig, targetClient)
+}
+
+func (p *ProxyPodProvider) addTarget(targetName, namespace string, targetConfig *rest.Config, targetClient kubernetes.Interface) {
+	f := informers.NewSharedInformerFactoryWithOptions(targetClient, 30*time.Second,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = common.LabelKeyParentUID
+		}))
+	delegatePodInformer := f.Core().V1().Pods().Informer()
+	ctx, cancel := context.WithCancel(p.ctx)
+	f.Start(ctx.Done())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.targetCancels[targetName]; ok {
+		cancel()
+	}
+	p.targetConfigs[targetName] = targetConfig
+	p.targetClients[targetName] = targetClient
+	p.targetNamespaces[targetName] = namespace
+	p.delegatePods[targetName] = delegatePodInformer
+	p.targetCancels[targetName] = cancel
+	p.nodeStats.forgetTarget(targetName)
+}
+
+func (p *ProxyPodProvider) RemoveTarget(t agent.Target) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cancel, ok := p.targetCancels[t.VirtualNodeName]; ok {
+		cancel()
+	}
+	delete(p.targetConfigs, t.VirtualNodeName)
+	delete(p.targetClients, t.VirtualNodeName)
+	delete(p.targetNamespaces, t.VirtualNodeName)
+	delete(p.delegatePods, t.VirtualNodeName)
+	delete(p.targetCancels, t.VirtualNodeName)
+	p.nodeStats.forgetTarget(t.VirtualNodeName)
+}
+
+func (p *ProxyPodProvider) getTarget(targetName string) (*rest.Config, kubernetes.Interface, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	client, ok := p.targetClients[targetName]
+	return p.targetConfigs[targetName], client, ok
 }
 
 // GetContainerLogs retrieves the logs of a container by name from the provider.
-func (p *LogsExecProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts api.ContainerLogOpts) (io.ReadCloser, error) {
+func (p *ProxyPodProvider) GetContainerLogs(ctx context.Context, namespace, podName, containerName string, opts api.ContainerLogOpts) (io.ReadCloser, error) {
 	targetName, delegatePodName, err := p.getTargetAndDelegatePodNames(ctx, namespace, podName)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot get delegate pod name")
@@ -69,7 +171,11 @@ func (p *LogsExecProvider) GetContainerLogs(ctx context.Context, namespace, podN
 		options.SinceTime = &metav1.Time{Time: opts.SinceTime}
 	}
 
-	logs := p.TargetClients[targetName].CoreV1().Pods(namespace).GetLogs(delegatePodName, options)
+	_, targetClient, ok := p.getTarget(targetName)
+	if !ok {
+		return nil, errors.Errorf("not a current target name")
+	}
+	logs := targetClient.CoreV1().Pods(namespace).GetLogs(delegatePodName, options)
 	stream, err := logs.Stream(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("could not get stream from logs request: %v", err)
@@ -77,7 +183,7 @@ func (p *LogsExecProvider) GetContainerLogs(ctx context.Context, namespace, podN
 	return stream, nil
 }
 
-func (p *LogsExecProvider) getTargetAndDelegatePodNames(ctx context.Context, namespace string, podName string) (string, string, error) {
+func (p *ProxyPodProvider) getTargetAndDelegatePodNames(ctx context.Context, namespace string, podName string) (string, string, error) {
 	proxyPod, err := p.SourceClient.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
 		return "", "", errors.Wrap(err, "cannot get proxy pod")
@@ -86,10 +192,11 @@ func (p *LogsExecProvider) getTargetAndDelegatePodNames(ctx context.Context, nam
 	if targetName == "" {
 		return "", "", errors.Errorf("proxy pod isn't scheduled yet")
 	}
-	if _, ok := p.TargetClients[targetName]; !ok {
+	_, targetClient, ok := p.getTarget(targetName)
+	if !ok {
 		return "", "", errors.Errorf("not a current target name")
 	}
-	l, err := p.TargetClients[targetName].CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(proxyPod.UID)})
+	l, err := targetClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: common.LabelKeyParentUID + "=" + string(proxyPod.UID)})
 	if err != nil {
 		return "", "", errors.Wrap(err, "cannot list delegate pod")
 	}
@@ -105,7 +212,31 @@ func (p *LogsExecProvider) getTargetAndDelegatePodNames(ctx context.Context, nam
 
 // RunInContainer executes a command in a container in the pod, copying data
 // between in/out/err and the container's stdin/stdout/stderr.
-func (p *LogsExecProvider) RunInContainer(ctx context.Context, namespace, name, container string, cmd []string, attach api.AttachIO) error {
+func (p *ProxyPodProvider) RunInContainer(ctx context.Context, namespace, name, container string, cmd []string, attach api.AttachIO) error {
+	return p.stream(ctx, namespace, name, "exec", &v1.PodExecOptions{
+		Container: container,
+		Command:   cmd,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}, attach)
+}
+
+// AttachToContainer attaches to the main process of a container in the pod, copying data
+// between in/out/err and the container's stdin/stdout/stderr.
+func (p *ProxyPodProvider) AttachToContainer(ctx context.Context, namespace, name, container string, attach api.AttachIO) error {
+	return p.stream(ctx, namespace, name, "attach", &v1.PodAttachOptions{
+		Container: container,
+		Stdin:     attach.Stdin() != nil,
+		Stdout:    attach.Stdout() != nil,
+		Stderr:    attach.Stderr() != nil,
+		TTY:       attach.TTY(),
+	}, attach)
+}
+
+// stream streams in/out/err to the exec or attach subresource of the delegate pod in the target cluster
+func (p *ProxyPodProvider) stream(ctx context.Context, namespace, name, subresource string, opts runtime.Object, attach api.AttachIO) error {
 	defer func() {
 		if attach.Stdout() != nil {
 			attach.Stdout().Close()
@@ -120,30 +251,27 @@ func (p *LogsExecProvider) RunInContainer(ctx context.Context, namespace, name,
 		return errors.Wrap(err, "cannot get delegate pod name")
 	}
 
-	req := p.TargetClients[targetName].CoreV1().RESTClient().
+	targetConfig, targetClient, ok := p.getTarget(targetName)
+	if !ok {
+		return errors.Errorf("not a current target name")
+	}
+	req := targetClient.CoreV1().RESTClient().
 		Post().
 		Namespace(namespace).
 		Resource("pods").
 		Name(delegatePodName).
-		SubResource("exec").
+		SubResource(subresource).
 		Timeout(0).
-		VersionedParams(&v1.PodExecOptions{
-			Container: container,
-			Command:   cmd,
-			Stdin:     attach.Stdin() != nil,
-			Stdout:    attach.Stdout() != nil,
-			Stderr:    attach.Stderr() != nil,
-			TTY:       attach.TTY(),
-		}, scheme.ParameterCodec)
-
-	exec, err := remotecommand.NewSPDYExecutor(p.TargetConfigs[targetName], "POST", req.URL())
+		VersionedParams(opts, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(targetConfig, "POST", req.URL())
 	if err != nil {
 		return fmt.Errorf("could not make remote command: %v", err)
 	}
 
 	ts := &termSize{attach: attach}
 
-	err = exec.Stream(remotecommand.StreamOptions{
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
 		Stdin:             attach.Stdin(),
 		Stdout:            attach.Stdout(),
 		Stderr:            attach.Stderr(),
@@ -162,7 +290,10 @@ type termSize struct {
 }
 
 func (t *termSize) Next() *remotecommand.TerminalSize {
-	resize := <-t.attach.Resize()
+	resize, ok := <-t.attach.Resize()
+	if !ok {
+		return nil
+	}
 	return &remotecommand.TerminalSize{
 		Height: resize.Height,
 		Width:  resize.Width,
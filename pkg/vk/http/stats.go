@@ -0,0 +1,277 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// All virtual nodes share the same kubelet endpoint (the agent pod IP), so we can't tell which one is scraped.
+// We serve the stats of all proxy pods bound to any virtual node, and no node-level stats.
+
+// delegatePods maps the namespaced names of delegate pods, per target node, to their proxy pods
+type delegatePods map[string]map[types.NamespacedName]*v1.Pod
+
+// statsCacheTTL is how long the stats summaries and resource metrics of target nodes are cached.
+// Virtual nodes share an endpoint, so one scrape per virtual node would otherwise query every target node as many times.
+const statsCacheTTL = 10 * time.Second
+
+// GetStatsSummary gets the stats summaries of the target nodes running delegate pods, through the target API servers' node proxies,
+// and renames the delegate pods' stats after their proxy pods.
+func (p *ProxyPodProvider) GetStatsSummary(ctx context.Context) (*statsv1alpha1.Summary, error) {
+	s := &statsv1alpha1.Summary{Node: statsv1alpha1.NodeStats{StartTime: metav1.NewTime(time.Now())}}
+	p.forEachTargetNode(ctx, "stats/summary", func(b []byte, delegates map[types.NamespacedName]*v1.Pod) error {
+		nodeSummary := &statsv1alpha1.Summary{}
+		if err := json.Unmarshal(b, nodeSummary); err != nil {
+			return err
+		}
+		s.Pods = append(s.Pods, proxyPodStats(nodeSummary.Pods, delegates)...)
+		return nil
+	})
+	return s, nil
+}
+
+// GetMetricsResource gets the resource metrics of the target nodes running delegate pods, through the target API servers' node proxies,
+// and relabels the delegate pods' metrics after their proxy pods.
+func (p *ProxyPodProvider) GetMetricsResource(ctx context.Context) ([]*dto.MetricFamily, error) {
+	familiesByName := map[string]*dto.MetricFamily{}
+	p.forEachTargetNode(ctx, "metrics/resource", func(b []byte, delegates map[types.NamespacedName]*v1.Pod) error {
+		var parser expfmt.TextParser
+		families, err := parser.TextToMetricFamilies(bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+		mergeProxyPodMetrics(familiesByName, families, delegates)
+		return nil
+	})
+
+	names := make([]string, 0, len(familiesByName))
+	for name := range familiesByName {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	families := make([]*dto.MetricFamily, 0, len(names))
+	for _, name := range names {
+		families = append(families, familiesByName[name])
+	}
+	return families, nil
+}
+
+// forEachTargetNode gets a path of the kubelet API (through the node proxy) of each node of each target running delegate pods,
+// from the cache if fresh, and calls f with the response. Errors are handled per target node, to serve partial stats rather than none,
+// and only logged when fetched, i.e., at most once per statsCacheTTL per target node.
+func (p *ProxyPodProvider) forEachTargetNode(ctx context.Context, path string, f func(b []byte, delegates map[types.NamespacedName]*v1.Pod) error) {
+	p.mu.RLock()
+	targetNames := make([]string, 0, len(p.targetClients))
+	for targetName := range p.targetClients {
+		targetNames = append(targetNames, targetName)
+	}
+	p.mu.RUnlock()
+	slices.Sort(targetNames)
+
+	for _, targetName := range targetNames {
+		targetClient, byNode, ok := p.getDelegatePods(targetName)
+		if !ok {
+			continue
+		}
+		for nodeName, delegates := range byNode {
+			b, cached, err := p.nodeStats.get(targetName, nodeName, path, func() ([]byte, error) {
+				return targetClient.CoreV1().RESTClient().Get().Resource("nodes").Name(nodeName).SubResource("proxy").Suffix(path).DoRaw(ctx)
+			})
+			if err == nil {
+				err = f(b, delegates)
+			}
+			if err != nil && !cached {
+				utilruntime.HandleError(fmt.Errorf("cannot get %s of node %s in target %s: %v", path, nodeName, targetName, err))
+			}
+		}
+	}
+}
+
+// getDelegatePods groups the delegate pods of a target by node name, from the informers' caches.
+// It returns false if the target was removed or its caches haven't synced yet.
+func (p *ProxyPodProvider) getDelegatePods(targetName string) (kubernetes.Interface, delegatePods, bool) {
+	p.mu.RLock()
+	targetClient, ok := p.targetClients[targetName]
+	delegatePodInformer := p.delegatePods[targetName]
+	p.mu.RUnlock()
+	if !ok || !p.proxyPodsSynced() || !delegatePodInformer.HasSynced() {
+		return nil, nil, false
+	}
+
+	proxyPods, err := p.proxyPods.ByIndex(proxyPodByNodeName, targetName)
+	utilruntime.Must(err) // the index exists
+	if len(proxyPods) == 0 {
+		return targetClient, nil, true
+	}
+	proxyPodsByUID := make(map[types.UID]*v1.Pod, len(proxyPods))
+	for _, obj := range proxyPods {
+		proxyPod := obj.(*v1.Pod)
+		proxyPodsByUID[proxyPod.UID] = proxyPod
+	}
+
+	byNode := delegatePods{}
+	for _, obj := range delegatePodInformer.GetStore().List() {
+		delegatePod := obj.(*v1.Pod)
+		proxyPod, ok := proxyPodsByUID[types.UID(delegatePod.Labels[common.LabelKeyParentUID])]
+		if !ok || delegatePod.Spec.NodeName == "" {
+			continue
+		}
+		if byNode[delegatePod.Spec.NodeName] == nil {
+			byNode[delegatePod.Spec.NodeName] = map[types.NamespacedName]*v1.Pod{}
+		}
+		byNode[delegatePod.Spec.NodeName][types.NamespacedName{Namespace: delegatePod.Namespace, Name: delegatePod.Name}] = proxyPod
+	}
+	return targetClient, byNode, true
+}
+
+const proxyPodByNodeName = "proxyPodByNodeName"
+
+func indexPodByNodeName(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
+type nodeStatsKey struct {
+	targetName, nodeName, path string
+}
+
+type nodeStatsEntry struct {
+	b       []byte
+	err     error
+	expires time.Time
+}
+
+// nodeStatsCache caches the responses (and errors) of target nodes' kubelet APIs for statsCacheTTL
+type nodeStatsCache struct {
+	mu      sync.Mutex
+	entries map[nodeStatsKey]nodeStatsEntry
+	now     func() time.Time
+}
+
+func newNodeStatsCache() *nodeStatsCache {
+	return &nodeStatsCache{entries: map[nodeStatsKey]nodeStatsEntry{}, now: time.Now}
+}
+
+// get returns the cached response if fresh, or calls fetch and caches its response otherwise.
+// Concurrent scrapes may fetch the same response, that's ok. It also returns whether the response came from the cache.
+func (c *nodeStatsCache) get(targetName, nodeName, path string, fetch func() ([]byte, error)) ([]byte, bool, error) {
+	k := nodeStatsKey{targetName: targetName, nodeName: nodeName, path: path}
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[k]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.b, true, e.err
+	}
+
+	b, err := fetch()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// drop expired entries, e.g., of nodes that don't run delegate pods anymore
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[k] = nodeStatsEntry{b: b, err: err, expires: now.Add(statsCacheTTL)}
+	return b, false, err
+}
+
+// forgetTarget drops the cached responses of a target's nodes, e.g., when its kubeconfig changes
+func (c *nodeStatsCache) forgetTarget(targetName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.entries {
+		if k.targetName == targetName {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// proxyPodStats filters the stats of delegate pods and renames them after their proxy pods
+func proxyPodStats(stats []statsv1alpha1.PodStats, delegates map[types.NamespacedName]*v1.Pod) []statsv1alpha1.PodStats {
+	var out []statsv1alpha1.PodStats
+	for _, s := range stats {
+		proxyPod, ok := delegates[types.NamespacedName{Namespace: s.PodRef.Namespace, Name: s.PodRef.Name}]
+		if !ok {
+			continue
+		}
+		s.PodRef = statsv1alpha1.PodReference{Namespace: proxyPod.Namespace, Name: proxyPod.Name, UID: string(proxyPod.UID)}
+		out = append(out, s)
+	}
+	return out
+}
+
+// mergeProxyPodMetrics merges the pod and container metrics of delegate pods into familiesByName,
+// relabeled after their proxy pods. Node-level metrics are dropped.
+func mergeProxyPodMetrics(familiesByName map[string]*dto.MetricFamily, families map[string]*dto.MetricFamily, delegates map[types.NamespacedName]*v1.Pod) {
+	for name, family := range families {
+		var metrics []*dto.Metric
+		for _, m := range family.Metric {
+			var namespace, pod *dto.LabelPair
+			for _, l := range m.Label {
+				switch l.GetName() {
+				case "namespace":
+					namespace = l
+				case "pod":
+					pod = l
+				}
+			}
+			if namespace == nil || pod == nil {
+				continue
+			}
+			proxyPod, ok := delegates[types.NamespacedName{Namespace: namespace.GetValue(), Name: pod.GetValue()}]
+			if !ok {
+				continue
+			}
+			namespace.Value = &proxyPod.Namespace
+			pod.Value = &proxyPod.Name
+			metrics = append(metrics, m)
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+		merged, ok := familiesByName[name]
+		if !ok {
+			merged = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+			familiesByName[name] = merged
+		}
+		merged.Metric = append(merged.Metric, metrics...)
+	}
+}
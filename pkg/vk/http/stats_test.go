@@ -0,0 +1,158 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/require"
+	"github.com/virtual-kubelet/virtual-kubelet/node/api/statsv1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+var testDelegates = map[types.NamespacedName]*v1.Pod{
+	{Namespace: "ns", Name: "proxy-abcde"}: {ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy", UID: "proxy-uid"}},
+}
+
+func TestProxyPodStats(t *testing.T) {
+	stats := []statsv1alpha1.PodStats{
+		{PodRef: statsv1alpha1.PodReference{Namespace: "ns", Name: "proxy-abcde", UID: "delegate-uid"}, Containers: []statsv1alpha1.ContainerStats{{Name: "c"}}},
+		{PodRef: statsv1alpha1.PodReference{Namespace: "ns", Name: "other", UID: "other-uid"}},
+	}
+	require.Equal(t, []statsv1alpha1.PodStats{
+		{PodRef: statsv1alpha1.PodReference{Namespace: "ns", Name: "proxy", UID: "proxy-uid"}, Containers: []statsv1alpha1.ContainerStats{{Name: "c"}}},
+	}, proxyPodStats(stats, testDelegates))
+}
+
+func TestMergeProxyPodMetrics(t *testing.T) {
+	in := `# HELP container_cpu_usage_seconds_total [STABLE] Cumulative cpu time consumed by the container in core-seconds
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="c",namespace="ns",pod="proxy-abcde"} 1.5 1700000000000
+container_cpu_usage_seconds_total{container="c",namespace="ns",pod="other"} 2.5 1700000000000
+# HELP node_cpu_usage_seconds_total [STABLE] Cumulative cpu time consumed by the node in core-seconds
+# TYPE node_cpu_usage_seconds_total counter
+node_cpu_usage_seconds_total 100 1700000000000
+# HELP pod_memory_working_set_bytes [STABLE] Current working set of the pod in bytes
+# TYPE pod_memory_working_set_bytes gauge
+pod_memory_working_set_bytes{namespace="ns",pod="proxy-abcde"} 1024 1700000000000
+`
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(in))
+	require.NoError(t, err)
+
+	merged := map[string]*dto.MetricFamily{}
+	mergeProxyPodMetrics(merged, families, testDelegates)
+	require.Len(t, merged, 2)
+
+	var out bytes.Buffer
+	for _, name := range []string{"container_cpu_usage_seconds_total", "pod_memory_working_set_bytes"} {
+		_, err := expfmt.MetricFamilyToText(&out, merged[name])
+		require.NoError(t, err)
+	}
+	require.Equal(t, `# HELP container_cpu_usage_seconds_total [STABLE] Cumulative cpu time consumed by the container in core-seconds
+# TYPE container_cpu_usage_seconds_total counter
+container_cpu_usage_seconds_total{container="c",namespace="ns",pod="proxy"} 1.5 1700000000000
+# HELP pod_memory_working_set_bytes [STABLE] Current working set of the pod in bytes
+# TYPE pod_memory_working_set_bytes gauge
+pod_memory_working_set_bytes{namespace="ns",pod="proxy"} 1024 1700000000000
+`, out.String())
+}
+
+func TestGetDelegatePods(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	proxyPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy", UID: "proxy-uid"},
+		Spec:       v1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: "admiralty-cloud"},
+	}
+	otherProxyPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other", UID: "other-uid"},
+		Spec:       v1.PodSpec{SchedulerName: common.ProxySchedulerName, NodeName: "admiralty-on-prem"},
+	}
+	delegatePod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "proxy-abcde", Labels: map[string]string{common.LabelKeyParentUID: "proxy-uid"}},
+		Spec:       v1.PodSpec{NodeName: "node-1"},
+	}
+	pendingDelegatePod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other-abcde", Labels: map[string]string{common.LabelKeyParentUID: "other-uid"}},
+	}
+
+	p := NewProxyPodProvider(ctx, fake.NewSimpleClientset(proxyPod, otherProxyPod))
+	targetClient := fake.NewSimpleClientset(delegatePod, pendingDelegatePod)
+	p.addTarget("admiralty-cloud", "ns", &rest.Config{Host: "https://cloud"}, targetClient)
+
+	var byNode delegatePods
+	require.Eventually(t, func() bool {
+		var ok bool
+		_, byNode, ok = p.getDelegatePods("admiralty-cloud")
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, delegatePods{"node-1": {{Namespace: "ns", Name: "proxy-abcde"}: proxyPod}}, byNode)
+
+	_, _, ok := p.getDelegatePods("admiralty-on-prem")
+	require.False(t, ok)
+}
+
+func TestNodeStatsCache(t *testing.T) {
+	c := newNodeStatsCache()
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	fetches := 0
+	fetch := func() ([]byte, error) {
+		fetches++
+		return nil, errors.New("forbidden")
+	}
+
+	_, cached, err := c.get("a", "node-1", "stats/summary", fetch)
+	require.Error(t, err)
+	require.False(t, cached)
+
+	// errors are cached too, so they're only logged once per TTL
+	_, cached, err = c.get("a", "node-1", "stats/summary", fetch)
+	require.Error(t, err)
+	require.True(t, cached)
+	require.Equal(t, 1, fetches)
+
+	// different path
+	_, cached, _ = c.get("a", "node-1", "metrics/resource", fetch)
+	require.False(t, cached)
+	require.Equal(t, 2, fetches)
+
+	now = now.Add(statsCacheTTL)
+	_, cached, _ = c.get("a", "node-1", "stats/summary", fetch)
+	require.False(t, cached)
+	require.Equal(t, 3, fetches)
+	require.Len(t, c.entries, 1) // the expired metrics entry was dropped
+
+	c.forgetTarget("a")
+	require.Empty(t, c.entries)
+}
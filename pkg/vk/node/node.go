@@ -31,7 +31,7 @@ func NodeFromOpts(t agent.Target) *v1.Node {
 	node := &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   t.VirtualNodeName,
-			Labels: virtualnode.BaseLabels(t.Namespace, t.Name),
+			Labels: virtualnode.BaseLabels(t),
 		},
 		Spec: v1.NodeSpec{
 			Taints: []v1.Taint{
@@ -43,6 +43,8 @@ func NodeFromOpts(t agent.Target) *v1.Node {
 			},
 		},
 		Status: v1.NodeStatus{
+			// initial conditions, until the upstream resources controller probes the target cluster (see virtualnode.Conditions);
+			// the virtual node is ready by default, so that proxy pods aren't evicted when the agent restarts
 			Conditions: []v1.NodeCondition{
 				{
 					Type:               v1.NodeReady,
@@ -50,30 +52,25 @@ func NodeFromOpts(t agent.Target) *v1.Node {
 					LastHeartbeatTime:  metav1.Now(),
 					LastTransitionTime: metav1.Now(),
 				},
-				//{
-				//	Type:               v1.NodeMemoryPressure,
-				//	Status:             v1.ConditionFalse,
-				//	LastHeartbeatTime:  metav1.Now(),
-				//	LastTransitionTime: metav1.Now(),
-				//},
-				//{
-				//	Type:               v1.NodeDiskPressure,
-				//	Status:             v1.ConditionFalse,
-				//	LastHeartbeatTime:  metav1.Now(),
-				//	LastTransitionTime: metav1.Now(),
-				//},
-				//{
-				//	Type:               v1.NodePIDPressure,
-				//	Status:             v1.ConditionFalse,
-				//	LastHeartbeatTime:  metav1.Now(),
-				//	LastTransitionTime: metav1.Now(),
-				//},
-				//{
-				//	Type:               v1.NodeNetworkUnavailable,
-				//	Status:             v1.ConditionFalse,
-				//	LastHeartbeatTime:  metav1.Now(),
-				//	LastTransitionTime: metav1.Now(),
-				//},
+				{
+					Type:               v1.NodeMemoryPressure,
+					Status:             v1.ConditionFalse,
+					LastHeartbeatTime:  metav1.Now(),
+					LastTransitionTime: metav1.Now(),
+				},
+				{
+					Type:               v1.NodeDiskPressure,
+					Status:             v1.ConditionFalse,
+					LastHeartbeatTime:  metav1.Now(),
+					LastTransitionTime: metav1.Now(),
+				},
+				{
+					Type:               v1.NodePIDPressure,
+					Status:             v1.ConditionFalse,
+					LastHeartbeatTime:  metav1.Now(),
+					LastTransitionTime: metav1.Now(),
+				},
+				// NetworkUnavailable is set by cloud route controllers, if any (proxy pods tolerate the corresponding taint anyway)
 			},
 			Addresses: []v1.NodeAddress{
 				{
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxypod
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// applyPolicy finds the MulticlusterPolicy or ClusterMulticlusterPolicy that applies to the pod, if any,
+// and adds the equivalent annotations that the pod doesn't have already, and AnnotationKeyPolicy.
+// Namespaced policies take precedence over cluster-scoped policies; within a scope, the first matching policy by name applies.
+// Delegate and candidate pods are never elected, even if they match a policy,
+// e.g., with a self target, or clusters targeting each other.
+func applyPolicy(ctx context.Context, c client.Reader, pod *corev1.Pod, namespace string) error {
+	if isDelegateOrCandidate(pod) {
+		return nil
+	}
+
+	spec, policyName, err := resolvePolicy(ctx, c, pod, namespace)
+	if err != nil {
+		return err
+	}
+	if spec == nil {
+		return nil
+	}
+
+	policyAnnotations, err := policyAnnotations(spec)
+	if err != nil {
+		return fmt.Errorf("invalid policy %s: %v", policyName, err)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	for k, v := range policyAnnotations {
+		if _, ok := pod.Annotations[k]; !ok {
+			pod.Annotations[k] = v
+		}
+	}
+	pod.Annotations[common.AnnotationKeyPolicy] = policyName
+	return nil
+}
+
+func resolvePolicy(ctx context.Context, c client.Reader, pod *corev1.Pod, namespace string) (*v1alpha1.MulticlusterPolicySpec, string, error) {
+	podLabels := labels.Set(pod.Labels)
+
+	policies := &v1alpha1.MulticlusterPolicyList{}
+	if err := c.List(ctx, policies, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			// CRDs not installed (yet), e.g., during an upgrade
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("cannot list multicluster policies: %v", err)
+	}
+	sort.Slice(policies.Items, func(i, j int) bool { return policies.Items[i].Name < policies.Items[j].Name })
+	for _, p := range policies.Items {
+		ok, err := matches(p.Spec.PodSelector, podLabels)
+		if err != nil {
+			// one invalid policy mustn't prevent all pod creations in the namespace
+			utilruntime.HandleError(fmt.Errorf("skipping MulticlusterPolicy %s in namespace %s: invalid pod selector: %v", p.Name, namespace, err))
+			continue
+		}
+		if ok {
+			return &p.Spec, "MulticlusterPolicy/" + p.Name, nil
+		}
+	}
+
+	clusterPolicies := &v1alpha1.ClusterMulticlusterPolicyList{}
+	if err := c.List(ctx, clusterPolicies); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("cannot list cluster multicluster policies: %v", err)
+	}
+	if len(clusterPolicies.Items) == 0 {
+		return nil, "", nil
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, "", fmt.Errorf("cannot get namespace: %v", err)
+	}
+	nsLabels := labels.Set(ns.Labels)
+	sort.Slice(clusterPolicies.Items, func(i, j int) bool { return clusterPolicies.Items[i].Name < clusterPolicies.Items[j].Name })
+	for _, p := range clusterPolicies.Items {
+		nsOK, err := matches(p.Spec.NamespaceSelector, nsLabels)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("skipping ClusterMulticlusterPolicy %s: invalid namespace selector: %v", p.Name, err))
+			continue
+		}
+		podOK, err := matches(p.Spec.PodSelector, podLabels)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("skipping ClusterMulticlusterPolicy %s: invalid pod selector: %v", p.Name, err))
+			continue
+		}
+		if nsOK && podOK {
+			return &p.Spec.MulticlusterPolicySpec, "ClusterMulticlusterPolicy/" + p.Name, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// isDelegateOrCandidate returns true if a pod is controlled by a PodChaperon, or has the parent UID label of delegate pods
+func isDelegateOrCandidate(pod *corev1.Pod) bool {
+	if ref := metav1.GetControllerOf(pod); ref != nil && ref.Kind == "PodChaperon" && strings.HasPrefix(ref.APIVersion, v1alpha1.SchemeGroupVersion.Group+"/") {
+		return true
+	}
+	_, ok := pod.Labels[common.LabelKeyParentUID]
+	return ok
+}
+
+func matches(selector *metav1.LabelSelector, l labels.Set) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(l), nil
+}
+
+// policyAnnotations returns the annotations equivalent to a policy spec
+func policyAnnotations(spec *v1alpha1.MulticlusterPolicySpec) (map[string]string, error) {
+	a := map[string]string{common.AnnotationKeyElect: ""}
+	if spec.NoReservation {
+		a[common.AnnotationKeyNoReservation] = ""
+	}
+	if c := spec.ProxyPodSchedulingConstraints; c != nil {
+		// same field names as in the annotation's format (a partial pod spec)
+		s, err := yaml.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		a[common.AnnotationKeyProxyPodSchedulingConstraints] = string(s)
+	}
+	if spec.UseConstraintsFromSpecForProxyPodScheduling {
+		a[common.AnnotationKeyUseConstraintsFromSpecForProxyPodScheduling] = ""
+	}
+	if spec.NoPrefixLabelRegexp != "" {
+		a[common.AnnotationNoPrefixLabelRegexp] = spec.NoPrefixLabelRegexp
+	}
+	return a, nil
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxypod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/common"
+	customscheme "admiralty.io/multicluster-scheduler/pkg/generated/clientset/versioned/scheme"
+)
+
+func TestApplyPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, kubescheme.AddToScheme(scheme))
+	require.NoError(t, customscheme.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{"team": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{"team": "b"}}},
+		&v1alpha1.MulticlusterPolicy{
+			// skipped, rather than failing all pod creations in the namespace
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "0-invalid"},
+			Spec: v1alpha1.MulticlusterPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bogus"}}},
+			},
+		},
+		&v1alpha1.MulticlusterPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "b-batch"},
+			Spec: v1alpha1.MulticlusterPolicySpec{
+				PodSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "batch"}},
+				NoReservation: true,
+			},
+		},
+		&v1alpha1.MulticlusterPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "a-batch"},
+			Spec: v1alpha1.MulticlusterPolicySpec{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "batch"}},
+				ProxyPodSchedulingConstraints: &v1alpha1.ProxyPodSchedulingConstraints{
+					NodeSelector: map[string]string{"topology.kubernetes.io/region": "us"},
+				},
+				NoPrefixLabelRegexp: "^app$",
+			},
+		},
+		&v1alpha1.ClusterMulticlusterPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a"},
+			Spec: v1alpha1.ClusterMulticlusterPolicySpec{
+				NamespaceSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				MulticlusterPolicySpec: v1alpha1.MulticlusterPolicySpec{UseConstraintsFromSpecForProxyPodScheduling: true},
+			},
+		},
+	).Build()
+	ctx := context.Background()
+
+	pod := func(namespace string, l map[string]string, a map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "pod", Labels: l, Annotations: a}}
+	}
+
+	for _, tc := range []struct {
+		name string
+		pod  *corev1.Pod
+		want map[string]string
+	}{{
+		name: "namespaced policies take precedence, first by name",
+		pod:  pod("ns", map[string]string{"app": "batch"}, nil),
+		want: map[string]string{
+			common.AnnotationKeyElect:                         "",
+			common.AnnotationKeyProxyPodSchedulingConstraints: "nodeSelector:\n  topology.kubernetes.io/region: us\n",
+			common.AnnotationNoPrefixLabelRegexp:              "^app$",
+			common.AnnotationKeyPolicy:                        "MulticlusterPolicy/a-batch",
+		},
+	}, {
+		name: "pod annotations take precedence",
+		pod:  pod("ns", map[string]string{"app": "batch"}, map[string]string{common.AnnotationNoPrefixLabelRegexp: "^foo$"}),
+		want: map[string]string{
+			common.AnnotationKeyElect:                         "",
+			common.AnnotationKeyProxyPodSchedulingConstraints: "nodeSelector:\n  topology.kubernetes.io/region: us\n",
+			common.AnnotationNoPrefixLabelRegexp:              "^foo$",
+			common.AnnotationKeyPolicy:                        "MulticlusterPolicy/a-batch",
+		},
+	}, {
+		name: "cluster policy",
+		pod:  pod("ns", map[string]string{"app": "web"}, nil),
+		want: map[string]string{
+			common.AnnotationKeyElect: "",
+			common.AnnotationKeyUseConstraintsFromSpecForProxyPodScheduling: "",
+			common.AnnotationKeyPolicy:                                      "ClusterMulticlusterPolicy/team-a",
+		},
+	}, {
+		name: "delegate pod",
+		pod: func() *corev1.Pod {
+			p := pod("ns", map[string]string{"app": "batch"}, nil)
+			p.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(&v1alpha1.PodChaperon{}, v1alpha1.SchemeGroupVersion.WithKind("PodChaperon"))}
+			return p
+		}(),
+		want: nil,
+	}, {
+		name: "candidate pod",
+		pod:  pod("ns", map[string]string{"app": "batch", common.LabelKeyParentUID: "uid"}, nil),
+		want: nil,
+	}, {
+		name: "no policy",
+		pod:  pod("other", map[string]string{"app": "batch"}, nil),
+		want: nil,
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, applyPolicy(ctx, c, tc.pod, tc.pod.Namespace))
+			require.Equal(t, tc.want, tc.pod.Annotations)
+		})
+	}
+}
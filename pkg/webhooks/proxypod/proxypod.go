@@ -23,13 +23,24 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"sigs.k8s.io/yaml"
 
 	"admiralty.io/multicluster-scheduler/pkg/common"
+	proxypodmodel "admiralty.io/multicluster-scheduler/pkg/model/proxypod"
 )
 
 type Mutator struct {
-	KnownFinalizers map[string][]string
+	// KnownFinalizers returns the finalizers of the current targets in a namespace.
+	// It is a function because targets can be added and removed at runtime.
+	KnownFinalizers func(namespace string) []string
+	// SourcePodManifestCompressionThreshold is the size in bytes above which source pod manifests are compressed
+	// (see proxypod.SetSourcePod). Zero disables compression.
+	SourcePodManifestCompressionThreshold int
+	// Client reads MulticlusterPolicies, ClusterMulticlusterPolicies and namespaces (see applyPolicy).
+	// If nil, pods are only multicluster pods if they have the elect annotation.
+	Client client.Reader
 }
 
 func (m Mutator) Default(ctx context.Context, obj runtime.Object) error {
@@ -38,29 +49,43 @@ func (m Mutator) Default(ctx context.Context, obj runtime.Object) error {
 		return fmt.Errorf("expected a Pod but got a %T", obj)
 	}
 
+	if m.Client != nil {
+		namespace := pod.Namespace
+		if namespace == "" {
+			// e.g., pods created by controllers may not have their namespace set yet
+			if req, err := admission.RequestFromContext(ctx); err == nil {
+				namespace = req.Namespace
+			}
+		}
+		if err := applyPolicy(ctx, m.Client, pod, namespace); err != nil {
+			return err
+		}
+	}
+
 	if _, ok := pod.Annotations[common.AnnotationKeyElect]; !ok {
 		// not a multicluster pod
 		return nil
 	}
 
+	// mutating webhooks are called before validating webhooks,
+	// so we validate here too, to fail with a clear message rather than a parsing error below
+	if err := toInvalid(pod, ValidateAnnotations(pod.Annotations, nil)); err != nil {
+		return err
+	}
+
 	// only save the source manifest if it's not set already
 	// webhooks may be run multiple times on the same object
 	// and have to be idempotent
 	// if we didn't check, we could lose the source scheduling constraints that we remove below
 	var srcPod *corev1.Pod
-	if _, ok := pod.Annotations[common.AnnotationKeySourcePodManifest]; !ok {
+	if !proxypodmodel.HasSourcePod(pod) {
 		srcPod = pod.DeepCopy()
-
-		srcPodManifest, err := yaml.Marshal(srcPod)
-		if err != nil {
+		if err := proxypodmodel.SetSourcePod(pod, srcPod, m.SourcePodManifestCompressionThreshold); err != nil {
 			return err
 		}
-
-		// pod.Annotations is not nil because we checked it contains AnnotationKeyElect
-		pod.Annotations[common.AnnotationKeySourcePodManifest] = string(srcPodManifest)
 	} else {
-		srcPod = &corev1.Pod{}
-		if err := yaml.UnmarshalStrict([]byte(pod.Annotations[common.AnnotationKeySourcePodManifest]), srcPod); err != nil {
+		var err error
+		if srcPod, err = proxypodmodel.GetSourcePod(pod); err != nil {
 			return err
 		}
 	}
@@ -112,6 +137,11 @@ func (m Mutator) Default(ctx context.Context, obj runtime.Object) error {
 		pod.Spec.TopologySpreadConstraints = srcPod.Spec.TopologySpreadConstraints
 	}
 
+	// We replaced the tolerations added by the DefaultTolerationSeconds admission plugin (which may run before us),
+	// so proxy pods would be evicted as soon as their virtual nodes are tainted unreachable or not-ready.
+	// Add them back, unless constraints copied above already tolerate those taints.
+	pod.Spec.Tolerations = appendDefaultNoExecuteTolerations(pod.Spec.Tolerations)
+
 	pod.Spec.SchedulerName = common.ProxySchedulerName // we don't allow bypassing the proxy scheduler for now
 	// TODO we would need to create delegate pod chaperons for proxy pods bound to virtual nodes outside of proxy scheduler
 
@@ -132,7 +162,7 @@ func (m Mutator) Default(ctx context.Context, obj runtime.Object) error {
 	}
 	// don't append finalizers of targets in different namespaces
 	// because they're useless, and wouldn't be removed because feedback controllers are namespaced
-	for _, f := range m.KnownFinalizers[pod.Namespace] {
+	for _, f := range m.KnownFinalizers(pod.Namespace) {
 		finalizers = append(finalizers, f)
 	}
 	pod.Finalizers = finalizers
@@ -145,3 +175,28 @@ func (m Mutator) Default(ctx context.Context, obj runtime.Object) error {
 
 	return nil
 }
+
+// defaultTolerationSeconds is the default of the DefaultTolerationSeconds admission plugin
+var defaultTolerationSeconds int64 = 300
+
+func appendDefaultNoExecuteTolerations(tolerations []corev1.Toleration) []corev1.Toleration {
+	for _, key := range []string{corev1.TaintNodeNotReady, corev1.TaintNodeUnreachable} {
+		taint := &corev1.Taint{Key: key, Effect: corev1.TaintEffectNoExecute}
+		tolerated := false
+		for i := range tolerations {
+			if tolerations[i].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			tolerations = append(tolerations, corev1.Toleration{
+				Key:               key,
+				Operator:          corev1.TolerationOpExists,
+				Effect:            corev1.TaintEffectNoExecute,
+				TolerationSeconds: &defaultTolerationSeconds,
+			})
+		}
+	}
+	return tolerations
+}
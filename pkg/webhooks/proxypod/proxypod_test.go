@@ -32,6 +32,13 @@ import (
 
 var zero int64 = 0
 
+var (
+	notReadyToleration = corev1.Toleration{Key: corev1.TaintNodeNotReady, Operator: corev1.TolerationOpExists,
+		Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &defaultTolerationSeconds}
+	unreachableToleration = corev1.Toleration{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists,
+		Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &defaultTolerationSeconds}
+)
+
 var testCases = map[string]struct {
 	pod        corev1.Pod
 	mutatedPod corev1.Pod
@@ -75,7 +82,7 @@ var testCases = map[string]struct {
 				}, {
 					Key:      corev1.TaintNodeNetworkUnavailable,
 					Operator: corev1.TolerationOpExists,
-				}},
+				}, notReadyToleration, unreachableToleration},
 				SchedulerName:                 common.ProxySchedulerName,
 				TerminationGracePeriodSeconds: &zero,
 			},
@@ -144,7 +151,7 @@ var testCases = map[string]struct {
 				}, {
 					Key:      corev1.TaintNodeNetworkUnavailable,
 					Operator: corev1.TolerationOpExists,
-				}},
+				}, notReadyToleration, unreachableToleration},
 				SchedulerName:                 common.ProxySchedulerName,
 				TerminationGracePeriodSeconds: &zero,
 			},
@@ -163,7 +170,7 @@ func TestMutate(t *testing.T) {
 		if k != "other pod" {
 			v.mutatedPod.Annotations[common.AnnotationKeySourcePodManifest] = string(podManifest)
 		}
-		m := Mutator{KnownFinalizers: knownFinalizers}
+		m := Mutator{KnownFinalizers: func(namespace string) []string { return knownFinalizers[namespace] }}
 		mutatedPod := v.pod.DeepCopy()
 		if err := m.Default(context.Background(), mutatedPod); err != nil {
 			t.Errorf("%s failed: %v", k, err)
@@ -174,3 +181,23 @@ func TestMutate(t *testing.T) {
 		}
 	}
 }
+
+func TestAppendDefaultNoExecuteTolerations(t *testing.T) {
+	var custom int64 = 3600
+	userUnreachable := corev1.Toleration{Key: corev1.TaintNodeUnreachable, Operator: corev1.TolerationOpExists,
+		Effect: corev1.TaintEffectNoExecute, TolerationSeconds: &custom}
+	all := corev1.Toleration{Operator: corev1.TolerationOpExists}
+
+	for name, tc := range map[string]struct {
+		tolerations []corev1.Toleration
+		want        []corev1.Toleration
+	}{
+		"none":                {nil, []corev1.Toleration{notReadyToleration, unreachableToleration}},
+		"custom unreachable":  {[]corev1.Toleration{userUnreachable}, []corev1.Toleration{userUnreachable, notReadyToleration}},
+		"tolerate everything": {[]corev1.Toleration{all}, []corev1.Toleration{all}},
+	} {
+		if diff := deep.Equal(appendDefaultNoExecuteTolerations(tc.tolerations), tc.want); len(diff) > 0 {
+			t.Errorf("%s failed with diff: %v", name, diff)
+		}
+	}
+}
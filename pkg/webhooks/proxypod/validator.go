@@ -0,0 +1,127 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxypod
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+// Validator rejects multicluster pods with malformed Admiralty annotations,
+// which would otherwise make the mutating webhook or the delegate pod controller fail with obscure errors.
+type Validator struct{}
+
+var _ admission.CustomValidator = Validator{}
+
+func (v Validator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a Pod but got a %T", obj)
+	}
+	return nil, toInvalid(pod, ValidateAnnotations(pod.Annotations, nil))
+}
+
+func (v Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPod, ok := oldObj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a Pod but got a %T", oldObj)
+	}
+	pod, ok := newObj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a Pod but got a %T", newObj)
+	}
+	// only validate annotations that changed,
+	// so pods admitted before the webhook was installed can still be updated, e.g., to remove finalizers
+	return nil, toInvalid(pod, ValidateAnnotations(pod.Annotations, oldPod.Annotations))
+}
+
+func (v Validator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateAnnotations validates the Admiralty annotations of a multicluster pod.
+// Annotations that have the same value in oldAnnotations aren't validated.
+func ValidateAnnotations(annotations, oldAnnotations map[string]string) field.ErrorList {
+	if _, ok := annotations[common.AnnotationKeyElect]; !ok {
+		// not a multicluster pod
+		return nil
+	}
+
+	var errs field.ErrorList
+	annotationsPath := field.NewPath("metadata", "annotations")
+
+	if s, ok := annotations[common.AnnotationKeyProxyPodSchedulingConstraints]; ok && !unchanged(oldAnnotations, common.AnnotationKeyProxyPodSchedulingConstraints, s) {
+		if err := yaml.UnmarshalStrict([]byte(s), &corev1.PodSpec{}); err != nil {
+			errs = append(errs, field.Invalid(annotationsPath.Key(common.AnnotationKeyProxyPodSchedulingConstraints), s,
+				fmt.Sprintf("must be a YAML or JSON pod spec (with scheduling constraints only): %v", err)))
+		}
+	}
+
+	if s, ok := annotations[common.AnnotationNoPrefixLabelRegexp]; ok && !unchanged(oldAnnotations, common.AnnotationNoPrefixLabelRegexp, s) {
+		if _, err := regexp.Compile(s); err != nil {
+			errs = append(errs, field.Invalid(annotationsPath.Key(common.AnnotationNoPrefixLabelRegexp), s, err.Error()))
+		}
+	}
+
+	if s, ok := annotations[common.AnnotationKeySchedulingStrategy]; ok && !unchanged(oldAnnotations, common.AnnotationKeySchedulingStrategy, s) {
+		supported := []string{common.SchedulingStrategySpread, common.SchedulingStrategyBinpack, common.SchedulingStrategyCheapestFirst}
+		if !slices.Contains(supported, s) {
+			errs = append(errs, field.NotSupported(annotationsPath.Key(common.AnnotationKeySchedulingStrategy), s, supported))
+		}
+	}
+
+	if s, ok := annotations[common.AnnotationKeyPodGroupMinMember]; ok && !unchanged(oldAnnotations, common.AnnotationKeyPodGroupMinMember, s) {
+		if n, err := strconv.Atoi(s); err != nil || n < 1 {
+			errs = append(errs, field.Invalid(annotationsPath.Key(common.AnnotationKeyPodGroupMinMember), s, "must be a positive integer"))
+		}
+		if _, ok := annotations[common.AnnotationKeyPodGroup]; !ok {
+			errs = append(errs, field.Required(annotationsPath.Key(common.AnnotationKeyPodGroup), "required with "+common.AnnotationKeyPodGroupMinMember))
+		}
+	}
+
+	if s, ok := annotations[common.AnnotationKeyPodGroupAcrossTargets]; ok && !unchanged(oldAnnotations, common.AnnotationKeyPodGroupAcrossTargets, s) {
+		if _, err := strconv.ParseBool(s); err != nil {
+			errs = append(errs, field.Invalid(annotationsPath.Key(common.AnnotationKeyPodGroupAcrossTargets), s, "must be a boolean"))
+		}
+	}
+
+	return errs
+}
+
+func unchanged(oldAnnotations map[string]string, key, value string) bool {
+	oldValue, ok := oldAnnotations[key]
+	return ok && oldValue == value
+}
+
+func toInvalid(pod *corev1.Pod, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.NewInvalid(corev1.SchemeGroupVersion.WithKind("Pod").GroupKind(), pod.Name, errs)
+}
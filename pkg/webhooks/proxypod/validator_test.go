@@ -0,0 +1,90 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxypod
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"admiralty.io/multicluster-scheduler/pkg/common"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		oldAnnotations map[string]string
+		errs           int
+	}{
+		{
+			name:        "not a multicluster pod",
+			annotations: map[string]string{common.AnnotationKeyProxyPodSchedulingConstraints: "foo: bar"},
+		},
+		{
+			name: "valid annotations",
+			annotations: map[string]string{
+				common.AnnotationKeyElect:                         "",
+				common.AnnotationKeyProxyPodSchedulingConstraints: "nodeSelector:\n  a: b\n",
+				common.AnnotationNoPrefixLabelRegexp:              "^app=",
+			},
+		},
+		{
+			name: "invalid annotations",
+			annotations: map[string]string{
+				common.AnnotationKeyElect:                         "",
+				common.AnnotationKeyProxyPodSchedulingConstraints: "foo: bar",
+				common.AnnotationNoPrefixLabelRegexp:              "(app",
+			},
+			errs: 2,
+		},
+		{
+			name: "valid pod group",
+			annotations: map[string]string{
+				common.AnnotationKeyElect:                 "",
+				common.AnnotationKeyPodGroup:              "job",
+				common.AnnotationKeyPodGroupMinMember:     "3",
+				common.AnnotationKeyPodGroupAcrossTargets: "true",
+			},
+		},
+		{
+			name: "invalid pod group",
+			annotations: map[string]string{
+				common.AnnotationKeyElect:                 "",
+				common.AnnotationKeyPodGroupMinMember:     "0",
+				common.AnnotationKeyPodGroupAcrossTargets: "yes please",
+			},
+			errs: 3,
+		},
+		{
+			name: "unchanged invalid annotation",
+			annotations: map[string]string{
+				common.AnnotationKeyElect:            "",
+				common.AnnotationNoPrefixLabelRegexp: "(app",
+			},
+			oldAnnotations: map[string]string{
+				common.AnnotationKeyElect:            "",
+				common.AnnotationNoPrefixLabelRegexp: "(app",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Len(t, ValidateAnnotations(tt.annotations, tt.oldAnnotations), tt.errs)
+		})
+	}
+}
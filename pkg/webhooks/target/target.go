@@ -0,0 +1,203 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target // import "admiralty.io/multicluster-scheduler/pkg/webhooks/target"
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+	"admiralty.io/multicluster-scheduler/pkg/model/delegatepod"
+)
+
+// Validator rejects Targets and ClusterTargets that the agent would otherwise ignore at runtime,
+// e.g., with both or neither of self and kubeconfigSecret, or with an excluded labels regexp that doesn't compile.
+// Objects created before the webhook was installed (or by an older version of it) were never validated, though,
+// so the agent and the schedulers still check what they rely on, and report invalid objects instead of crashing.
+type Validator struct{}
+
+var _ admission.CustomValidator = Validator{}
+
+func (v Validator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, validate(obj)
+}
+
+func (v Validator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return nil, validate(newObj)
+}
+
+func (v Validator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validate(obj runtime.Object) error {
+	switch t := obj.(type) {
+	case *v1alpha1.Target:
+		if errs := ValidateTarget(t); len(errs) > 0 {
+			return errors.NewInvalid(v1alpha1.SchemeGroupVersion.WithKind("Target").GroupKind(), t.Name, errs)
+		}
+	case *v1alpha1.ClusterTarget:
+		if errs := ValidateClusterTarget(t); len(errs) > 0 {
+			return errors.NewInvalid(v1alpha1.SchemeGroupVersion.WithKind("ClusterTarget").GroupKind(), t.Name, errs)
+		}
+	default:
+		return fmt.Errorf("expected a Target or ClusterTarget but got a %T", obj)
+	}
+	return nil
+}
+
+func ValidateTarget(t *v1alpha1.Target) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateSelfXORKubeconfigSecret(t.Spec.Self, t.Spec.KubeconfigSecret != nil, specPath)
+	if s := t.Spec.KubeconfigSecret; s != nil && s.Name == "" {
+		errs = append(errs, field.Required(specPath.Child("kubeconfigSecret", "name"), ""))
+	}
+	errs = append(errs, validateExcludedLabelsRegexp(t.Spec.ExcludedLabelsRegexp, specPath.Child("excludedLabelsRegexp"))...)
+	errs = append(errs, validateTimeouts(t.Spec.Timeouts, specPath.Child("timeouts"))...)
+	errs = append(errs, validateWeight(t.Spec.Weight, specPath.Child("weight"))...)
+	errs = append(errs, validateTier(t.Spec.Tier, specPath.Child("tier"))...)
+	errs = append(errs, validatePriorityClassMap(t.Spec.PriorityClassMap, specPath.Child("priorityClassMap"))...)
+	errs = append(errs, validateDelegatePodTransformations(t.Spec.DelegatePodTransformations, specPath.Child("delegatePodTransformations"))...)
+	errs = append(errs, validateServiceAccountRBAC(t.Spec.ServiceAccountRBAC, specPath.Child("serviceAccountRBAC"))...)
+	return errs
+}
+
+func ValidateClusterTarget(t *v1alpha1.ClusterTarget) field.ErrorList {
+	specPath := field.NewPath("spec")
+	errs := validateSelfXORKubeconfigSecret(t.Spec.Self, t.Spec.KubeconfigSecret != nil, specPath)
+	if s := t.Spec.KubeconfigSecret; s != nil {
+		if s.Namespace == "" {
+			errs = append(errs, field.Required(specPath.Child("kubeconfigSecret", "namespace"), ""))
+		}
+		if s.Name == "" {
+			errs = append(errs, field.Required(specPath.Child("kubeconfigSecret", "name"), ""))
+		}
+	}
+	errs = append(errs, validateExcludedLabelsRegexp(t.Spec.ExcludedLabelsRegexp, specPath.Child("excludedLabelsRegexp"))...)
+	errs = append(errs, validateTimeouts(t.Spec.Timeouts, specPath.Child("timeouts"))...)
+	errs = append(errs, validateWeight(t.Spec.Weight, specPath.Child("weight"))...)
+	errs = append(errs, validateTier(t.Spec.Tier, specPath.Child("tier"))...)
+	errs = append(errs, validatePriorityClassMap(t.Spec.PriorityClassMap, specPath.Child("priorityClassMap"))...)
+	errs = append(errs, validateDelegatePodTransformations(t.Spec.DelegatePodTransformations, specPath.Child("delegatePodTransformations"))...)
+	errs = append(errs, validateServiceAccountRBAC(t.Spec.ServiceAccountRBAC, specPath.Child("serviceAccountRBAC"))...)
+	return errs
+}
+
+func validateSelfXORKubeconfigSecret(self bool, hasKubeconfigSecret bool, specPath *field.Path) field.ErrorList {
+	if self && hasKubeconfigSecret {
+		return field.ErrorList{field.Invalid(specPath.Child("self"), self, "self and kubeconfigSecret are mutually exclusive")}
+	}
+	if !self && !hasKubeconfigSecret {
+		return field.ErrorList{field.Required(specPath.Child("kubeconfigSecret"), "either self must be true or kubeconfigSecret must be set")}
+	}
+	return nil
+}
+
+func validateExcludedLabelsRegexp(excludedLabelsRegexp *string, fldPath *field.Path) field.ErrorList {
+	if excludedLabelsRegexp == nil {
+		return nil
+	}
+	if _, err := regexp.Compile(*excludedLabelsRegexp); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, *excludedLabelsRegexp, err.Error())}
+	}
+	return nil
+}
+
+func validateTimeouts(timeouts *v1alpha1.TargetTimeouts, fldPath *field.Path) field.ErrorList {
+	if timeouts == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	validate := func(name string, d *metav1.Duration) {
+		if d != nil && d.Duration <= 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(name), d.Duration.String(), "must be positive"))
+		}
+	}
+	validate("filter", timeouts.Filter)
+	validate("preBind", timeouts.PreBind)
+	validate("permit", timeouts.Permit)
+	return errs
+}
+
+func validateWeight(weight *int32, fldPath *field.Path) field.ErrorList {
+	if weight != nil && (*weight < 0 || *weight > 100) {
+		return field.ErrorList{field.Invalid(fldPath, *weight, "must be between 0 and 100")}
+	}
+	return nil
+}
+
+func validateTier(tier *int32, fldPath *field.Path) field.ErrorList {
+	if tier != nil && *tier < 0 {
+		return field.ErrorList{field.Invalid(fldPath, *tier, "must be non-negative")}
+	}
+	return nil
+}
+
+// validatePriorityClassMap allows empty keys (pods without a priority class) and values (the target cluster's default)
+func validatePriorityClassMap(m map[string]string, fldPath *field.Path) field.ErrorList {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys) // for deterministic error order
+
+	var errs field.ErrorList
+	for _, k := range keys {
+		for _, name := range []string{k, m[k]} {
+			if name == "" {
+				continue
+			}
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				errs = append(errs, field.Invalid(fldPath.Key(k), name, msg))
+			}
+		}
+	}
+	return errs
+}
+
+// validateDelegatePodTransformations compiles the transformations like the agent and the proxy scheduler do,
+// so custom transformers must be compiled into the agent running the webhook (and the scheduler, which checks again)
+func validateDelegatePodTransformations(transformations []v1alpha1.DelegatePodTransformation, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, t := range transformations {
+		if _, err := delegatepod.NewTransformer(t); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), t, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateServiceAccountRBAC(rbac v1alpha1.ServiceAccountRBAC, fldPath *field.Path) field.ErrorList {
+	switch rbac {
+	case "", v1alpha1.ServiceAccountRBACNone, v1alpha1.ServiceAccountRBACRoleBindings, v1alpha1.ServiceAccountRBACRoleBindingsAndRoles:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath, rbac, []string{
+		string(v1alpha1.ServiceAccountRBACNone),
+		string(v1alpha1.ServiceAccountRBACRoleBindings),
+		string(v1alpha1.ServiceAccountRBACRoleBindingsAndRoles),
+	})}
+}
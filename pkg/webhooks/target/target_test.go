@@ -0,0 +1,139 @@
+/*
+ * Copyright 2024 The Multicluster-Scheduler Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package target
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"admiralty.io/multicluster-scheduler/pkg/apis/multicluster/v1alpha1"
+)
+
+func TestValidate(t *testing.T) {
+	validRegexp := "^foo"
+	invalidRegexp := "(foo"
+	tier := int32(1)
+	negativeTier := int32(-1)
+	meta := metav1.ObjectMeta{Name: "a", Namespace: "ns"}
+
+	tests := []struct {
+		name    string
+		obj     runtime.Object
+		invalid bool
+	}{
+		{
+			name: "self target",
+			obj:  &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true}},
+		},
+		{
+			name: "remote target",
+			obj:  &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{KubeconfigSecret: &v1alpha1.KubeconfigSecret{Name: "a"}, ExcludedLabelsRegexp: &validRegexp}},
+		},
+		{
+			name:    "target with self and kubeconfig secret",
+			obj:     &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, KubeconfigSecret: &v1alpha1.KubeconfigSecret{Name: "a"}}},
+			invalid: true,
+		},
+		{
+			name:    "target with neither self nor kubeconfig secret",
+			obj:     &v1alpha1.Target{ObjectMeta: meta},
+			invalid: true,
+		},
+		{
+			name:    "target with invalid excluded labels regexp",
+			obj:     &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, ExcludedLabelsRegexp: &invalidRegexp}},
+			invalid: true,
+		},
+		{
+			name: "target with tier",
+			obj:  &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, Tier: &tier}},
+		},
+		{
+			name:    "target with negative tier",
+			obj:     &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, Tier: &negativeTier}},
+			invalid: true,
+		},
+		{
+			name: "target with priority class map",
+			obj:  &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, PriorityClassMap: map[string]string{"high": "target-high", "": "target-default", "low": ""}}},
+		},
+		{
+			name:    "target with invalid priority class map",
+			obj:     &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, PriorityClassMap: map[string]string{"high": "Not_A_Name"}}},
+			invalid: true,
+		},
+		{
+			name: "target with delegate pod transformations",
+			obj: &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, DelegatePodTransformations: []v1alpha1.DelegatePodTransformation{
+				{ImageRewrite: &v1alpha1.ImageRewrite{Regexp: "^docker\\.io/", Replacement: "mirror.example.com/"}},
+				{FieldMapping: &v1alpha1.FieldMapping{Path: "/spec/runtimeClassName", Values: map[string]string{"": "gvisor"}}},
+			}}},
+		},
+		{
+			name: "target with ambiguous delegate pod transformation",
+			obj: &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, DelegatePodTransformations: []v1alpha1.DelegatePodTransformation{
+				{ImageRewrite: &v1alpha1.ImageRewrite{Regexp: "^"}, Custom: "foo"},
+			}}},
+			invalid: true,
+		},
+		{
+			name: "target with unknown custom delegate pod transformation",
+			obj: &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, DelegatePodTransformations: []v1alpha1.DelegatePodTransformation{
+				{Custom: "not-compiled-in"},
+			}}},
+			invalid: true,
+		},
+		{
+			name: "target mirroring service account role bindings",
+			obj:  &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, ServiceAccountRBAC: v1alpha1.ServiceAccountRBACRoleBindings}},
+		},
+		{
+			name:    "target with invalid service account RBAC",
+			obj:     &v1alpha1.Target{ObjectMeta: meta, Spec: v1alpha1.TargetSpec{Self: true, ServiceAccountRBAC: "ClusterRoleBindings"}},
+			invalid: true,
+		},
+		{
+			name: "remote cluster target",
+			obj:  &v1alpha1.ClusterTarget{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: v1alpha1.ClusterTargetSpec{KubeconfigSecret: &v1alpha1.ClusterKubeconfigSecret{Namespace: "ns", Name: "a"}}},
+		},
+		{
+			name:    "cluster target without kubeconfig secret namespace",
+			obj:     &v1alpha1.ClusterTarget{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: v1alpha1.ClusterTargetSpec{KubeconfigSecret: &v1alpha1.ClusterKubeconfigSecret{Name: "a"}}},
+			invalid: true,
+		},
+		{
+			name:    "cluster target with invalid excluded labels regexp",
+			obj:     &v1alpha1.ClusterTarget{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: v1alpha1.ClusterTargetSpec{Self: true, ExcludedLabelsRegexp: &invalidRegexp}},
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validator{}.ValidateCreate(context.Background(), tt.obj)
+			if tt.invalid {
+				require.True(t, errors.IsInvalid(err), "expected invalid error, got %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -21,7 +21,6 @@ imgs=(
   admiralty-agent
   admiralty-remove-finalizers
   admiralty-scheduler
-  admiralty-restarter
 )
 
 for img in "${imgs[@]}"; do
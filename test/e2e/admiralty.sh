@@ -30,17 +30,14 @@ admiralty_setup() {
   kind load docker-image admiralty-agent:$VERSION-amd64 --name cluster$i
   kind load docker-image admiralty-scheduler:$VERSION-amd64 --name cluster$i
   kind load docker-image admiralty-remove-finalizers:$VERSION-amd64 --name cluster$i
-  kind load docker-image admiralty-restarter:$VERSION-amd64 --name cluster$i
 
   h $i upgrade --install admiralty charts/multicluster-scheduler -n admiralty --create-namespace -f $VALUES \
     --set controllerManager.image.repository=admiralty-agent \
     --set scheduler.image.repository=admiralty-scheduler \
     --set postDeleteJob.image.repository=admiralty-remove-finalizers \
-    --set restarter.image.repository=admiralty-restarter \
     --set controllerManager.image.tag=$VERSION-amd64 \
     --set scheduler.image.tag=$VERSION-amd64 \
-    --set postDeleteJob.image.tag=$VERSION-amd64 \
-    --set restarter.image.tag=$VERSION-amd64
+    --set postDeleteJob.image.tag=$VERSION-amd64
   k $i delete pod --all -n admiralty
 }
 